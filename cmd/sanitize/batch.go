@@ -0,0 +1,368 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/batch"
+	"github.com/luismascotto/subtitle-sanitizer/internal/container"
+	"github.com/luismascotto/subtitle-sanitizer/internal/diff"
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/imagesub"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/sanitize"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+// defaultBatchExcludes skip outputs of earlier runs so re-running on a folder does not
+// produce "-his-his" files or sanitize remuxed copies.
+var defaultBatchExcludes = []string{"*-his.srt", "*-his_*.srt", "*.clean.mkv", "*.clean.webm"}
+
+// forcedBatchExcludes skip, with --forced, the forced tracks earlier runs wrote (or that
+// were extracted as such) so they do not get a ".forced.forced.srt".
+var forcedBatchExcludes = []string{"*.forced.srt"}
+
+// expandBatchInputs resolves directories and globs into absolute file paths.
+func expandBatchInputs(inputs []string, extractOnly, recursive bool, include, exclude []string) ([]string, error) {
+	exts := append([]string{".srt", ".ass", ".sup", ".idx"}, container.Extensions...)
+	if extractOnly {
+		exts = container.Extensions
+	}
+	files, err := batch.Expand(inputs, batch.Options{
+		Recursive:  recursive,
+		Include:    include,
+		Exclude:    append(append([]string{}, defaultBatchExcludes...), exclude...),
+		Extensions: exts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return absPaths(files), nil
+}
+
+// batchOptions controls a non-interactive run.
+type batchOptions struct {
+	Overwrite bool
+	Jobs      int
+	Progress  io.Writer           // progress lines and summary table
+	Report    batch.ReportWriter  // optional per-file records
+	DryRun    bool                // render diffs instead of writing outputs
+	Diff      io.Writer           // dry runs: where unified diffs go (nil: not printed)
+	Color     bool                // colorize diffs
+	Track     container.Selector  // container subtitle track to sanitize
+	Remux     container.RemuxMode // containers: also write file.clean.mkv with the sanitized track ("" off)
+	OCR       ocrOptions          // image subtitles and PGS tracks
+	Forced    bool                // write FILE.forced.srt with only the forced cues instead of sanitizing
+	// Output, when set, names the output of each subtitle instead of the usual naming;
+	// it is written even without changes, and video tracks are not extracted next to
+	// the video.
+	Output      func(subtitlePath string) string
+	RemoveInput bool // with Output: delete text subtitle inputs once written (journaled)
+}
+
+// runBatch sanitizes files without review through a bounded worker pool sharing the
+// prepared Rules of each directory config, prints one progress line per file and a
+// summary table.
+func runBatch(files []string, ruleSets *ruleSet, opts batchOptions) int {
+	out := opts.Progress
+	finished := 0
+	var reportErr error
+	results := batch.Run(files, opts.Jobs, func(path string) batch.FileResult {
+		return processFile(path, ruleSets, opts)
+	}, func(_ int, res batch.FileResult) {
+		finished++
+		if opts.Report != nil && reportErr == nil {
+			reportErr = opts.Report.Write(res)
+		}
+		if opts.Diff != nil && res.Diff != "" {
+			io.WriteString(opts.Diff, res.Diff)
+		}
+		switch {
+		case res.Err != nil:
+			fmt.Fprintf(out, "[%d/%d] %s: error: %v\n", finished, len(files), res.Path, res.Err)
+		case res.Output == "":
+			fmt.Fprintf(out, "[%d/%d] %s: no changes%s%s%s\n", finished, len(files), res.Path, suppressedNote(res.CuesSuppressed), parseNote(res.Diagnostics), hiNote(res.HI))
+		case opts.DryRun:
+			fmt.Fprintf(out, "[%d/%d] %s -> %s (dry run: %d changed, %d removed%s%s)%s\n",
+				finished, len(files), res.Path, filepath.Base(res.Output), res.CuesChanged, res.CuesRemoved, suppressedNote(res.CuesSuppressed), parseNote(res.Diagnostics), hiNote(res.HI))
+		default:
+			output := filepath.Base(res.Output)
+			if res.Remuxed != "" {
+				output += ", " + filepath.Base(res.Remuxed)
+			}
+			fmt.Fprintf(out, "[%d/%d] %s -> %s (%d changed, %d removed%s%s)%s\n",
+				finished, len(files), res.Path, output, res.CuesChanged, res.CuesRemoved, suppressedNote(res.CuesSuppressed), parseNote(res.Diagnostics), hiNote(res.HI))
+		}
+	})
+
+	fmt.Fprintln(out)
+	if err := batch.WriteSummary(out, results); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+	}
+	if reportErr != nil {
+		fmt.Fprintln(os.Stderr, "Error writing report:", reportErr)
+		return exitFailed
+	}
+	if batch.Summarize(results).Errors > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// hiNote is the progress line suffix describing the HI content of a file.
+func hiNote(score *hi.Score) string {
+	switch {
+	case score == nil:
+		return ""
+	case !score.NeedsSanitizing():
+		return " [clean: no HI markers]"
+	default:
+		return fmt.Sprintf(" [%s %.0f%%]", score.Level, score.Value*100)
+	}
+}
+
+// suppressedNote counts the cues the allowlist kept some rule from changing.
+func suppressedNote(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return fmt.Sprintf(", %d suppressed", n)
+}
+
+// parseNote counts the parse warnings and errors the lenient SRT parser got past (see
+// the report for the lines).
+func parseNote(diags []subtitle.Diagnostic) string {
+	n := 0
+	for _, d := range diags {
+		if d.Severity != subtitle.SeverityInfo {
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return ""
+	case 1:
+		return ", 1 parse warning"
+	}
+	return fmt.Sprintf(", %d parse warnings", n)
+}
+
+// processFile extracts (containers), parses, applies rules and writes one file without any UI.
+// Dry runs write nothing and render the unified diff of the would-be output instead.
+func processFile(inputPath string, ruleSets *ruleSet, opts batchOptions) batch.FileResult {
+	res := batch.FileResult{Path: inputPath}
+	_, prepared, err := ruleSets.forFile(inputPath)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	stage := time.Now()
+	lap := func() time.Duration {
+		now := time.Now()
+		d := now.Sub(stage)
+		stage = now
+		return d
+	}
+
+	subtitlePath, data, track, err := loadSubtitle(inputPath, opts.Track, opts.OCR, opts.DryRun || opts.Output != nil)
+	res.Timings.Load = lap()
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if subtitlePath != inputPath {
+		res.Subtitle = subtitlePath
+	}
+	format := subtitle.FormatFromPath(subtitlePath)
+	if format == model.SubtitleFormatUnknown {
+		res.Err = fmt.Errorf("unsupported extension: %s", filepath.Ext(subtitlePath))
+		return res
+	}
+	res.Format = strings.ToLower(strings.TrimPrefix(filepath.Ext(subtitlePath), "."))
+	var doc *model.Document
+	if format == model.SubtitleFormatSRT {
+		doc, res.Diagnostics, err = subtitle.ParseSRTDiagnostics(data, true)
+	} else {
+		doc, err = subtitle.Parse(data, format)
+	}
+	res.Timings.Parse = lap()
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Cues = len(doc.Cues)
+	score := hi.Analyze(*doc)
+	res.HI = &score
+
+	if opts.Forced {
+		processForced(&res, subtitlePath, data, *doc, prepared, opts)
+		res.Timings.Transform = lap()
+		return res
+	}
+
+	transformations := sanitize.ApplyRules(*doc, prepared)
+	res.Timings.Transform = lap()
+	res.Changes = transformations.Changes
+	for _, ch := range transformations.Changes {
+		if len(ch.Suppressed) > 0 {
+			res.CuesSuppressed++
+		}
+		switch {
+		case !ch.Applied():
+		case strings.TrimSpace(ch.Transformed) == "":
+			res.CuesRemoved++
+		default:
+			res.CuesChanged++
+		}
+	}
+
+	if res.CuesChanged+res.CuesRemoved == 0 && format == model.SubtitleFormatSRT && opts.Output == nil {
+		// Nothing to sanitize; do not add an identical -his.srt copy.
+		return res
+	}
+	if opts.DryRun {
+		res.Output, res.Diff, res.Err = renderDiff(subtitlePath, data, &transformations.Document, opts)
+		res.Timings.Write = lap()
+		return res
+	}
+	if opts.Output != nil {
+		res.Output = opts.Output(subtitlePath)
+		removeInput := opts.RemoveInput && subtitlePath == inputPath && res.Output != inputPath
+		res.Err = saveOutput(subtitlePath, data, &transformations.Document, res.Output, removeInput)
+	} else {
+		res.Output, res.Err = writeOutput(subtitlePath, data, &transformations.Document, true, opts.Overwrite)
+	}
+	if res.Err == nil && opts.Remux != "" && track != nil && res.Output != "" {
+		res.Remuxed, res.Err = container.Remux(inputPath, *track, res.Output, opts.Remux)
+	}
+	res.Timings.Write = lap()
+	return res
+}
+
+// renderDiff returns the path writeOutput would pick and the unified diff between the
+// input file and the SRT that would be written there.
+func renderDiff(inputPath string, original []byte, result *model.Document, opts batchOptions) (string, string, error) {
+	outputMu.Lock()
+	outPath, err := outputPath(inputPath, opts.Overwrite)
+	outputMu.Unlock()
+	if err != nil {
+		return "", "", err
+	}
+	var sb strings.Builder
+	_, err = diff.Unified(&sb, inputPath, outPath,
+		diff.SplitLines(string(original)),
+		diff.SplitLines(string(subtitle.FormatSRT(*result))),
+		diff.UnifiedOptions{Context: diff.DefaultContext, Color: opts.Color})
+	return outPath, sb.String(), err
+}
+
+// colorOutput reports whether f is a terminal that should get ANSI colors (NO_COLOR unset).
+func colorOutput(f *os.File) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// runExtractHeadless extracts every subtitle track of the container inputs without the TUI.
+func runExtractHeadless(files []string, opts container.ExtractOptions) int {
+	code := exitOK
+	for i, path := range files {
+		opts.Skipped = func(track container.Track) {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s: warning: subtitle codec of track %d not supported (%s, %q), skipped\n",
+				i+1, len(files), path, track.Index, track.Codec, track.Title)
+		}
+		count, err := container.BatchExtractSubtitles(path, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s: error: %v\n", i+1, len(files), path, err)
+			code = exitFailed
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "[%d/%d] %s: %d tracks extracted\n", i+1, len(files), path, count)
+	}
+	return code
+}
+
+// openReport returns the --report writer (nil when format is empty) and a func that
+// finishes it and closes the report file.
+func openReport(format, path string) (batch.ReportWriter, func() error, error) {
+	if format == "" {
+		return nil, func() error { return nil }, nil
+	}
+	if _, err := batch.ParseReportFormat(format); err != nil {
+		return nil, nil, err
+	}
+	var w io.Writer = os.Stdout
+	var f *os.File
+	if path != "" {
+		var err error
+		if f, err = os.Create(path); err != nil {
+			return nil, nil, fmt.Errorf("create report: %w", err)
+		}
+		w = f
+	}
+	report, err := batch.NewReportWriter(w, format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return report, func() error {
+		err := report.Close()
+		if f != nil {
+			err = errors.Join(err, f.Close())
+		}
+		return err
+	}, nil
+}
+
+// loadSubtitle returns the subtitle path and bytes for inputPath, extracting the
+// preferred track matching sel first when it is a container and recognizing image
+// subtitles with ocr (only in memory when dryRun). track is the extracted track, nil for
+// subtitle files.
+func loadSubtitle(inputPath string, sel container.Selector, ocr ocrOptions, dryRun bool) (string, []byte, *container.Track, error) {
+	path, data, track, err := readSubtitle(inputPath, sel, dryRun)
+	if err == nil && imagesub.IsImageSubtitle(path) {
+		path, data, err = ocrSubtitle(path, data, ocr, !dryRun)
+	}
+	return path, data, track, err
+}
+
+func readSubtitle(inputPath string, sel container.Selector, dryRun bool) (string, []byte, *container.Track, error) {
+	if container.IsContainer(inputPath) {
+		tracks, err := container.ProbeTracks(inputPath)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		if sel.NeedsAnalysis(tracks) {
+			container.AnalyzeTracks(inputPath, tracks)
+		}
+		track, err := container.SelectTrack(tracks, sel)
+		if err != nil {
+			return "", nil, nil, err
+		}
+		read := container.ExtractTrack
+		if dryRun {
+			read = container.ReadTrack
+		}
+		path, data, err := read(inputPath, track)
+		return path, data, &track, err
+	}
+	if err := validateInputPath(inputPath); err != nil {
+		return "", nil, nil, err
+	}
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return "", nil, nil, fmt.Errorf("read file: %w", err)
+	}
+	if len(data) == 0 {
+		return "", nil, nil, errors.New("data is empty")
+	}
+	return inputPath, data, nil, nil
+}
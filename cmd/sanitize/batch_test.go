@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+)
+
+// testRuleSet returns the rules of an empty config (the defaults).
+func testRuleSet(t *testing.T) *ruleSet {
+	t.Helper()
+	conf := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(conf, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loader, err := rules.NewLoader(conf, rules.Layer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newRuleSet(loader)
+}
+
+// sample.webm: VP9 video (0) and an eng D_WEBVTT/SUBTITLES track (1) with voice and
+// class spans.
+func TestProcessFile_webm(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "sample.webm"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	video := filepath.Join(t.TempDir(), "sample.webm")
+	if err := os.WriteFile(video, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res := processFile(video, testRuleSet(t), batchOptions{})
+	if res.Err != nil {
+		t.Fatalf("processFile: %v", res.Err)
+	}
+	if res.Format != "srt" || res.Cues != 3 || res.CuesChanged != 1 || res.CuesRemoved != 1 {
+		t.Fatalf("result = %+v", res)
+	}
+	got, err := os.ReadFile(res.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:03,000\nWho's there?\n\n2\n00:00:06,000 --> 00:00:07,500\nFish & chips\n"
+	if string(got) != want {
+		t.Fatalf("%s = %q, want %q", filepath.Base(res.Output), got, want)
+	}
+}
+
+func TestProcessFile_diagnostics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "movie.srt")
+	srt := "1\n00:00:01,000 --> 00:00:02,000\n(sighs) Hello\n\n2\n00:00:04,000 --> 00:00:03,000\nBye\n\n"
+	if err := os.WriteFile(path, []byte(srt), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res := processFile(path, testRuleSet(t), batchOptions{DryRun: true})
+	if res.Err != nil {
+		t.Fatalf("processFile: %v", res.Err)
+	}
+	if len(res.Diagnostics) == 0 || res.Diagnostics[0].Line != 6 {
+		t.Fatalf("diagnostics = %+v", res.Diagnostics)
+	}
+	if got := parseNote(res.Diagnostics); got != ", 1 parse warning" {
+		t.Fatalf("parseNote = %q", got)
+	}
+}
+
+func TestForcedReruns(t *testing.T) {
+	dir := t.TempDir()
+	full, forced := filepath.Join(dir, "Movie.srt"), filepath.Join(dir, "Movie.forced.srt")
+	for _, path := range []string{full, forced} {
+		if err := os.WriteFile(path, []byte("1\n00:00:01,000 --> 00:00:02,000\n<i>EXIT</i>\n\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := expandBatchInputs([]string{dir}, false, false, nil, forcedBatchExcludes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0] != full {
+		t.Fatalf("files = %q, want only %s", files, full)
+	}
+	if res := processFile(forced, testRuleSet(t), batchOptions{Forced: true, DryRun: true}); res.Err == nil || res.Output != "" {
+		t.Fatalf("forced input: %+v", res)
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+// configFlags are the flags shared by sanitize and the config subcommand.
+type configFlags struct {
+	Config string   `arg:"--config" help:"config file used instead of the user one (default: user config dir/subtitle-sanitizer/config.json)"`
+	Preset string   `arg:"--preset" help:"rule preset the config extends: light, standard, aggressive, netflix-plain (overrides \"extends\")"`
+	Set    []string `arg:"--set,separate" help:"override a config value, eg: --set removeTextBeforeColon=true --set lint.maxCps=20 (repeatable)"`
+}
+
+// loader builds the config loader with --set (plus extra flag values) as the top layer.
+func (f configFlags) loader(extra map[string]any) (*rules.Loader, error) {
+	var flags rules.Layer
+	if f.Preset != "" {
+		flags.Set("extends", f.Preset)
+	}
+	for _, s := range f.Set {
+		if err := flags.SetString(s); err != nil {
+			return nil, err
+		}
+	}
+	for k, v := range extra {
+		flags.Set(k, v)
+	}
+	return rules.NewLoader(f.Config, flags)
+}
+
+// ruleSet hands out prepared Rules per input directory, compiled once per distinct
+// .subtitle-sanitizer.json. Safe for concurrent use by batch workers.
+type ruleSet struct {
+	loader *rules.Loader
+	mu     sync.Mutex
+	rules  map[string]preparedRules // dir config path ("" none) -> rules
+}
+
+type preparedRules struct {
+	conf     rules.Config
+	prepared transform.Rules
+}
+
+func newRuleSet(loader *rules.Loader) *ruleSet {
+	return &ruleSet{loader: loader, rules: map[string]preparedRules{}}
+}
+
+// forFile returns the config and prepared Rules applying to path (with the allowed cues
+// of that file).
+func (s *ruleSet) forFile(path string) (rules.Config, transform.Rules, error) {
+	conf, key, err := s.loader.ForDir(filepath.Dir(path))
+	if err != nil {
+		return rules.Config{}, transform.Rules{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rules[key]
+	if !ok {
+		r = preparedRules{conf: conf, prepared: transform.NewRules(conf)}
+		s.rules[key] = r
+	}
+	return r.conf, r.prepared.ForFile(path), nil
+}
+
+// allowCuesContaining saves the patterns rejected during review to allow.cuesContaining
+// of the user config and drops the compiled rules so the next files use them.
+func (s *ruleSet) allowCuesContaining(phrases []string) error {
+	changed, err := s.loader.AllowCuesContaining(phrases)
+	if changed {
+		s.mu.Lock()
+		s.rules = map[string]preparedRules{}
+		s.mu.Unlock()
+	}
+	return err
+}
+
+type configArgs struct {
+	Show     *configShowArgs     `arg:"subcommand:show" help:"print the effective config of a directory and where each value comes from"`
+	Init     *configInitArgs     `arg:"subcommand:init" help:"write a config extending a preset to the user config file (or --config)"`
+	Validate *configValidateArgs `arg:"subcommand:validate" help:"check config files against the schema: unknown keys, types, regexes, version"`
+	Migrate  *configMigrateArgs  `arg:"subcommand:migrate" help:"rewrite config files in the current version"`
+}
+
+type configShowArgs struct {
+	configFlags
+	Dir string `arg:"positional" help:"directory whose .subtitle-sanitizer.json applies (default: current)"`
+}
+
+type configValidateArgs struct {
+	Input []string `arg:"positional" help:"config files, or directories whose .subtitle-sanitizer.json to check (default: the user config and the current directory's)"`
+}
+
+type configMigrateArgs struct {
+	Input []string `arg:"positional,required" help:"config files to migrate"`
+	Write bool     `arg:"-w,--write" help:"rewrite the files in place (the old one is kept as FILE.vN.bak) instead of printing them"`
+}
+
+type configInitArgs struct {
+	Config string `arg:"--config" help:"file to write (default: user config dir/subtitle-sanitizer/config.json)"`
+	Preset string `arg:"--preset" help:"preset the new config extends" default:"standard"`
+	Full   bool   `arg:"--full" help:"write every value of the preset instead of only \"extends\" (pins them when the preset changes)"`
+	Force  bool   `arg:"--force" help:"overwrite an existing file"`
+}
+
+// runConfig shows or initializes the layered config.
+func runConfig(argv []string) int {
+	var args configArgs
+	mustParseSubcommand("config", &args, argv)
+	switch {
+	case args.Show != nil:
+		return runConfigShow(args.Show)
+	case args.Init != nil:
+		return runConfigInit(args.Init)
+	case args.Validate != nil:
+		return runConfigValidate(args.Validate)
+	case args.Migrate != nil:
+		return runConfigMigrate(args.Migrate)
+	default:
+		fmt.Fprintln(os.Stderr, "Error: expected a subcommand: show, init, validate, migrate")
+		return exitUsage
+	}
+}
+
+func runConfigShow(args *configShowArgs) int {
+	loader, err := args.loader(nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	dir := args.Dir
+	if dir == "" {
+		dir = "."
+	}
+	conf, _, err := loader.ForDir(dir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	fmt.Println(conf.DescribeEffective())
+	return exitOK
+}
+
+func runConfigInit(args *configInitArgs) int {
+	preset, err := rules.Preset(args.Preset)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	path := args.Config
+	if path == "" {
+		path = rules.UserConfigPath()
+	}
+	if _, err := os.Stat(path); err == nil && !args.Force {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists (use --force to overwrite)\n", path)
+		return exitUsage
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailed
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailed
+	}
+	if args.Full {
+		err = preset.Save(path)
+	} else {
+		data, _ := json.MarshalIndent(map[string]any{"version": rules.ConfigVersion, "extends": preset.Extends}, "", "  ")
+		err = os.WriteFile(path, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailed
+	}
+	fmt.Println("Wrote", path)
+	return exitOK
+}
+
+// runConfigValidate prints FILE:LINE:COL: problems of each config, or FILE: ok.
+func runConfigValidate(args *configValidateArgs) int {
+	paths := args.Input
+	if len(paths) == 0 {
+		if p := rules.UserConfigPath(); FileExists(p) {
+			paths = append(paths, p)
+		}
+		if p := rules.FindDirConfig("."); p != "" {
+			paths = append(paths, p)
+		}
+		if len(paths) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: no config file found (give one as argument)")
+			return exitUsage
+		}
+	}
+	code := exitOK
+	for _, path := range paths {
+		if st, err := os.Stat(path); err == nil && st.IsDir() {
+			dir := path
+			if path = rules.FindDirConfig(dir); path == "" {
+				fmt.Fprintf(os.Stderr, "Error: no %s in %s or its parents\n", rules.DirConfigName, dir)
+				return exitUsage
+			}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+		err = rules.Validate(data)
+		var verrs rules.ValidationErrors
+		switch {
+		case err == nil:
+			fmt.Printf("%s: ok\n", path)
+		case errors.As(err, &verrs):
+			for _, e := range verrs {
+				if e.Path != "" {
+					fmt.Printf("%s:%d:%d: %s: %s\n", path, e.Line, e.Col, e.Path, e.Msg)
+				} else {
+					fmt.Printf("%s:%d:%d: %s\n", path, e.Line, e.Col, e.Msg)
+				}
+			}
+			code = exitIssues
+		default:
+			fmt.Printf("%s: %v\n", path, err)
+			code = exitIssues
+		}
+	}
+	return code
+}
+
+// runConfigMigrate prints (or writes with --write) each config in the current version.
+func runConfigMigrate(args *configMigrateArgs) int {
+	code := exitOK
+	for _, path := range args.Input {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+		out, from, err := rules.Migrate(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s:\n%v\n", path, err)
+			code = exitIssues
+			continue
+		}
+		switch {
+		case !args.Write:
+			os.Stdout.Write(out)
+		case from == rules.ConfigVersion:
+			fmt.Printf("%s: already version %d\n", path, from)
+		default:
+			backup := fmt.Sprintf("%s.v%d.bak", path, from)
+			if err := os.WriteFile(backup, data, 0644); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return exitFailed
+			}
+			if err := os.WriteFile(path, out, 0644); err != nil {
+				fmt.Fprintln(os.Stderr, "Error:", err)
+				return exitFailed
+			}
+			fmt.Printf("%s: migrated from version %d to %d (old file: %s)\n", path, from, rules.ConfigVersion, filepath.Base(backup))
+		}
+	}
+	return code
+}
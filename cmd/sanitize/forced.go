@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/batch"
+	"github.com/luismascotto/subtitle-sanitizer/internal/diff"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+// forcedPath is FILE.forced.srt next to the full track it is built from.
+func forcedPath(inputPath string) string {
+	return strings.TrimSuffix(inputPath, filepath.Ext(inputPath)) + ".forced.srt"
+}
+
+// isForcedPath reports whether path is named like a forced track (FILE.forced.srt).
+func isForcedPath(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".forced.srt")
+}
+
+// processForced builds the forced track of doc (read from subtitlePath) into res and
+// writes it as FILE.forced.srt, replacing an existing one only with overwrite.
+func processForced(res *batch.FileResult, subtitlePath string, data []byte, doc model.Document, prepared transform.Rules, opts batchOptions) {
+	if isForcedPath(subtitlePath) {
+		res.Err = fmt.Errorf("%s is a forced track already", filepath.Base(subtitlePath))
+		return
+	}
+	forced, changes := transform.ApplyForced(doc, prepared)
+	forced.Format = model.SubtitleFormatSRT
+	res.Changes = changes
+	res.CuesChanged = len(forced.Cues)
+	res.CuesRemoved = len(doc.Cues) - len(forced.Cues)
+	for _, ch := range changes {
+		if len(ch.Suppressed) > 0 {
+			res.CuesSuppressed++
+		}
+	}
+	if len(forced.Cues) == 0 {
+		return // no foreign dialogue or on-screen text: nothing to write
+	}
+
+	outPath := forcedPath(subtitlePath)
+	if FileExists(outPath) && !opts.Overwrite {
+		res.Err = fmt.Errorf("%s already exists (use --auto to overwrite)", filepath.Base(outPath))
+		return
+	}
+	res.Output = outPath
+	if opts.DryRun {
+		var sb strings.Builder
+		_, res.Err = diff.Unified(&sb, subtitlePath, outPath,
+			diff.SplitLines(string(data)),
+			diff.SplitLines(string(subtitle.FormatSRT(forced))),
+			diff.UnifiedOptions{Context: diff.DefaultContext, Color: opts.Color})
+		res.Diff = sb.String()
+		return
+	}
+	if err := undoJournal.WriteFile(subtitlePath, data, outPath, subtitle.FormatSRT(forced), false); err != nil {
+		res.Err = fmt.Errorf("write output: %w", err)
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hook"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+type hookArgs struct {
+	configFlags
+	NoBackup  bool   `arg:"--no-backup" help:"do not keep a copy of each subtitle before rewriting it (hook.backup)"`
+	Journal   string `arg:"--journal" help:"journal directory for restore (default: user config dir/subtitle-sanitizer/journal)"`
+	NoJournal bool   `arg:"--no-journal" help:"do not journal writes"`
+
+	Bazarr   *hookBazarrArgs `arg:"subcommand:bazarr" help:"Bazarr custom post-processing, eg: hook bazarr --subtitle \"{{subtitles}}\" --language \"{{subtitles_language_code2}}\" --episode \"{{episode}}\""`
+	Sonarr   *hookEnvArgs    `arg:"subcommand:sonarr" help:"Sonarr custom script connection: sanitizes the subtitles next to imported episodes"`
+	Radarr   *hookEnvArgs    `arg:"subcommand:radarr" help:"Radarr custom script connection: sanitizes the subtitles next to imported movies"`
+	Jellyfin *hookPathsArgs  `arg:"subcommand:jellyfin" help:"subtitle files, or media files whose subtitles to sanitize (Jellyfin tasks, manual runs)"`
+}
+
+type hookBazarrArgs struct {
+	Subtitle string `arg:"--subtitle,required" help:"the subtitle Bazarr wrote: {{subtitles}}"`
+	Language string `arg:"--language" help:"its language: {{subtitles_language_code2}} or {{subtitles_language_code3}} (default: from the file name)"`
+	Episode  string `arg:"--episode" help:"the episode or movie file: {{episode}}"`
+}
+
+// hookEnvArgs is empty: Sonarr and Radarr pass everything in sonarr_*/radarr_* variables.
+type hookEnvArgs struct{}
+
+type hookPathsArgs struct {
+	Paths []string `arg:"positional,required" help:"subtitle or media files"`
+}
+
+// runHook sanitizes, in place, the SRT subtitles a media tool's post-processing call
+// names, each with the preset of its language (ASS ones are skipped). A copy of each original is kept (FILE.bak) and
+// the writes are journaled. It exits 0 when done or when there is nothing to do (Sonarr
+// and Radarr tests and other events), 1 when a subtitle failed and 2 when the call
+// itself could not be read, so the tools log the failure.
+func runHook(argv []string) int {
+	var args hookArgs
+	mustParseSubcommand("hook", &args, argv)
+	headless = true
+
+	var tool hook.Tool
+	var in hook.Input
+	switch {
+	case args.Bazarr != nil:
+		tool, in = hook.Bazarr, hook.Input{Subtitle: args.Bazarr.Subtitle, Language: args.Bazarr.Language, Media: args.Bazarr.Episode}
+	case args.Sonarr != nil:
+		tool = hook.Sonarr
+	case args.Radarr != nil:
+		tool = hook.Radarr
+	case args.Jellyfin != nil:
+		tool, in = hook.Jellyfin, hook.Input{Paths: args.Jellyfin.Paths}
+	default:
+		fmt.Fprintln(os.Stderr, "Error: expected a subcommand: bazarr, sonarr, radarr, jellyfin")
+		return exitUsage
+	}
+	call, err := hook.Read(tool, in)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	switch {
+	case call.IsTest():
+		fmt.Printf("%s: connection test ok\n", tool)
+		return exitOK
+	case len(call.Subtitles) == 0 && call.Event != "" && call.Event != hook.EventDownload:
+		fmt.Printf("%s: %s event ignored\n", tool, call.Event)
+		return exitOK
+	case len(call.Subtitles) == 0:
+		fmt.Printf("%s: no subtitles for %s\n", tool, call.Media)
+		return exitOK
+	}
+
+	configs, err := args.loader(nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	if _, err := configs.Base(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	if !args.NoJournal {
+		if undoJournal, err = openJournal(args.Journal); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+	}
+
+	h := hookRunner{args: args, configs: configs, ruleSets: map[string]*ruleSet{"": newRuleSet(configs)}}
+	failed := 0
+	for _, sub := range call.Subtitles {
+		if err := h.sanitize(sub); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v\n", sub.Path, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// hookRunner sanitizes the subtitles of one call, sharing the prepared rules per preset.
+type hookRunner struct {
+	args     hookArgs
+	configs  *rules.Loader
+	ruleSets map[string]*ruleSet // preset ("" the config's own) -> rules
+}
+
+func (h *hookRunner) sanitize(sub hook.Subtitle) error {
+	if subtitle.FormatFromPath(sub.Path) != model.SubtitleFormatSRT {
+		// The output is SRT: rewriting ASS in place would replace the file the tool
+		// tracks with one of another name.
+		fmt.Printf("%s: skipped, only SRT is rewritten in place (convert it with subtitle-sanitizer --auto)\n", sub.Path)
+		return nil
+	}
+	conf, _, err := h.configs.ForDir(filepath.Dir(sub.Path))
+	if err != nil {
+		return err
+	}
+	hookConf := rules.DefaultHookConfig()
+	if conf.Hook != nil {
+		hookConf = *conf.Hook
+	}
+	preset := ""
+	if h.args.Preset == "" {
+		preset = hookConf.PresetFor(sub.Language)
+	}
+	rs, ok := h.ruleSets[preset]
+	if !ok {
+		flags := h.args.configFlags
+		flags.Preset = preset
+		loader, err := flags.loader(nil)
+		if err != nil {
+			return err
+		}
+		rs = newRuleSet(loader)
+		h.ruleSets[preset] = rs
+	}
+
+	original, err := os.ReadFile(sub.Path)
+	if err != nil {
+		return err
+	}
+	// The backup is written first so the original is never only in memory; it is taken
+	// back when nothing was rewritten.
+	suffix := hookConf.BackupSuffix()
+	if h.args.NoBackup {
+		suffix = ""
+	}
+	backup, err := hook.Backup(sub.Path, original, suffix)
+	if err != nil {
+		return err
+	}
+	res := processFile(sub.Path, rs, batchOptions{Overwrite: true})
+	if res.Output == "" && backup != "" {
+		os.Remove(backup)
+		backup = ""
+	}
+	if res.Err != nil {
+		return res.Err
+	}
+
+	note := ""
+	if preset != "" {
+		note = ", preset " + preset
+	}
+	if backup != "" {
+		note += ", backup " + filepath.Base(backup)
+	}
+	if sub.Language != "" {
+		note = ", " + sub.Language + note
+	}
+	if res.Output == "" {
+		fmt.Printf("%s: no changes%s%s\n", sub.Path, suppressedNote(res.CuesSuppressed), note)
+		return nil
+	}
+	fmt.Printf("%s -> %s (%d changed, %d removed%s%s)\n", sub.Path, filepath.Base(res.Output),
+		res.CuesChanged, res.CuesRemoved, suppressedNote(res.CuesSuppressed), note)
+	return nil
+}
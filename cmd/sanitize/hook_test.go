@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/journal"
+)
+
+func TestRunHook(t *testing.T) {
+	t.Cleanup(func() { undoJournal = nil })
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(conf, []byte(`{}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	srt, ass := filepath.Join(dir, "Movie.en.srt"), filepath.Join(dir, "Movie.en.ass")
+	original := "1\n00:00:01,000 --> 00:00:02,000\n(sighs) Hello\n\n"
+	assData := "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,(sighs) Hi\n"
+	for path, data := range map[string]string{srt: original, ass: assData} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	journalDir := filepath.Join(dir, "journal")
+	flags := []string{"--config", conf, "--journal", journalDir}
+	read := func(path string) string {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	if code := runHook(append(flags, "jellyfin", filepath.Join(dir, "Movie.mkv"))); code != exitOK {
+		t.Fatalf("code = %d", code)
+	}
+	if got, want := read(srt), "1\n00:00:01,000 --> 00:00:02,000\nHello\n"; got != want {
+		t.Fatalf("srt = %q, want %q", got, want)
+	}
+	if got := read(srt + ".bak"); got != original {
+		t.Fatalf("backup = %q", got)
+	}
+	if got := read(ass); got != assData {
+		t.Fatalf("ass rewritten: %q", got)
+	}
+	j, err := journal.Open(journalDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entries, err := j.Entries(); err != nil || len(entries) != 1 || entries[0].Output != srt {
+		t.Fatalf("journal = %+v, %v", entries, err)
+	}
+
+	// Nothing left to change: the backup keeps the first original.
+	if code := runHook(append(flags, "jellyfin", srt)); code != exitOK {
+		t.Fatalf("second run: code = %d", code)
+	}
+	if got := read(srt + ".bak"); got != original {
+		t.Fatalf("backup after second run = %q", got)
+	}
+
+	if code := runHook(append(flags, "jellyfin", filepath.Join(dir, "Gone.en.srt"))); code != exitFailed {
+		t.Fatalf("missing subtitle: code = %d, want %d", code, exitFailed)
+	}
+	t.Setenv("sonarr_eventtype", "Test")
+	if code := runHook(append(flags, "sonarr")); code != exitOK {
+		t.Fatalf("sonarr test: code = %d", code)
+	}
+	t.Setenv("sonarr_eventtype", "")
+	if code := runHook(append(flags, "sonarr")); code != exitUsage {
+		t.Fatalf("sonarr without event: code = %d, want %d", code, exitUsage)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alexflint/go-arg"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/lint"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+type lintArgs struct {
+	Input  []string `arg:"positional,required" help:"subtitle files to check (.srt, .ass)"`
+	Preset string   `arg:"-p,--preset" help:"threshold preset: netflix, bbc (default: config lint.preset or netflix)"`
+	Config string   `arg:"-c,--config" help:"config file used instead of the user one; its \"lint\" section overrides the preset"`
+	Format string   `arg:"-f,--format" help:"report format: text, json, junit" default:"text"`
+	Output string   `arg:"-o,--output" help:"write the report to this file instead of stdout"`
+	Strict bool     `arg:"--strict" help:"fail on warnings too"`
+}
+
+func runLint(argv []string) int {
+	var args lintArgs
+	mustParseSubcommand("lint", &args, argv)
+
+	configs, err := rules.NewLoader(args.Config, rules.Layer{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	conf, err := configs.Base()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	th, err := lint.ThresholdsFromConfig(conf.Lint, args.Preset)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+
+	reports := make([]lint.FileReport, 0, len(args.Input))
+	failed := false
+	for _, path := range args.Input {
+		report := lintFile(path, th)
+		errs, _ := report.Counts()
+		if errs > 0 || (args.Strict && len(report.Issues) > 0) {
+			failed = true
+		}
+		reports = append(reports, report)
+	}
+
+	var out io.Writer = os.Stdout
+	if args.Output != "" {
+		f, err := os.Create(args.Output)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+		defer f.Close()
+		out = f
+	}
+	switch strings.ToLower(args.Format) {
+	case "text":
+		err = lint.WriteText(out, reports)
+	case "json":
+		err = lint.WriteJSON(out, reports)
+	case "junit", "junit-xml", "xml":
+		err = lint.WriteJUnit(out, reports, args.Strict)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown report format %q (text, json, junit)\n", args.Format)
+		return exitUsage
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing report:", err)
+		return exitUsage
+	}
+
+	if failed {
+		return exitIssues
+	}
+	return exitOK
+}
+
+func lintFile(path string, th lint.Thresholds) lint.FileReport {
+	report := lint.FileReport{Path: path}
+	format := subtitle.FormatFromPath(path)
+	if format == model.SubtitleFormatUnknown {
+		report.Error = "unsupported extension (only .srt, .ass)"
+		return report
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	var doc *model.Document
+	if format == model.SubtitleFormatSRT {
+		var diags []subtitle.Diagnostic
+		doc, diags, err = subtitle.ParseSRTDiagnostics(data, true)
+		report.Issues = lint.ParseIssues(diags)
+	} else {
+		doc, err = subtitle.Parse(data, format)
+	}
+	if err != nil {
+		report.Error = err.Error()
+		return report
+	}
+	report.Cues = len(doc.Cues)
+	report.Issues = append(report.Issues, lint.Document(*doc, th)...)
+	return report
+}
+
+// mustParseSubcommand parses argv into dest, printing help or usage errors and exiting like arg.MustParse.
+func mustParseSubcommand(name string, dest any, argv []string) {
+	p, err := arg.NewParser(arg.Config{Program: "subtitle-sanitizer " + name}, dest)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(exitUsage)
+	}
+	p.MustParse(argv)
+}
@@ -1,91 +1,258 @@
 package main
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	tea "charm.land/bubbletea/v2"
 	"github.com/alexflint/go-arg"
 
-	"github.com/luismascotto/subtitle-sanitizer/internal/mkv"
+	"github.com/luismascotto/subtitle-sanitizer/internal/batch"
+	"github.com/luismascotto/subtitle-sanitizer/internal/container"
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/imagesub"
 	"github.com/luismascotto/subtitle-sanitizer/internal/model"
 	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
 	"github.com/luismascotto/subtitle-sanitizer/internal/sanitize"
 	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
-	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
 	"github.com/luismascotto/subtitle-sanitizer/internal/view"
 )
 
+// subcommands run instead of the interactive sanitize flow when named as the first argument.
+// Each returns the process exit code.
+var subcommands = map[string]func(args []string) int{
+	"config":  runConfig,
+	"hook":    runHook,
+	"lint":    runLint,
+	"ocr":     runOCR,
+	"restore": runRestore,
+	"serve":   runServe,
+	"watch":   runWatch,
+}
+
+// Exit codes for headless runs and subcommands.
+const (
+	exitOK     = 0
+	exitFailed = 1 // sanitize: at least one input could not be processed
+	exitIssues = 1 // lint found errors (or warnings with --strict)
+	exitUsage  = 2 // bad flags, no inputs, unreadable config, unknown preset
+)
+
+// headless disables every TUI and the pause before exiting on errors.
+var headless bool
+
 func main() {
+	if len(os.Args) > 1 {
+		if run, ok := subcommands[os.Args[1]]; ok {
+			os.Exit(run(os.Args[2:]))
+		}
+	}
+
 	var args struct {
+		configFlags
 		Input        []string `arg:"positional"`
 		IgnoreErrors bool     `arg:"-i,--ignore-errors" help:"ignore minor errors" default:"true"`
-		MkvExtract   bool     `arg:"-m,--mkv-extract" help:"extract all subtitles from mkv files" default:"false"`
+		MkvExtract   bool     `arg:"-m,--mkv-extract" help:"extract all subtitles from video files (mkv, webm, mp4, m4v, mov, ts, m2ts, avi)" default:"false"`
 		Auto         bool     `arg:"-a,--auto" help:"auto apply transformations and overwrite" default:"false"`
+		Recursive    bool     `arg:"-r,--recursive" help:"recurse into subdirectories of directory inputs"`
+		Include      []string `arg:"--include,separate" help:"batch: only process files matching this pattern (repeatable)"`
+		Exclude      []string `arg:"--exclude,separate" help:"batch: skip files and directories matching this pattern (repeatable)"`
+		Jobs         int      `arg:"-j,--jobs" help:"batch: files processed in parallel (default: CPU count)"`
+		Headless     bool     `arg:"--headless" help:"no TUI: sanitize every input without review and exit (implied by --report)"`
+		Report       string   `arg:"--report" help:"headless: write one record per file to stdout: json, ndjson, csv"`
+		ReportFile   string   `arg:"--report-file" help:"write the --report to this file instead of stdout"`
+		DryRun       bool     `arg:"-n,--dry-run" help:"write nothing; print per-file progress and the unified diff of each output"`
+		Diff         bool     `arg:"--diff" help:"write nothing; print only the unified diff of each output"`
+		Track        string   `arg:"-t,--track" help:"video: subtitle track to sanitize, eg: lang=spa,!sdh or index=3 (default: ask when there are several)"`
+		Remux        string   `arg:"--remux" help:"video: also write file.clean.mkv with the sanitized track added (add) or replacing the source track (replace)"`
+		NameTemplate string   `arg:"--name-template" help:"-m: extracted file names, eg: {base}.{lang2}{.forced}{.sdh}.{ext} (fields: base lang lang2 index codec title ext forced sdh default)"`
+		Sidecar      bool     `arg:"--sidecar" help:"-m: also write SUBTITLE.json with the source stream index, codec, title and flags"`
+		SkipSDH      bool     `arg:"--skip-sdh" help:"-m: skip SDH tracks (flagged, titled or detected by content) when the language has a plain track"`
+		OCR          string   `arg:"--ocr" help:"image subtitles (.sup, .idx, PGS tracks): OCR engine auto, glyph, tesseract" default:"auto"`
+		OCRDict      string   `arg:"--ocr-dict" help:"glyph OCR dictionary (default: user config dir/subtitle-sanitizer/glyphs.json)"`
+		OCRLang      string   `arg:"--ocr-lang" help:"tesseract language, eg: eng, spa+eng" default:"eng"`
+		Forced       bool     `arg:"--forced" help:"write FILE.forced.srt with only foreign-language and on-screen text cues (no review; --auto overwrites)"`
+		Journal      string   `arg:"--journal" help:"journal directory keeping what each write replaced, for restore (default: user config dir/subtitle-sanitizer/journal)"`
+		NoJournal    bool     `arg:"--no-journal" help:"do not journal writes (restore cannot undo them)"`
+		StdinFormat  string   `arg:"--stdin-format" help:"input -: format of the subtitle read from stdin (srt, ass); the SRT is streamed to stdout" default:"srt"`
+	}
+	p := arg.MustParse(&args)
+	dryRun := args.DryRun || args.Diff
+	headless = args.Headless || args.Report != "" || dryRun
+	if dryRun && args.MkvExtract {
+		p.Fail("--dry-run/--diff cannot be combined with --mkv-extract")
+	}
+	if args.Forced && (args.MkvExtract || args.Remux != "") {
+		p.Fail("--forced cannot be combined with --mkv-extract or --remux")
+	}
+	trackSel, err := container.ParseSelector(args.Track)
+	if err != nil {
+		p.Fail(err.Error())
+	}
+	var remux container.RemuxMode
+	if args.Remux != "" {
+		if remux, err = container.ParseRemuxMode(args.Remux); err != nil {
+			p.Fail(err.Error())
+		}
+	}
+	ocr := ocrOptions{Engine: args.OCR, Dict: args.OCRDict, Lang: args.OCRLang}
+	if err := ocr.validate(); err != nil {
+		p.Fail(err.Error())
 	}
-	arg.MustParse(&args)
-
-	normalizePwdPath()
 
-	mkvDependenciesError := mkv.VerifyDependencies()
+	if slices.Contains(args.Input, stdinInput) {
+		if len(args.Input) > 1 || args.MkvExtract || args.Forced || dryRun || args.Report != "" {
+			p.Fail("input - (stdin) is read alone, without --mkv-extract, --forced, --dry-run, --diff or --report")
+		}
+		format, err := parseStdinFormat(args.StdinFormat)
+		if err != nil {
+			p.Fail(err.Error())
+		}
+		configs, err := args.loader(nil)
+		if err != nil {
+			exitWithCode(exitUsage, err)
+		}
+		if _, err := configs.Base(); err != nil {
+			exitWithCode(exitUsage, err)
+		}
+		os.Exit(runStdin(os.Stdin, os.Stdout, os.Stderr, newRuleSet(configs), format))
+	}
 
-	if len(args.Input) == 0 {
-		exitWithErr(errors.New("no input files provided"))
+	if !dryRun && !args.NoJournal {
+		if undoJournal, err = openJournal(args.Journal); err != nil {
+			exitWithCode(exitUsage, err)
+		}
 	}
-	for _, inputPath := range args.Input {
-		ext := strings.ToLower(filepath.Ext(inputPath))
-		if ext == ".mkv" && mkvDependenciesError != nil {
-			exitWithErr(mkvDependenciesError)
+
+	// Directories and glob patterns switch to non-interactive batch mode.
+	batchMode := batch.NeedsExpansion(args.Input)
+	if batchMode {
+		exclude := args.Exclude
+		if args.Forced {
+			exclude = append(exclude, forcedBatchExcludes...)
 		}
+		files, err := expandBatchInputs(args.Input, args.MkvExtract, args.Recursive, args.Include, exclude)
+		if err != nil {
+			exitWithCode(exitUsage, err)
+		}
+		args.Input = files
+	} else {
+		args.Input = absPaths(args.Input)
 	}
 
-	conf := rules.LoadDefaultOrEmpty()
-	prepared := transform.NewRules(conf)
+	if len(args.Input) == 0 {
+		exitWithCode(exitUsage, errors.New("no input files provided"))
+	}
 
-	rulesDisplay := conf.DescribeEffective()
-	backupJSON, err := json.MarshalIndent(conf, "", "  ")
+	configs, err := args.loader(extractFlags(args.NameTemplate, args.Sidecar, args.SkipSDH))
+	if err != nil {
+		exitWithCode(exitUsage, err)
+	}
+	conf, err := configs.Base()
 	if err != nil {
-		exitWithErr(fmt.Errorf("marshal config backup: %w", err))
+		exitWithCode(exitUsage, err)
 	}
-	if !conf.LoadedFromFile {
-		_ = conf.SaveToBackupFile(backupJSON)
+	ruleSets := newRuleSet(configs)
+	extractOpts, err := extractOptions(conf.Extract)
+	if err != nil {
+		exitWithCode(exitUsage, err)
+	}
+
+	if headless {
+		report, closeReport, err := openReport(args.Report, args.ReportFile)
+		if err != nil {
+			exitWithCode(exitUsage, err)
+		}
+		var code int
+		if args.MkvExtract {
+			code = runExtractHeadless(args.Input, extractOpts)
+		} else {
+			opts := batchOptions{
+				Overwrite: args.Auto,
+				Jobs:      args.Jobs,
+				Progress:  os.Stderr,
+				Report:    report,
+				DryRun:    dryRun,
+				Track:     trackSel,
+				Remux:     remux,
+				OCR:       ocr,
+				Forced:    args.Forced,
+			}
+			if args.Diff {
+				opts.Progress = io.Discard
+			}
+			if dryRun && (report == nil || args.ReportFile != "") {
+				// With the report on stdout the diffs are only in its records.
+				opts.Diff = os.Stdout
+				opts.Color = colorOutput(os.Stdout)
+			}
+			code = runBatch(args.Input, ruleSets, opts)
+		}
+		if err := closeReport(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing report:", err)
+			code = exitFailed
+		}
+		os.Exit(code)
 	}
 	if args.MkvExtract {
 		// batchModel := view.NewBatchModel(args.Input)
-		if _, err := tea.NewProgram(view.NewBatchModel(args.Input)).Run(); err != nil {
+		if _, err := tea.NewProgram(view.NewBatchModel(args.Input, extractOpts)).Run(); err != nil {
 			exitWithErr(fmt.Errorf("run batch tea program: %w", err))
 		}
 		time.Sleep(1 * time.Second)
 		return
 	}
+	if batchMode || args.Forced {
+		os.Exit(runBatch(args.Input, ruleSets, batchOptions{
+			Overwrite: args.Auto,
+			Jobs:      args.Jobs,
+			Progress:  os.Stdout,
+			Track:     trackSel,
+			Remux:     remux,
+			OCR:       ocr,
+			Forced:    args.Forced,
+		}))
+	}
 
 	for _, inputPath := range args.Input {
 		var data []byte
-		var err error
+		var track *container.Track // video inputs: the sanitized track
+		videoPath := inputPath
+		conf, prepared, err := ruleSets.forFile(inputPath)
+		if err != nil {
+			exitWithCode(exitUsage, err)
+		}
+		rulesDisplay := conf.DescribeEffective()
 
 		ext := strings.ToLower(filepath.Ext(inputPath))
 		if ext == "" {
 			exitWithErr(fmt.Errorf("extension is empty"))
 		}
 
-		if ext == ".mkv" {
+		if container.IsContainer(inputPath) {
+			picked, ok := pickTrack(inputPath, trackSel, args.Auto)
+			if !ok {
+				break
+			}
+			track = &picked
 			loader := tea.NewProgram(view.NewLoaderModel())
 
 			go func() {
-				loader.Send(view.LoaderMsg{Message: fmt.Sprintf("Extracting from %s", filepath.Base(inputPath)), Quit: false})
-				inputPath, data, err = mkv.ExtractSingleSubtitle(inputPath)
+				loader.Send(view.LoaderMsg{Message: fmt.Sprintf("Extracting track %d from %s", track.Index, filepath.Base(inputPath)), Quit: false})
+				inputPath, data, err = container.ExtractTrack(inputPath, *track)
 				if err != nil {
-					loader.Send(view.LoaderMsg{Message: "Error extracting subtitles from MKV file", Quit: false})
+					loader.Send(view.LoaderMsg{Message: "Error extracting subtitles from video file", Quit: false})
 					time.Sleep(2 * time.Second)
-					loader.Send(view.LoaderMsg{Message: "Error extracting subtitles from MKV file", Quit: true})
+					loader.Send(view.LoaderMsg{Message: "Error extracting subtitles from video file", Quit: true})
 				} else {
 					loader.Send(view.LoaderMsg{Message: "Subtitles extracted successfully", Quit: true})
 				}
@@ -96,7 +263,7 @@ func main() {
 			}
 
 			if err != nil {
-				exitWithErr(fmt.Errorf("extract mkv subtitles: %w", err))
+				exitWithErr(fmt.Errorf("extract subtitles: %w", err))
 			}
 
 			ext = strings.ToLower(filepath.Ext(inputPath))
@@ -107,6 +274,14 @@ func main() {
 			data = ReadFileContent(inputPath)
 		}
 
+		if imagesub.IsImageSubtitle(inputPath) {
+			fmt.Printf("Recognizing %s...\n", filepath.Base(inputPath))
+			if inputPath, data, err = ocrSubtitle(inputPath, data, ocr, true); err != nil {
+				exitWithErr(err)
+			}
+			ext = ".srt"
+		}
+
 		if len(data) == 0 {
 			exitWithErr(fmt.Errorf("data is empty"))
 		}
@@ -126,37 +301,106 @@ func main() {
 		}
 
 		transformations := sanitize.ApplyRules(*doc, prepared)
+		score := hi.Analyze(*doc)
 
 		var final *model.Document
 		var optApply, optOverwrite bool
 		if args.Auto {
+			fmt.Printf("%s: %s\n", filepath.Base(inputPath), score)
 			final = &transformations.Document
 			optApply = true
 			optOverwrite = true
 		} else {
-			result, retModel := RenderTransformations(rulesDisplay, inputPath, &transformations)
-			retModelCheck, ok := retModel.(view.ReviewTransformationsModel)
-			if !ok {
-				exitWithErr(errors.New("retModel is not of type UIModel"))
+			retModel := RenderTransformations(rulesDisplay, filepath.Base(inputPath)+hiNote(&score), *doc, &transformations)
+			if len(retModel.CuesContaining) > 0 {
+				if err := ruleSets.allowCuesContaining(retModel.CuesContaining); err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
 			}
-			if retModelCheck.Quit {
+			if retModel.Quit {
 				break
 			}
-			if retModelCheck.Skip {
+			if retModel.Skip {
 				continue
 			}
-			if retModelCheck.Apply {
-				final = &result
+			if retModel.Apply {
+				selected := sanitize.Select(*doc, transformations.Changes, retModel.Decisions)
+				final = &selected
 			} else {
 				final = doc
 			}
-			optApply = retModelCheck.Apply
-			optOverwrite = retModelCheck.Overwrite
+			optApply = retModel.Apply
+			optOverwrite = retModel.Overwrite
+		}
+		outPath := ApplyTransformations(inputPath, data, final, optApply, optOverwrite)
+		if remux != "" && track != nil && outPath != "" {
+			fmt.Printf("Remuxing %s into %s...\n", filepath.Base(outPath), filepath.Base(container.RemuxOutputPath(videoPath)))
+			if _, err := container.Remux(videoPath, *track, outPath, remux); err != nil {
+				exitWithErr(err)
+			}
 		}
-		ApplyTransformations(inputPath, final, optApply, optOverwrite)
 	}
 }
 
+// extractFlags are the -m flags as config values ("extract" section of the flags layer).
+func extractFlags(nameTemplate string, sidecar, skipSDH bool) map[string]any {
+	values := map[string]any{}
+	if nameTemplate != "" {
+		values["extract.nameTemplate"] = nameTemplate
+	}
+	if sidecar {
+		values["extract.sidecar"] = true
+	}
+	if skipSDH {
+		values["extract.skipSdh"] = true
+	}
+	return values
+}
+
+// extractOptions reads the "extract" section of the effective config.
+func extractOptions(conf *rules.ExtractConfig) (container.ExtractOptions, error) {
+	if conf == nil {
+		conf = &rules.ExtractConfig{}
+	}
+	tmpl, err := container.ParseNameTemplate(conf.NameTemplate)
+	if err != nil {
+		return container.ExtractOptions{}, err
+	}
+	return container.ExtractOptions{Template: tmpl, Sidecar: conf.Sidecar, SkipSDH: conf.SkipSDH}, nil
+}
+
+// pickTrack chooses the subtitle track of a video: the preferred match of sel when given
+// (or with auto), the only track, or the one picked in the track chooser. ok is false
+// when the user quits the chooser.
+func pickTrack(inputPath string, sel container.Selector, auto bool) (track container.Track, ok bool) {
+	tracks, err := container.ProbeTracks(inputPath)
+	if err != nil {
+		exitWithErr(fmt.Errorf("probe subtitles: %w", err))
+	}
+	// The chooser shows the HI scores of every track.
+	chooser := sel.IsZero() && !auto && len(tracks) > 1
+	if chooser || sel.NeedsAnalysis(tracks) {
+		container.AnalyzeTracks(inputPath, tracks)
+	}
+	track, err = container.SelectTrack(tracks, sel)
+	if err != nil {
+		exitWithErr(err)
+	}
+	if !chooser {
+		return track, true
+	}
+
+	ret, err := tea.NewProgram(view.NewTrackPickerModel(filepath.Base(inputPath), tracks, track.Index)).Run()
+	if err != nil {
+		exitWithErr(fmt.Errorf("run track picker: %w", err))
+	}
+	picker, ok := ret.(view.TrackPickerModel)
+	if !ok {
+		exitWithErr(errors.New("retModel is not of type TrackPickerModel"))
+	}
+	return picker.Selected, picker.Chosen
+}
+
 func ReadFileContent(inputPath string) []byte {
 	if inputPath == "" {
 		exitWithErr(errors.New("missing input file(s)"))
@@ -179,50 +423,65 @@ func ReadFileContent(inputPath string) []byte {
 	return data
 }
 
-func ApplyTransformations(inputPath string, result *model.Document, apply, overwrite bool) {
+// ApplyTransformations writes the output and returns its path ("" when nothing was written).
+func ApplyTransformations(inputPath string, original []byte, result *model.Document, apply, overwrite bool) string {
+	outPath, err := writeOutput(inputPath, original, result, apply, overwrite)
+	if err != nil {
+		exitWithErr(err)
+	}
+	return outPath
+}
+
+// outputMu serializes output path derivation and writes so parallel batch workers
+// cannot pick the same free name.
+var outputMu sync.Mutex
+
+// writeOutput saves result as SRT next to inputPath (whose bytes were original) and
+// returns the written path ("" when there was nothing to write). What it replaces or
+// deletes is kept in the undo journal.
+func writeOutput(inputPath string, original []byte, result *model.Document, apply, overwrite bool) (string, error) {
 	if result.Format == model.SubtitleFormatSRT && overwrite && !apply {
-		return
+		return "", nil
 	}
-	outPath := deriveOutputPath(inputPath, overwrite)
+	outputMu.Lock()
+	defer outputMu.Unlock()
 
-	outData := subtitle.FormatSRT(*result) // Always save as .srt
-	if err := os.WriteFile(outPath, outData, 0644); err != nil {
-		exitWithErr(fmt.Errorf("write output: %w", err))
+	outPath, err := outputPath(inputPath, overwrite)
+	if err != nil {
+		return "", err
 	}
 
-	if result.Format == model.SubtitleFormatASS && overwrite {
-		_ = os.Remove(inputPath)
+	if err := saveOutput(inputPath, original, result, outPath, result.Format == model.SubtitleFormatASS && overwrite); err != nil {
+		return "", err
 	}
+	return outPath, nil
 }
 
-func RenderTransformations(rulesDisplay string, inputPath string, transformations *sanitize.Result) (model.Document, tea.Model) {
-	sbContent := strings.Builder{}
-	sbContent.WriteString("\n\n# Subtitle Sanitizer\n\n## Active rules\n\n```\n")
-	sbContent.WriteString(rulesDisplay)
-	sbContent.WriteString("\n```\n\n")
-
-	sbContent.WriteString("## " + filepath.Base(inputPath) + "\n")
-
-	sbContent.WriteString("## Transformations\n")
-	if len(transformations.Changes) > 0 {
-		sbContent.WriteString("| Pos# | Original | Transformed/removed/empty | Rules |\n")
-		sbContent.WriteString("| --- | --- | --- | --- |\n")
-		sbContent.WriteString(transform.MarkdownRows(transformations.Changes))
-	} else {
-		sbContent.WriteString("Nothing to remove...\n")
+// saveOutput writes result as SRT to outPath, creating its directory, and deletes
+// inputPath afterwards with removeInput. Both go through the undo journal.
+func saveOutput(inputPath string, original []byte, result *model.Document, outPath string, removeInput bool) error {
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return fmt.Errorf("write output: %w", err)
 	}
-
-	vpModel, err := view.NewViewPortModel(sbContent.String())
-	if err != nil {
-		exitWithErr(fmt.Errorf("new model: %w", err))
+	outData := subtitle.FormatSRT(*result) // Always save as .srt
+	if err := undoJournal.WriteFile(inputPath, original, outPath, outData, removeInput); err != nil {
+		return fmt.Errorf("write output: %w", err)
 	}
+	return nil
+}
 
-	retModel, err := tea.NewProgram(vpModel).Run()
+// RenderTransformations runs the per-change review screen for one file.
+func RenderTransformations(rulesDisplay string, title string, original model.Document, transformations *sanitize.Result) view.ReviewTransformationsModel {
+	reviewModel := view.NewReviewModel(title, rulesDisplay, original, transformations.Changes)
+	retModel, err := tea.NewProgram(reviewModel).Run()
 	if err != nil {
 		exitWithErr(fmt.Errorf("run tea program: %w", err))
 	}
-	//time.Sleep(1 * time.Second)
-	return transformations.Document, retModel
+	retModelCheck, ok := retModel.(view.ReviewTransformationsModel)
+	if !ok {
+		exitWithErr(errors.New("retModel is not of type ReviewTransformationsModel"))
+	}
+	return retModelCheck
 }
 
 func validateInputPath(p string) error {
@@ -235,37 +494,36 @@ func validateInputPath(p string) error {
 	}
 	ext := strings.ToLower(filepath.Ext(p))
 	switch ext {
-	case ".srt", ".ass":
+	case ".srt", ".ass", ".sup", ".idx":
 		return nil
 	default:
-		return fmt.Errorf("unsupported extension: %s (only .srt, .ass)", ext)
+		return fmt.Errorf("unsupported extension: %s (only .srt, .ass, .sup, .idx)", ext)
 	}
 }
 
-func deriveOutputPath(inputPath string, overwrite bool) string {
+func outputPath(inputPath string, overwrite bool) (string, error) {
 	dir := filepath.Dir(inputPath)
 	base := filepath.Base(inputPath)
 	name := strings.TrimSuffix(base, filepath.Ext(base))
 	newName := filepath.Join(dir, name+".srt")
 	if !FileExists(newName) || overwrite {
 		// Happy path .ass to .srt
-		return newName
+		return newName, nil
 	}
 
 	newName = filepath.Join(dir, name+"-his.srt")
 	if !FileExists(newName) {
 		// Happy path .srt to -his.srt
-		return newName
+		return newName, nil
 	}
 
 	for range 5 {
 		newName = filepath.Join(dir, name+"-his_"+strconv.FormatInt(int64(rand.Intn(1000)), 16)+".srt")
 		if !FileExists(newName) {
-			return newName
+			return newName, nil
 		}
 	}
-	exitWithErr(errors.New("failed to derive output path"))
-	return ""
+	return "", errors.New("failed to derive output path")
 }
 
 func FileExists(path string) bool {
@@ -274,42 +532,28 @@ func FileExists(path string) bool {
 }
 
 func exitWithErr(err error) {
-	fmt.Fprintln(os.Stderr, "Error:", err)
-	fmt.Println("Exiting with error:", err)
-	time.Sleep(5 * time.Second)
-	os.Exit(1)
+	exitWithCode(exitFailed, err)
 }
 
-func normalizePwdPath() {
-	wd, err := os.Getwd()
-	if err != nil {
-		exitWithErr(fmt.Errorf("get working directory: %w", err))
-	}
-	//fmt.Printf("Current working directory: %s\n", wd)
-
-	// 1. Get the absolute path of the running executable.
-	executablePath, err := os.Executable()
-	if err != nil {
-		exitWithErr(fmt.Errorf("get executable path: %w", err))
-	}
-
-	// 2. Extract the directory part from the full path.
-	// filepath.Dir is used for cross-platform compatibility.
-	executableDir := filepath.Dir(executablePath)
-	//fmt.Printf("Executable directory: %s\n", executableDir)
-
-	if wd == executableDir {
-		return
+// exitWithCode reports err on stderr and exits. Interactive runs also echo it to stdout
+// and pause so the message stays visible when the console window closes.
+func exitWithCode(code int, err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	if !headless {
+		fmt.Println("Exiting with error:", err)
+		time.Sleep(5 * time.Second)
 	}
+	os.Exit(code)
+}
 
-	// 3. Change the current working directory to the executable's directory.
-	if err := os.Chdir(executableDir); err != nil {
-		exitWithErr(fmt.Errorf("change working directory: %w", err))
+// absPaths makes inputs absolute so progress lines and reports show full paths.
+func absPaths(inputs []string) []string {
+	out := make([]string, len(inputs))
+	for i, in := range inputs {
+		out[i] = in
+		if abs, err := filepath.Abs(in); err == nil {
+			out[i] = abs
+		}
 	}
-
-	// newWd, err := os.Getwd()
-	// if err != nil {
-	// 	exitWithErr(fmt.Errorf("get working directory: %w", err))
-	// }
-	// fmt.Printf("New current working directory: %s\n", newWd)
+	return out
 }
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/imagesub"
+)
+
+// ocrOptions selects how image subtitles (.sup, .idx/.sub, PGS tracks) become text.
+type ocrOptions struct {
+	Engine string // auto, glyph, tesseract
+	Dict   string // glyph dictionary file
+	Lang   string // tesseract language
+}
+
+type ocrArgs struct {
+	Input  []string `arg:"positional,required" help:"image subtitles to convert (.sup, .idx with its .sub)"`
+	Engine string   `arg:"-e,--engine" help:"OCR engine: auto (tesseract when installed, else glyph), glyph, tesseract" default:"auto"`
+	Dict   string   `arg:"-d,--dict" help:"glyph dictionary (default: user config dir/subtitle-sanitizer/glyphs.json)"`
+	Lang   string   `arg:"-l,--lang" help:"tesseract language, eg: eng, spa+eng" default:"eng"`
+	Learn  bool     `arg:"--learn" help:"glyph engine: ask for unknown or doubtful glyphs and save the answers to the dictionary"`
+	Index  int      `arg:"--index" help:"VobSub: stream index in the .idx (default: first)" default:"-1"`
+
+	Journal   string `arg:"--journal" help:"journal directory for restore (default: user config dir/subtitle-sanitizer/journal)"`
+	NoJournal bool   `arg:"--no-journal" help:"do not journal writes"`
+}
+
+// runOCR converts image subtitles to FILE.ocr.srt, optionally training the glyph dictionary.
+func runOCR(argv []string) int {
+	var args ocrArgs
+	mustParseSubcommand("ocr", &args, argv)
+	opts := ocrOptions{Engine: args.Engine, Dict: args.Dict, Lang: args.Lang}
+	if args.Learn {
+		opts.Engine = "glyph"
+	}
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+
+	if !args.NoJournal {
+		var err error
+		if undoJournal, err = openJournal(args.Journal); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+	}
+
+	var dict *imagesub.Dictionary
+	if opts.engine() == "glyph" {
+		var err error
+		if dict, err = imagesub.LoadDictionary(opts.dictPath()); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+	}
+	stdin := bufio.NewReader(os.Stdin)
+	code := exitOK
+	for _, path := range args.Input {
+		var engine imagesub.Engine = imagesub.Tesseract{Lang: opts.Lang}
+		glyphs := &imagesub.GlyphEngine{Dict: dict}
+		if dict != nil {
+			if args.Learn {
+				glyphs.Ask = askGlyph(stdin)
+			}
+			engine = glyphs
+		}
+		out, err := convertImageSubtitle(path, args.Index, engine)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: error: %v\n", path, err)
+			code = exitFailed
+			continue
+		}
+		if glyphs.Unknown > 0 {
+			fmt.Fprintf(os.Stderr, "%s -> %s (%d unknown glyphs read as ?)\n", path, filepath.Base(out), glyphs.Unknown)
+		} else {
+			fmt.Fprintf(os.Stderr, "%s -> %s\n", path, filepath.Base(out))
+		}
+	}
+	if args.Learn {
+		if err := dict.Save(opts.dictPath()); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitFailed
+		}
+		fmt.Fprintf(os.Stderr, "%d glyphs in %s\n", len(dict.Glyphs), opts.dictPath())
+	}
+	return code
+}
+
+// askGlyph prompts for one glyph on the terminal: enter keeps the guess, "!" stops asking.
+func askGlyph(in *bufio.Reader) func(imagesub.Bitmap, string) (string, bool) {
+	return func(glyph imagesub.Bitmap, guess string) (string, bool) {
+		for {
+			fmt.Print("\n", glyph.String())
+			if guess != "" {
+				fmt.Printf("Text [%s] (! to stop): ", guess)
+			} else {
+				fmt.Print("Text (! to stop): ")
+			}
+			line, err := in.ReadString('\n')
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case line == "!" || (err != nil && line == ""):
+				return "", false
+			case line == "" && guess != "":
+				return guess, true
+			case line != "":
+				return line, true
+			}
+		}
+	}
+}
+
+func (o ocrOptions) validate() error {
+	switch o.Engine {
+	case "", "auto", "glyph", "tesseract":
+	default:
+		return fmt.Errorf("unknown OCR engine %q (auto, glyph, tesseract)", o.Engine)
+	}
+	if o.Engine == "tesseract" && !imagesub.TesseractAvailable() {
+		return errors.New("tesseract not found in PATH")
+	}
+	return nil
+}
+
+// engine resolves auto to tesseract when installed, else the glyph engine.
+func (o ocrOptions) engine() string {
+	if o.Engine == "" || o.Engine == "auto" {
+		if imagesub.TesseractAvailable() {
+			return "tesseract"
+		}
+		return "glyph"
+	}
+	return o.Engine
+}
+
+func (o ocrOptions) dictPath() string {
+	if o.Dict != "" {
+		return o.Dict
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "glyphs.json"
+	}
+	return filepath.Join(dir, "subtitle-sanitizer", "glyphs.json")
+}
+
+// newEngine returns the engine for one file (glyph engines count their unknown glyphs).
+func (o ocrOptions) newEngine() (imagesub.Engine, error) {
+	if o.engine() == "tesseract" {
+		return imagesub.Tesseract{Lang: o.Lang}, nil
+	}
+	dict, err := imagesub.LoadDictionary(o.dictPath())
+	if err != nil {
+		return nil, err
+	}
+	if len(dict.Glyphs) == 0 {
+		return nil, fmt.Errorf("glyph dictionary %s is empty: train it with `subtitle-sanitizer ocr --learn FILE` or install tesseract", o.dictPath())
+	}
+	return &imagesub.GlyphEngine{Dict: dict}, nil
+}
+
+// ocrPath is where the recognized SRT of an image subtitle goes: movie.sup -> movie.ocr.srt.
+func ocrPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".ocr.srt"
+}
+
+// decodeImageSubtitle decodes a .sup, or a .idx with the .sub next to it.
+func decodeImageSubtitle(path string, data []byte, index int) ([]imagesub.Subtitle, error) {
+	if strings.EqualFold(filepath.Ext(path), ".idx") {
+		sub, err := os.ReadFile(strings.TrimSuffix(path, filepath.Ext(path)) + ".sub")
+		if err != nil {
+			return nil, fmt.Errorf("read vobsub: %w", err)
+		}
+		return imagesub.DecodeVobSub(data, sub, index)
+	}
+	return imagesub.DecodePGS(data)
+}
+
+// recognizeImageSubtitle decodes the image subtitle data of path and renders it as SRT.
+func recognizeImageSubtitle(path string, data []byte, index int, engine imagesub.Engine) ([]byte, error) {
+	subs, err := decodeImageSubtitle(path, data, index)
+	if err != nil {
+		return nil, err
+	}
+	return imagesub.ToSRT(subs, engine)
+}
+
+// convertImageSubtitle reads, recognizes and writes path as ocrPath(path).
+func convertImageSubtitle(path string, index int, engine imagesub.Engine) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+	srt, err := recognizeImageSubtitle(path, data, index, engine)
+	if err != nil {
+		return "", err
+	}
+	out := ocrPath(path)
+	if err := undoJournal.WriteFile(path, data, out, srt, false); err != nil {
+		return "", fmt.Errorf("write output: %w", err)
+	}
+	return out, nil
+}
+
+// ocrSubtitle turns the image subtitle data of path into SRT for the sanitize pipeline,
+// returning the .ocr.srt path it is (or, with write, was) saved under.
+func ocrSubtitle(path string, data []byte, opts ocrOptions, write bool) (string, []byte, error) {
+	engine, err := opts.newEngine()
+	if err != nil {
+		return "", nil, err
+	}
+	srt, err := recognizeImageSubtitle(path, data, -1, engine)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(srt) == 0 {
+		return "", nil, errors.New("ocr: no text recognized")
+	}
+	out := ocrPath(path)
+	if write {
+		if err := undoJournal.WriteFile(path, data, out, srt, false); err != nil {
+			return "", nil, fmt.Errorf("write ocr output: %w", err)
+		}
+	}
+	return out, srt, nil
+}
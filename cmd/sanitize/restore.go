@@ -0,0 +1,118 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/journal"
+)
+
+type restoreArgs struct {
+	Input   []string `arg:"positional" help:"sanitized outputs or their inputs to restore (default: every journaled file, with --since or --last)"`
+	Since   string   `arg:"--since" help:"only undo writes from this time on: 2006-01-02 or RFC 3339"`
+	Last    bool     `arg:"--last" help:"undo only the newest write of each file instead of going back to the original"`
+	List    bool     `arg:"-l,--list" help:"list the journaled writes that would be undone and change nothing"`
+	Force   bool     `arg:"-f,--force" help:"restore files edited since they were written"`
+	DryRun  bool     `arg:"-n,--dry-run" help:"print what would be restored and change nothing"`
+	Journal string   `arg:"--journal" help:"journal directory (default: user config dir/subtitle-sanitizer/journal)"`
+}
+
+// undoJournal records every output the sanitizer writes; nil (--no-journal, dry runs)
+// writes without recording.
+var undoJournal *journal.Journal
+
+// openJournal opens the journal in dir, or the default one when dir is empty.
+func openJournal(dir string) (*journal.Journal, error) {
+	if dir == "" {
+		var err error
+		if dir, err = journal.DefaultDir(); err != nil {
+			return nil, fmt.Errorf("journal: %w", err)
+		}
+	}
+	return journal.Open(dir)
+}
+
+func runRestore(argv []string) int {
+	var args restoreArgs
+	mustParseSubcommand("restore", &args, argv)
+
+	var since time.Time
+	if args.Since != "" {
+		var err error
+		if since, err = parseSince(args.Since); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+	}
+	if len(args.Input) == 0 && since.IsZero() && !args.Last && !args.List {
+		fmt.Fprintln(os.Stderr, "Error: name the files to restore, or select them with --since or --last")
+		return exitUsage
+	}
+	j, err := openJournal(args.Journal)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	entries, err := j.Entries()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailed
+	}
+	selected := journal.Select(entries, args.Input, since, args.Last)
+	if len(selected) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to restore in", j.Dir())
+		if len(args.Input) > 0 {
+			return exitFailed
+		}
+		return exitOK
+	}
+
+	code := exitOK
+	for _, e := range selected {
+		if args.List || args.DryRun {
+			fmt.Println(describeEntry(e))
+			continue
+		}
+		if err := j.Restore(e, args.Force); err != nil {
+			if errors.Is(err, journal.ErrChanged) {
+				err = fmt.Errorf("%w (use --force to restore anyway)", err)
+			}
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			code = exitFailed
+			continue
+		}
+		fmt.Println("restored", describeEntry(e))
+	}
+	return code
+}
+
+// describeEntry is one line per journaled write: time, output and what undoing it does.
+func describeEntry(e journal.Entry) string {
+	var what string
+	switch {
+	case e.Output == e.Input:
+		what = "original bytes"
+	case e.Previous != "":
+		what = "previous contents"
+	default:
+		what = "removed"
+	}
+	if e.InputRemoved {
+		what += ", " + e.Input + " recreated"
+	}
+	return fmt.Sprintf("%s  %s  (%s)", e.Time.Local().Format("2006-01-02 15:04:05"), e.Output, what)
+}
+
+// parseSince accepts a date (local midnight) or an RFC 3339 time.
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--since %q: want 2006-01-02 or RFC 3339", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"os/signal"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/wasmbridge"
+)
+
+type serveArgs struct {
+	Listen      string        `arg:"-l,--listen" help:"address to listen on" default:"127.0.0.1:8765"`
+	MaxBody     int64         `arg:"--max-body" help:"largest request body in bytes (MKV uploads included)" default:"67108864"`
+	Timeout     time.Duration `arg:"--timeout" help:"longest a request may take, waiting for a free slot included" default:"30s"`
+	Concurrency int           `arg:"-j,--concurrency" help:"requests processed at once (default: CPU count)"`
+	Cache       int           `arg:"--cache" help:"compiled configs kept in memory" default:"32"`
+	LogFormat   string        `arg:"--log-format" help:"log lines on stderr: text, json" default:"text"`
+}
+
+func runServe(argv []string) int {
+	var args serveArgs
+	mustParseSubcommand("serve", &args, argv)
+	headless = true
+
+	logger, err := newLogger(os.Stderr, args.LogFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	if args.Concurrency <= 0 {
+		args.Concurrency = runtime.GOMAXPROCS(0)
+	}
+	if args.MaxBody <= 0 || args.Timeout <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --max-body and --timeout must be positive")
+		return exitUsage
+	}
+
+	api := &apiServer{
+		proc:    wasmbridge.NewProcessor(args.Cache),
+		slots:   make(chan struct{}, args.Concurrency),
+		maxBody: args.MaxBody,
+		timeout: args.Timeout,
+		log:     logger,
+	}
+	srv := &http.Server{
+		Addr:              args.Listen,
+		Handler:           api.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		ReadTimeout:       args.Timeout,
+		WriteTimeout:      args.Timeout + 5*time.Second,
+		IdleTimeout:       time.Minute,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() {
+		logger.Info("listening", "addr", args.Listen, "concurrency", args.Concurrency,
+			"maxBody", args.MaxBody, "timeout", args.Timeout.String())
+		errc <- srv.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		logger.Error("serve", "err", err)
+		return exitFailed
+	case <-ctx.Done():
+	}
+	logger.Info("shutting down")
+	shutdown, cancel := context.WithTimeout(context.Background(), args.Timeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		logger.Error("shutdown", "err", err)
+		return exitFailed
+	}
+	return exitOK
+}
+
+// apiServer exposes the wasmbridge JSON contract over HTTP.
+type apiServer struct {
+	proc     *wasmbridge.Processor
+	slots    chan struct{} // one per request being processed
+	inFlight atomic.Int64
+	maxBody  int64
+	timeout  time.Duration
+	log      *slog.Logger
+}
+
+func (s *apiServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/sanitize", s.handle(s.proc.Process))
+	mux.HandleFunc("POST /v1/lint", s.handle(s.proc.Lint))
+	mux.HandleFunc("POST /v1/convert", s.handle(s.proc.Convert))
+	mux.HandleFunc("GET /healthz", s.health)
+	return s.logged(mux)
+}
+
+// handle reads a JSON or multipart request, runs fn on it within the concurrency and
+// time limits and writes its JSON response: 200 when ok, 400 otherwise, 500 when fn
+// panicked.
+func (s *apiServer) handle(fn func([]byte) []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), s.timeout)
+		defer cancel()
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+		body, err := requestJSON(r)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeAPIError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body larger than %d bytes", s.maxBody))
+				return
+			}
+			writeAPIError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		select {
+		case s.slots <- struct{}{}:
+		case <-ctx.Done():
+			w.Header().Set("Retry-After", "1")
+			writeAPIError(w, http.StatusServiceUnavailable, "busy: no free slot before the timeout")
+			return
+		}
+		s.inFlight.Add(1)
+		done := make(chan []byte, 1)
+		crashed := make(chan any, 1)
+		go func() {
+			// net/http only recovers panics of handler goroutines; one here would stop the
+			// server. Deferred first, it runs after the slot is released.
+			defer func() {
+				if p := recover(); p != nil {
+					s.log.Error("panic", "path", r.URL.Path, "panic", p, "stack", string(debug.Stack()))
+					crashed <- p
+				}
+			}()
+			// The slot is held until fn returns, even after a timeout, so the limit holds.
+			defer func() { <-s.slots; s.inFlight.Add(-1) }()
+			done <- fn(body)
+		}()
+		select {
+		case <-crashed:
+			writeAPIError(w, http.StatusInternalServerError, "internal error while processing the request")
+		case out := <-done:
+			status := http.StatusOK
+			var head struct {
+				OK bool `json:"ok"`
+			}
+			if json.Unmarshal(out, &head) != nil || !head.OK {
+				status = http.StatusBadRequest
+			}
+			writeAPIJSON(w, status, out)
+		case <-ctx.Done():
+			writeAPIError(w, http.StatusGatewayTimeout, "timeout: processing took longer than "+s.timeout.String())
+		}
+	}
+}
+
+// requestJSON returns the wasmbridge request of r: a JSON body as it is, or a multipart
+// form with the subtitle (or MKV) in "file" and the other request fields as form
+// values ("config" holding JSON).
+func requestJSON(r *http.Request) ([]byte, error) {
+	mediatype, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediatype != "multipart/form-data" {
+		return io.ReadAll(r.Body)
+	}
+	if err := r.ParseMultipartForm(8 << 20); err != nil {
+		return nil, err
+	}
+	defer r.MultipartForm.RemoveAll()
+	f, header, err := r.FormFile("file")
+	if err != nil {
+		return nil, fmt.Errorf("multipart: %w", err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	req := wasmbridge.Request{
+		SubtitleB64: base64.StdEncoding.EncodeToString(data),
+		Name:        r.FormValue("name"),
+		Preset:      r.FormValue("preset"),
+	}
+	if req.Name == "" {
+		req.Name = header.Filename
+	}
+	if c := r.FormValue("config"); c != "" {
+		req.Config = json.RawMessage(c)
+		if !json.Valid(req.Config) {
+			return nil, errors.New("multipart: config is not valid JSON")
+		}
+	}
+	if t := r.FormValue("track"); t != "" {
+		track, err := strconv.Atoi(t)
+		if err != nil {
+			return nil, fmt.Errorf("multipart: track %q is not a number", t)
+		}
+		req.Track = &track
+	}
+	return json.Marshal(req)
+}
+
+func (s *apiServer) health(w http.ResponseWriter, _ *http.Request) {
+	out, _ := json.Marshal(struct {
+		OK          bool                  `json:"ok"`
+		InFlight    int64                 `json:"inFlight"`
+		Concurrency int                   `json:"concurrency"`
+		Cache       wasmbridge.CacheStats `json:"cache"`
+	}{true, s.inFlight.Load(), cap(s.slots), s.proc.Stats()})
+	writeAPIJSON(w, http.StatusOK, out)
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(body)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	out, _ := json.Marshal(wasmbridge.Response{OK: false, Error: msg})
+	writeAPIJSON(w, status, out)
+}
+
+// statusRecorder keeps the status and size of a response for the access log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// logged writes one structured log line per request.
+func (s *apiServer) logged(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		s.log.Info("request", "method", r.Method, "path", r.URL.Path, "status", rec.status,
+			"bytes", rec.bytes, "ms", time.Since(start).Milliseconds(), "remote", r.RemoteAddr)
+	})
+}
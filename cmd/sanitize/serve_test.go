@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/wasmbridge"
+)
+
+func TestServe_panic(t *testing.T) {
+	s := &apiServer{
+		proc:    wasmbridge.NewProcessor(1),
+		slots:   make(chan struct{}, 1),
+		maxBody: 1 << 20,
+		timeout: 5 * time.Second,
+		log:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	h := s.handle(func([]byte) []byte { panic("crafted input") })
+
+	for range 2 { // the slot is released: the second request is not left waiting
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodPost, "/v1/sanitize", strings.NewReader(`{}`)))
+		if rec.Code != http.StatusInternalServerError {
+			t.Fatalf("status = %d, want 500", rec.Code)
+		}
+		var resp wasmbridge.Response
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil || resp.OK || resp.Error == "" {
+			t.Fatalf("body = %s (%v)", rec.Body, err)
+		}
+	}
+	if n := s.inFlight.Load(); n != 0 {
+		t.Fatalf("inFlight = %d after the panics", n)
+	}
+}
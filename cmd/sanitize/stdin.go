@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/sanitize"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+// stdinInput is the input name that reads a subtitle from stdin and writes the sanitized
+// SRT to stdout.
+const stdinInput = "-"
+
+// parseStdinFormat reads the --stdin-format flag: srt or ass.
+func parseStdinFormat(s string) (model.SubtitleFormat, error) {
+	switch strings.ToLower(s) {
+	case "srt":
+		return model.SubtitleFormatSRT, nil
+	case "ass", "ssa":
+		return model.SubtitleFormatASS, nil
+	}
+	return model.SubtitleFormatUnknown, fmt.Errorf("--stdin-format %q: want srt or ass", s)
+}
+
+// runStdin sanitizes the subtitle read from in and writes SRT to out a window of cues at a
+// time, so multi-hour captions are never held in memory. The rules are those of the
+// current directory; the counts go to progress.
+func runStdin(in io.Reader, out, progress io.Writer, ruleSets *ruleSet, format model.SubtitleFormat) int {
+	dir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitFailed
+	}
+	_, prepared, err := ruleSets.forFile(filepath.Join(dir, "stdin"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	changed, removed, suppressed := 0, 0, 0
+	written, err := sanitize.Stream(in, out, format, prepared, transform.DefaultWindow, func(ch transform.CueChange) {
+		if len(ch.Suppressed) > 0 {
+			suppressed++
+		}
+		switch {
+		case !ch.Applied():
+		case strings.TrimSpace(ch.Transformed) == "":
+			removed++
+		default:
+			changed++
+		}
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: stdin: %v (%d cues written)\n", err, written)
+		return exitFailed
+	}
+	fmt.Fprintf(progress, "stdin: %d cues written (%d changed, %d removed%s)\n", written, changed, removed, suppressedNote(suppressed))
+	return exitOK
+}
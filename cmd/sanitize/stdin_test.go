@@ -0,0 +1,34 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+)
+
+func TestRunStdin(t *testing.T) {
+	var in strings.Builder
+	for i := range 1000 { // several windows
+		in.WriteString("0\n00:00:01,000 --> 00:00:02,000\n")
+		if i%2 == 0 {
+			in.WriteString("(sighs) Hello\n\n")
+		} else {
+			in.WriteString("[music]\n\n")
+		}
+	}
+	var out, progress bytes.Buffer
+	if code := runStdin(strings.NewReader(in.String()), &out, &progress, testRuleSet(t), model.SubtitleFormatSRT); code != exitOK {
+		t.Fatalf("code = %d", code)
+	}
+	if got := strings.Count(out.String(), "\nHello\n"); got != 500 || strings.Contains(out.String(), "music") {
+		t.Fatalf("%d cues kept, output:\n%.200s", got, out.String())
+	}
+	if !strings.HasPrefix(out.String(), "1\n00:00:01,000") || !strings.Contains(out.String(), "\n500\n") {
+		t.Fatalf("cues not renumbered:\n%.200s", out.String())
+	}
+	if want := "stdin: 500 cues written (500 changed, 500 removed)\n"; progress.String() != want {
+		t.Fatalf("progress = %q, want %q", progress.String(), want)
+	}
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/batch"
+	"github.com/luismascotto/subtitle-sanitizer/internal/container"
+	"github.com/luismascotto/subtitle-sanitizer/internal/watch"
+)
+
+type watchArgs struct {
+	configFlags
+	Dirs      []string      `arg:"positional,required" help:"directories to watch"`
+	Recursive bool          `arg:"-r,--recursive" help:"watch subdirectories too"`
+	Include   []string      `arg:"--include,separate" help:"only process files matching this pattern (repeatable)"`
+	Exclude   []string      `arg:"--exclude,separate" help:"skip files and directories matching this pattern (repeatable)"`
+	Auto      bool          `arg:"-a,--auto" help:"overwrite inputs (SRT in place, ASS replaced by SRT) instead of writing FILE-his.srt; ignored with --output"`
+	Output    string        `arg:"-o,--output" help:"where sanitized subtitles go, eg: /media/library/{rel}/{base}.srt (fields: dir root rel base name date; default: next to the input)"`
+	Move      bool          `arg:"--move" help:"with --output: move video and image subtitle inputs next to their output and delete text subtitle inputs (journaled) once sanitized"`
+	Track     string        `arg:"-t,--track" help:"video: subtitle track to sanitize, eg: lang=spa,!sdh (default: English, not SDH, not forced)"`
+	State     string        `arg:"--state" help:"state file of processed files (default: user config dir/subtitle-sanitizer/watch-state.json)"`
+	Poll      bool          `arg:"--poll" help:"poll the directories instead of using file system events (network shares)"`
+	Interval  time.Duration `arg:"--interval" help:"polling period" default:"2s"`
+	Settle    time.Duration `arg:"--settle" help:"process a file once its size and modification time held still this long" default:"5s"`
+	Once      bool          `arg:"--once" help:"process the files already there (once stable) and exit"`
+	LogFormat string        `arg:"--log-format" help:"log lines on stderr: text, json" default:"text"`
+	Journal   string        `arg:"--journal" help:"journal directory for restore (default: user config dir/subtitle-sanitizer/journal)"`
+	NoJournal bool          `arg:"--no-journal" help:"do not journal writes"`
+}
+
+func runWatch(argv []string) int {
+	var args watchArgs
+	mustParseSubcommand("watch", &args, argv)
+	headless = true
+
+	logger, err := newLogger(os.Stderr, args.LogFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	var tmpl watch.Template
+	if args.Output != "" {
+		if tmpl, err = watch.ParseTemplate(args.Output); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+	} else if args.Move {
+		fmt.Fprintln(os.Stderr, "Error: --move needs --output")
+		return exitUsage
+	}
+	sel, err := container.ParseSelector(args.Track)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	configs, err := args.loader(nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	if _, err := configs.Base(); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	statePath := args.State
+	if statePath == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+		statePath = filepath.Join(dir, "subtitle-sanitizer", "watch-state.json")
+	}
+	state, err := watch.OpenState(statePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		return exitUsage
+	}
+	if !args.NoJournal {
+		if undoJournal, err = openJournal(args.Journal); err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return exitUsage
+		}
+	}
+
+	d := &watchDaemon{
+		log:      logger,
+		ruleSets: newRuleSet(configs),
+		state:    state,
+		opts:     batchOptions{Overwrite: args.Auto, Track: sel, OCR: ocrOptions{Engine: "auto", Lang: "eng"}},
+		output:   tmpl,
+		move:     args.Move,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	err = watch.Run(ctx, watch.Options{
+		Dirs: absPaths(args.Dirs),
+		Filter: batch.Options{
+			Recursive:  args.Recursive,
+			Include:    args.Include,
+			Exclude:    append(append([]string{}, defaultBatchExcludes...), args.Exclude...),
+			Extensions: append([]string{".srt", ".ass", ".sup", ".idx"}, container.Extensions...),
+		},
+		Interval: args.Interval,
+		Settle:   args.Settle,
+		Poll:     args.Poll,
+		Once:     args.Once,
+		Logger:   logger,
+	}, d.handle)
+	if err != nil {
+		logger.Error("watch", "err", err)
+		return exitFailed
+	}
+	if ctx.Err() != nil {
+		logger.Info("shutting down", "processed", d.processed, "failed", d.failed)
+	}
+	if args.Once && d.failed > 0 {
+		return exitFailed
+	}
+	return exitOK
+}
+
+// newLogger returns the structured logger of the daemon modes.
+func newLogger(w io.Writer, format string) (*slog.Logger, error) {
+	switch strings.ToLower(format) {
+	case "text", "":
+		return slog.New(slog.NewTextHandler(w, nil)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, nil)), nil
+	}
+	return nil, fmt.Errorf("unknown log format %q (text, json)", format)
+}
+
+// watchDaemon sanitizes the files watch.Run reports and records them in the state file.
+type watchDaemon struct {
+	log       *slog.Logger
+	ruleSets  *ruleSet
+	state     *watch.State
+	opts      batchOptions
+	output    watch.Template // zero: usual output naming
+	move      bool
+	processed int
+	failed    int
+}
+
+func (d *watchDaemon) handle(root, path string) {
+	if d.state.Done(path) {
+		d.log.Debug("already processed", "file", path)
+		return
+	}
+
+	opts := d.opts
+	if d.output.String() != "" {
+		now := time.Now()
+		opts.Output = func(string) string { return d.output.Path(root, path, now) }
+		opts.RemoveInput = d.move
+	}
+	res := processFile(path, d.ruleSets, opts)
+
+	// Outputs, extracted tracks and moved inputs are recorded too, so they are not taken
+	// for new files when they land in a watched directory.
+	written := []string{res.Output, res.Subtitle}
+	if res.Err == nil && d.move && res.Subtitle != "" {
+		// A video (or image subtitle) goes with its sanitized track.
+		moved := filepath.Join(filepath.Dir(res.Output), filepath.Base(path))
+		if err := moveFile(path, moved); err != nil {
+			res.Err = fmt.Errorf("move input: %w", err)
+		} else {
+			written = append(written, moved)
+		}
+	}
+	rec := watch.FileState{}
+	for _, out := range written {
+		if out != "" && out != path {
+			rec.Outputs = append(rec.Outputs, out)
+			if err := d.state.Record(out, watch.FileState{Source: path}); err != nil {
+				d.log.Error("state", "err", err)
+			}
+		}
+	}
+
+	attrs := []any{"file", path, "ms", res.Timings.Total().Milliseconds()}
+	if res.HI != nil {
+		attrs = append(attrs, "hi", string(res.HI.Level))
+	}
+	switch {
+	case res.Err != nil:
+		d.failed++
+		rec.Error = res.Err.Error()
+		d.log.Error("failed", append(attrs, "err", res.Err)...)
+	case res.Output == "":
+		d.processed++
+		d.log.Info("no changes", attrs...)
+	default:
+		d.processed++
+		d.log.Info("sanitized", append(attrs, "output", res.Output, "changed", res.CuesChanged,
+			"removed", res.CuesRemoved, "suppressed", res.CuesSuppressed)...)
+	}
+	if err := d.state.Record(path, rec); err != nil {
+		d.log.Error("state", "err", err)
+	}
+}
+
+// moveFile renames src to dst, copying across file systems.
+func moveFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return errors.Join(err, os.Remove(dst))
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
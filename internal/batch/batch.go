@@ -0,0 +1,291 @@
+// Package batch expands directory and glob inputs into file lists and runs a per-file
+// function over them with a bounded worker pool.
+package batch
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+// Options controls how directories and glob patterns are expanded.
+// Include/Exclude are filepath.Match patterns tested against the base name and the
+// slash-separated path relative to the directory being walked.
+type Options struct {
+	Recursive  bool
+	Include    []string
+	Exclude    []string
+	Extensions []string // lower-case, with dot (eg: ".srt"); empty accepts every file
+}
+
+// IsPattern reports whether p contains glob metacharacters.
+func IsPattern(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+// NeedsExpansion reports whether any input is a directory or a glob pattern.
+func NeedsExpansion(inputs []string) bool {
+	for _, in := range inputs {
+		if IsPattern(in) {
+			return true
+		}
+		if st, err := os.Stat(in); err == nil && st.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// Expand resolves inputs into a de-duplicated file list in input order. Plain files are
+// kept as given (no filtering); directories are walked and glob matches are filtered by opts.
+func Expand(inputs []string, opts Options) ([]string, error) {
+	var out []string
+	seen := map[string]bool{}
+	add := func(p string) {
+		key := filepath.Clean(p)
+		if !seen[key] {
+			seen[key] = true
+			out = append(out, p)
+		}
+	}
+
+	for _, in := range inputs {
+		if IsPattern(in) {
+			matches, err := filepath.Glob(in)
+			if err != nil {
+				return nil, fmt.Errorf("glob %q: %w", in, err)
+			}
+			for _, m := range matches {
+				st, err := os.Stat(m)
+				if err != nil {
+					continue
+				}
+				if st.IsDir() {
+					if err := walk(m, opts, add); err != nil {
+						return nil, err
+					}
+				} else if opts.accepts(filepath.Base(m), filepath.Base(m)) {
+					add(m)
+				}
+			}
+			continue
+		}
+		st, err := os.Stat(in)
+		if err != nil {
+			return nil, fmt.Errorf("stat input: %w", err)
+		}
+		if st.IsDir() {
+			if err := walk(in, opts, add); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		add(in)
+	}
+	return out, nil
+}
+
+func walk(root string, opts Options, add func(string)) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if path == root {
+				return nil
+			}
+			if !opts.Recursive || opts.excluded(d.Name(), rel) {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if opts.accepts(d.Name(), rel) {
+			add(path)
+		}
+		return nil
+	})
+}
+
+// Accepts reports whether walking the directory root would list path, a file under it
+// (eg: one that appeared after the walk).
+func (o Options) Accepts(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	if len(parts) > 1 && !o.Recursive {
+		return false
+	}
+	for i, dir := range parts[:len(parts)-1] {
+		if o.excluded(dir, strings.Join(parts[:i+1], "/")) {
+			return false
+		}
+	}
+	return o.accepts(parts[len(parts)-1], filepath.ToSlash(rel))
+}
+
+func (o Options) accepts(name, rel string) bool {
+	if len(o.Extensions) > 0 && !slices.Contains(o.Extensions, strings.ToLower(filepath.Ext(name))) {
+		return false
+	}
+	if o.excluded(name, rel) {
+		return false
+	}
+	if len(o.Include) == 0 {
+		return true
+	}
+	return matchAny(o.Include, name, rel)
+}
+
+func (o Options) excluded(name, rel string) bool {
+	return matchAny(o.Exclude, name, rel)
+}
+
+func matchAny(patterns []string, name, rel string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(filepath.ToSlash(p), rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Run calls fn for every file using at most jobs goroutines (GOMAXPROCS when jobs <= 0)
+// and returns results in file order. done, when non-nil, is called after each file
+// (serialized, in completion order) for progress output.
+func Run[T any](files []string, jobs int, fn func(path string) T, done func(i int, res T)) []T {
+	results := make([]T, len(files))
+	if len(files) == 0 {
+		return results
+	}
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+	jobs = min(jobs, len(files))
+
+	work := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(jobs)
+	for range jobs {
+		go func() {
+			defer wg.Done()
+			for i := range work {
+				res := fn(files[i])
+				results[i] = res
+				if done != nil {
+					mu.Lock()
+					done(i, res)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for i := range files {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+	return results
+}
+
+// FileResult is the per-file outcome reported in the batch summary and report records.
+type FileResult struct {
+	Path           string
+	Output         string
+	Remuxed        string // MKV written with the sanitized track (--remux)
+	Format         string
+	Cues           int
+	HI             *hi.Score // hearing-impaired content of the input (nil when not parsed)
+	CuesChanged    int
+	CuesRemoved    int
+	CuesSuppressed int                   // cues where the allowlist kept a rule from applying
+	Subtitle       string                // the subtitle sanitized when it is not Path: track extracted from a video, OCR output
+	Diagnostics    []subtitle.Diagnostic // what the SRT parser tolerated, repaired or dropped
+	Changes        []transform.CueChange
+	Diff           string // unified diff of the would-be output (dry runs)
+	Timings        Timings
+	Err            error
+}
+
+// Timings splits the time spent on one file by pipeline stage.
+type Timings struct {
+	Load      time.Duration // read or MKV extraction
+	Parse     time.Duration
+	Transform time.Duration
+	Write     time.Duration // output and remux
+}
+
+// Total is the sum of all stages.
+func (t Timings) Total() time.Duration {
+	return t.Load + t.Parse + t.Transform + t.Write
+}
+
+// Summary totals a batch run.
+type Summary struct {
+	Files       int
+	Processed   int
+	SDH         int // inputs scored as full SDH tracks
+	Clean       int // inputs without any HI marker: nothing to sanitize
+	CuesChanged int
+	CuesRemoved int
+	Errors      int
+}
+
+// Summarize totals results.
+func Summarize(results []FileResult) Summary {
+	s := Summary{Files: len(results)}
+	for _, r := range results {
+		if r.Err != nil {
+			s.Errors++
+			continue
+		}
+		s.Processed++
+		if r.HI != nil && r.HI.IsSDH() {
+			s.SDH++
+		}
+		if r.HI != nil && !r.HI.NeedsSanitizing() {
+			s.Clean++
+		}
+		s.CuesChanged += r.CuesChanged
+		s.CuesRemoved += r.CuesRemoved
+	}
+	return s
+}
+
+// WriteSummary prints the totals table followed by one line per failed file.
+func WriteSummary(w io.Writer, results []FileResult) error {
+	s := Summarize(results)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "Files\tProcessed\tSDH\tNo HI\tCues changed\tCues removed\tErrors\t")
+	fmt.Fprintf(tw, "%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", s.Files, s.Processed, s.SDH, s.Clean, s.CuesChanged, s.CuesRemoved, s.Errors)
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			if _, err := fmt.Fprintf(w, "error: %s: %v\n", r.Path, r.Err); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
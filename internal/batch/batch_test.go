@@ -0,0 +1,170 @@
+package batch
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+)
+
+func touch(t *testing.T, root string, rel ...string) {
+	t.Helper()
+	for _, r := range rel {
+		p := filepath.Join(root, filepath.FromSlash(r))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func rels(t *testing.T, root string, files []string) []string {
+	t.Helper()
+	out := make([]string, 0, len(files))
+	for _, f := range files {
+		r, err := filepath.Rel(root, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, filepath.ToSlash(r))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func TestExpand(t *testing.T) {
+	root := t.TempDir()
+	touch(t, root,
+		"a.srt", "a-his.srt", "notes.txt",
+		"S01/e1.ass", "S01/e1.mkv",
+		"S01/Extras/bonus.srt",
+	)
+	exts := []string{".srt", ".ass", ".mkv"}
+
+	tests := []struct {
+		name string
+		opts Options
+		want []string
+	}{
+		{"flat", Options{Extensions: exts}, []string{"a-his.srt", "a.srt"}},
+		{"recursive", Options{Recursive: true, Extensions: exts}, []string{"S01/Extras/bonus.srt", "S01/e1.ass", "S01/e1.mkv", "a-his.srt", "a.srt"}},
+		{"exclude dir and outputs", Options{Recursive: true, Extensions: exts, Exclude: []string{"Extras", "*-his.srt"}}, []string{"S01/e1.ass", "S01/e1.mkv", "a.srt"}},
+		{"include", Options{Recursive: true, Extensions: exts, Include: []string{"*.ass"}}, []string{"S01/e1.ass"}},
+		{"include relative path", Options{Recursive: true, Extensions: exts, Include: []string{"S01/*"}}, []string{"S01/e1.ass", "S01/e1.mkv"}},
+	}
+	all := []string{"S01/Extras/bonus.srt", "S01/e1.ass", "S01/e1.mkv", "a-his.srt", "a.srt", "notes.txt"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Expand([]string{root}, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := rels(t, root, files); !slices.Equal(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			var accepted []string
+			for _, rel := range all {
+				if tt.opts.Accepts(root, filepath.Join(root, filepath.FromSlash(rel))) {
+					accepted = append(accepted, rel)
+				}
+			}
+			if !slices.Equal(accepted, tt.want) {
+				t.Fatalf("Accepts: got %v, want %v", accepted, tt.want)
+			}
+		})
+	}
+	if (Options{}).Accepts(filepath.Join(root, "S01"), filepath.Join(root, "a.srt")) {
+		t.Fatal("Accepts: file outside root")
+	}
+}
+
+func TestExpand_globAndDedup(t *testing.T) {
+	root := t.TempDir()
+	touch(t, root, "a.srt", "b.srt", "c.ass")
+	explicit := filepath.Join(root, "a.srt")
+	files, err := Expand([]string{explicit, filepath.Join(root, "*.srt")}, Options{Extensions: []string{".srt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rels(t, root, files); !slices.Equal(got, []string{"a.srt", "b.srt"}) {
+		t.Fatalf("got %v", got)
+	}
+	if files[0] != explicit {
+		t.Fatalf("input order not kept: %v", files)
+	}
+}
+
+func TestExpand_missingInput(t *testing.T) {
+	if _, err := Expand([]string{filepath.Join(t.TempDir(), "nope.srt")}, Options{}); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestNeedsExpansion(t *testing.T) {
+	root := t.TempDir()
+	touch(t, root, "a.srt")
+	if NeedsExpansion([]string{filepath.Join(root, "a.srt")}) {
+		t.Fatal("plain file should not need expansion")
+	}
+	if !NeedsExpansion([]string{root}) || !NeedsExpansion([]string{"*.srt"}) {
+		t.Fatal("directory and glob should need expansion")
+	}
+}
+
+func TestRun_orderAndBound(t *testing.T) {
+	files := []string{"a", "b", "c", "d", "e", "f", "g"}
+	var running, peak atomic.Int32
+	var done []int
+	got := Run(files, 2, func(p string) string {
+		n := running.Add(1)
+		for {
+			old := peak.Load()
+			if n <= old || peak.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		running.Add(-1)
+		return strings.ToUpper(p)
+	}, func(i int, _ string) { done = append(done, i) })
+
+	if !slices.Equal(got, []string{"A", "B", "C", "D", "E", "F", "G"}) {
+		t.Fatalf("results out of order: %v", got)
+	}
+	if peak.Load() > 2 {
+		t.Fatalf("peak concurrency %d exceeds 2", peak.Load())
+	}
+	if len(done) != len(files) {
+		t.Fatalf("done called %d times", len(done))
+	}
+}
+
+func TestWriteSummary(t *testing.T) {
+	results := []FileResult{
+		{Path: "a.srt", CuesChanged: 3, CuesRemoved: 1, HI: &hi.Score{Level: hi.LevelSDH}},
+		{Path: "b.srt", CuesChanged: 2, HI: &hi.Score{Level: hi.LevelLight}},
+		{Path: "c.srt", Err: errors.New("boom")},
+		{Path: "d.srt", HI: &hi.Score{Level: hi.LevelClean}},
+	}
+	s := Summarize(results)
+	if s != (Summary{Files: 4, Processed: 3, SDH: 1, Clean: 1, CuesChanged: 5, CuesRemoved: 1, Errors: 1}) {
+		t.Fatalf("summary: %+v", s)
+	}
+	var buf bytes.Buffer
+	if err := WriteSummary(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "Cues removed") || !strings.Contains(out, "No HI") || !strings.Contains(out, "error: c.srt: boom") {
+		t.Fatalf("summary output:\n%s", out)
+	}
+}
@@ -0,0 +1,202 @@
+package batch
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+// ReportFormats lists the accepted --report values.
+var ReportFormats = []string{"json", "ndjson", "csv"}
+
+// Record is the machine-readable form of a FileResult (one per input file).
+type Record struct {
+	Path           string                `json:"path"`
+	Output         string                `json:"output,omitempty"`
+	Remuxed        string                `json:"remuxed,omitempty"`
+	Format         string                `json:"format,omitempty"`
+	Cues           int                   `json:"cues"`
+	HI             *hi.Score             `json:"hi,omitempty"`
+	CuesChanged    int                   `json:"cuesChanged"`
+	CuesRemoved    int                   `json:"cuesRemoved"`
+	CuesSuppressed int                   `json:"cuesSuppressed,omitempty"`
+	Diagnostics    []subtitle.Diagnostic `json:"diagnostics,omitempty"`
+	Changes        []transform.CueChange `json:"changes"`
+	Diff           string                `json:"diff,omitempty"`
+	Timings        RecordTimings         `json:"timings"`
+	Error          string                `json:"error,omitempty"`
+}
+
+// RecordTimings holds stage durations in milliseconds.
+type RecordTimings struct {
+	LoadMs      float64 `json:"loadMs"`
+	ParseMs     float64 `json:"parseMs"`
+	TransformMs float64 `json:"transformMs"`
+	WriteMs     float64 `json:"writeMs"`
+	TotalMs     float64 `json:"totalMs"`
+}
+
+// NewRecord converts res for reporting.
+func NewRecord(res FileResult) Record {
+	rec := Record{
+		Path:           res.Path,
+		Output:         res.Output,
+		Remuxed:        res.Remuxed,
+		Format:         res.Format,
+		Cues:           res.Cues,
+		HI:             res.HI,
+		CuesChanged:    res.CuesChanged,
+		CuesRemoved:    res.CuesRemoved,
+		CuesSuppressed: res.CuesSuppressed,
+		Diagnostics:    res.Diagnostics,
+		Changes:        res.Changes,
+		Diff:           res.Diff,
+		Timings: RecordTimings{
+			LoadMs:      millis(res.Timings.Load),
+			ParseMs:     millis(res.Timings.Parse),
+			TransformMs: millis(res.Timings.Transform),
+			WriteMs:     millis(res.Timings.Write),
+			TotalMs:     millis(res.Timings.Total()),
+		},
+	}
+	if rec.Changes == nil {
+		rec.Changes = []transform.CueChange{}
+	}
+	if res.Err != nil {
+		rec.Error = res.Err.Error()
+	}
+	return rec
+}
+
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// ReportWriter writes one record per file. NDJSON and CSV rows are written as files
+// finish; JSON is buffered and written as one array on Close.
+type ReportWriter interface {
+	Write(res FileResult) error
+	Close() error
+}
+
+// ParseReportFormat normalizes a --report value ("jsonl" is accepted for ndjson).
+func ParseReportFormat(format string) (string, error) {
+	switch f := strings.ToLower(format); f {
+	case "json", "ndjson", "csv":
+		return f, nil
+	case "jsonl":
+		return "ndjson", nil
+	default:
+		return "", fmt.Errorf("unknown report format %q (%s)", format, strings.Join(ReportFormats, ", "))
+	}
+}
+
+// NewReportWriter returns a writer for format (json, ndjson or csv).
+func NewReportWriter(w io.Writer, format string) (ReportWriter, error) {
+	f, err := ParseReportFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	switch f {
+	case "json":
+		return &jsonReport{w: w, records: []Record{}}, nil
+	case "ndjson":
+		return &ndjsonReport{enc: json.NewEncoder(w)}, nil
+	default:
+		return &csvReport{w: csv.NewWriter(w)}, nil
+	}
+}
+
+type jsonReport struct {
+	w       io.Writer
+	records []Record
+}
+
+func (r *jsonReport) Write(res FileResult) error {
+	r.records = append(r.records, NewRecord(res))
+	return nil
+}
+
+func (r *jsonReport) Close() error {
+	enc := json.NewEncoder(r.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r.records)
+}
+
+type ndjsonReport struct {
+	enc *json.Encoder
+}
+
+func (r *ndjsonReport) Write(res FileResult) error {
+	return r.enc.Encode(NewRecord(res))
+}
+
+func (r *ndjsonReport) Close() error { return nil }
+
+// csvHeader is the column order of CSV reports; diagnostics and changes are embedded as
+// JSON arrays.
+// New columns go before changes so existing column positions stay put.
+var csvHeader = []string{
+	"path", "output", "format", "cues", "cues_changed", "cues_removed",
+	"load_ms", "parse_ms", "transform_ms", "write_ms", "total_ms", "error", "remuxed", "hi_level", "hi_score", "diagnostics", "changes",
+}
+
+type csvReport struct {
+	w           *csv.Writer
+	wroteHeader bool
+}
+
+func (r *csvReport) Write(res FileResult) error {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		if err := r.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	rec := NewRecord(res)
+	changes, err := json.Marshal(rec.Changes)
+	if err != nil {
+		return err
+	}
+	var diagnostics []byte
+	if len(rec.Diagnostics) > 0 {
+		if diagnostics, err = json.Marshal(rec.Diagnostics); err != nil {
+			return err
+		}
+	}
+	ms := func(v float64) string { return strconv.FormatFloat(v, 'f', 3, 64) }
+	var hiLevel, hiScore string
+	if rec.HI != nil {
+		hiLevel, hiScore = string(rec.HI.Level), strconv.FormatFloat(rec.HI.Value, 'f', 3, 64)
+	}
+	if err := r.w.Write([]string{
+		rec.Path, rec.Output, rec.Format,
+		strconv.Itoa(rec.Cues), strconv.Itoa(rec.CuesChanged), strconv.Itoa(rec.CuesRemoved),
+		ms(rec.Timings.LoadMs), ms(rec.Timings.ParseMs), ms(rec.Timings.TransformMs),
+		ms(rec.Timings.WriteMs), ms(rec.Timings.TotalMs),
+		rec.Error, rec.Remuxed, hiLevel, hiScore, string(diagnostics), string(changes),
+	}); err != nil {
+		return err
+	}
+	r.w.Flush()
+	return r.w.Error()
+}
+
+func (r *csvReport) Close() error {
+	if !r.wroteHeader {
+		r.wroteHeader = true
+		if err := r.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	r.w.Flush()
+	return r.w.Error()
+}
@@ -0,0 +1,116 @@
+package batch
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+var reportResults = []FileResult{
+	{
+		Path: "a.srt", Output: "a-his.srt", Format: "srt", Cues: 2, CuesChanged: 1, CuesRemoved: 1,
+		HI: &hi.Score{Cues: 2, Marked: 2, Bracketed: 1, Speakers: 1, Value: 1, Level: hi.LevelSDH},
+		Changes: []transform.CueChange{
+			{CueIndex: 1, Original: "[DOOR]", Transformed: "", Rules: []string{"\\ Delims / [ ]"}},
+			{CueIndex: 2, Original: "JOHN: Hi", Transformed: "Hi", Rules: []string{"TEXT:"}},
+		},
+		Diagnostics: []subtitle.Diagnostic{{Line: 5, Severity: subtitle.SeverityWarning, Message: "cue has no text"}},
+		Timings:     Timings{Load: time.Millisecond, Parse: 2 * time.Millisecond},
+	},
+	{Path: "b.srt", Err: errors.New("data is empty")},
+}
+
+func writeReport(t *testing.T, format string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	rw, err := NewReportWriter(&buf, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range reportResults {
+		if err := rw.Write(res); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestReport_json(t *testing.T) {
+	var records []Record
+	if err := json.Unmarshal([]byte(writeReport(t, "json")), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("got %d records", len(records))
+	}
+	if got := records[0]; len(got.Changes) != 2 || got.Changes[1].Transformed != "Hi" || got.Timings.TotalMs != 3 {
+		t.Fatalf("record 0: %+v", got)
+	}
+	if got := records[0].Diagnostics; len(got) != 1 || got[0].Line != 5 {
+		t.Fatalf("record 0 diagnostics: %+v", got)
+	}
+	if got := records[0].HI; got == nil || got.Level != hi.LevelSDH || got.Speakers != 1 {
+		t.Fatalf("record 0 hi: %+v", got)
+	}
+	if got := records[1]; got.Error != "data is empty" || got.Changes == nil || got.HI != nil {
+		t.Fatalf("record 1: %+v", got)
+	}
+}
+
+func TestReport_ndjson(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(writeReport(t, "jsonl")), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines", len(lines))
+	}
+	var rec Record
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Path != "a.srt" || rec.Output != "a-his.srt" {
+		t.Fatalf("record: %+v", rec)
+	}
+}
+
+func TestReport_csv(t *testing.T) {
+	rows, err := csv.NewReader(strings.NewReader(writeReport(t, "csv"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0][0] != "path" {
+		t.Fatalf("rows: %v", rows)
+	}
+	var changes []transform.CueChange
+	if err := json.Unmarshal([]byte(rows[1][len(rows[1])-1]), &changes); err != nil || len(changes) != 2 {
+		t.Fatalf("changes column: %q (%v)", rows[1][len(rows[1])-1], err)
+	}
+	if rows[2][11] != "data is empty" {
+		t.Fatalf("error column: %v", rows[2])
+	}
+	if rows[0][13] != "hi_level" || rows[1][13] != "sdh" || rows[1][14] != "1.000" || rows[2][13] != "" {
+		t.Fatalf("hi columns: %v", rows)
+	}
+	var diags []subtitle.Diagnostic
+	if err := json.Unmarshal([]byte(rows[1][15]), &diags); rows[0][15] != "diagnostics" || err != nil || len(diags) != 1 || rows[2][15] != "" {
+		t.Fatalf("diagnostics column: %v (%v)", rows, err)
+	}
+}
+
+func TestParseReportFormat(t *testing.T) {
+	if _, err := ParseReportFormat("xml"); err == nil {
+		t.Fatal("expected error")
+	}
+	if f, err := ParseReportFormat("NDJSON"); err != nil || f != "ndjson" {
+		t.Fatalf("got %q, %v", f, err)
+	}
+}
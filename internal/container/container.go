@@ -0,0 +1,30 @@
+// Package container lists and extracts the subtitle tracks of video containers: Matroska
+// and WebM with the native demuxer (internal/matroska), MP4/MOV, MPEG-TS and AVI through
+// ffprobe/ffmpeg. It also remuxes sanitized tracks back (see Remux).
+package container
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// matroskaExtensions are read natively; ffmpeg is only a fallback for them.
+var matroskaExtensions = []string{".mkv", ".mks", ".webm"}
+
+// Extensions lists every accepted container extension.
+var Extensions = append(slices.Clone(matroskaExtensions), ".mp4", ".m4v", ".mov", ".ts", ".m2ts", ".avi")
+
+// IsContainer reports whether path has one of Extensions (case-insensitive).
+func IsContainer(path string) bool {
+	return slices.Contains(Extensions, strings.ToLower(filepath.Ext(path)))
+}
+
+func isMatroska(path string) bool {
+	return slices.Contains(matroskaExtensions, strings.ToLower(filepath.Ext(path)))
+}
+
+// trimExt removes the extension of path (E:\folder\file.m2ts -> E:\folder\file).
+func trimExt(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path))
+}
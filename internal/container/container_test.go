@@ -0,0 +1,53 @@
+package container
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestIsContainer(t *testing.T) {
+	t.Parallel()
+
+	for path, want := range map[string]bool{
+		"movie.mkv": true, "Movie.MP4": true, "clip.webm": true, "show.m2ts": true, "old.avi": true,
+		"movie.srt": false, "movie": false, "song.mka": false,
+	} {
+		if got := IsContainer(path); got != want {
+			t.Fatalf("IsContainer(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+func TestOutputNames(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{name: "mp4 text", got: extractedPath("/v/movie.mp4", Track{Index: 2, ext: ".srt"}), want: "/v/movie.srt"},
+		{name: "m2ts", got: extractedPath("/v/show.m2ts", Track{Index: 4, ext: ".sup"}), want: "/v/show.sup"},
+		{name: "same as input", got: extractedPath("/v/subs.mks", Track{Index: 3, ext: ".mks"}), want: "/v/subs.3.mks"},
+		{name: "remux mkv", got: RemuxOutputPath("/v/movie.mkv"), want: "/v/movie.clean.mkv"},
+		{name: "remux mp4", got: RemuxOutputPath("/v/movie.mp4"), want: "/v/movie.clean.mkv"},
+		{name: "remux webm", got: RemuxOutputPath("/v/clip.webm"), want: "/v/clip.clean.webm"},
+	}
+	for _, tc := range tests {
+		if tc.got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestProbeTracks_needsFFprobe(t *testing.T) {
+	original := execLookPath
+	t.Cleanup(func() { execLookPath = original })
+	execLookPath = func(file string) (string, error) { return "", errors.New("not found") }
+
+	if _, err := ProbeTracks("movie.mp4"); err == nil || !strings.Contains(err.Error(), "ffprobe") {
+		t.Fatalf("ProbeTracks(mp4) without ffprobe: err = %v", err)
+	}
+	if _, err := ProbeTracks("movie.srt"); err == nil || !strings.Contains(err.Error(), "unsupported extension") {
+		t.Fatalf("ProbeTracks(srt): err = %v", err)
+	}
+}
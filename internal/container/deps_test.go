@@ -1,4 +1,4 @@
-package mkv
+package container
 
 import (
 	"errors"
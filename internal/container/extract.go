@@ -0,0 +1,271 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+type subtitleTrack struct {
+	Index         int         `json:"index"`
+	Codec         string      `json:"codec_name"`
+	CodecTag      string      `json:"codec_tag_string"`
+	CodecLongName string      `json:"codec_long_name"`
+	Disposition   Disposition `json:"disposition"`
+	Tags          Tag         `json:"tags"`
+}
+type Tag struct {
+	Language          string `json:"language"`
+	Title             string `json:"title"`
+	NumberOfFrames    string `json:"NUMBER_OF_FRAMES"`     // mkvmerge statistics tags
+	NumberOfFramesEng string `json:"NUMBER_OF_FRAMES-eng"` // (cue count for subtitles)
+}
+type Disposition struct {
+	Default         int `json:"default"`
+	Forced          int `json:"forced"`
+	HearingImpaired int `json:"hearing_impaired"`
+}
+
+type ffprobeOutput struct {
+	Streams []subtitleTrack `json:"streams"`
+}
+
+var execLookPath = exec.LookPath
+
+func trackLanguage(t subtitleTrack) string {
+	lang := strings.ToLower(strings.TrimSpace(t.Tags.Language))
+	if lang == "und" {
+		return ""
+	}
+	return lang
+}
+
+func trackTitle(t subtitleTrack) string {
+	return t.Tags.Title
+}
+
+func titleContainsSDH(title string) bool {
+	return strings.Contains(strings.ToLower(title), "sdh")
+}
+
+func titleContainsForced(title string) bool {
+	return strings.Contains(strings.ToLower(title), "forced")
+}
+
+// subtitleTrackOrders reports whether a should sort before b (see PreferredOrder).
+func subtitleTrackOrders(a, b subtitleTrack) bool {
+	return trackOrders(a.track(), b.track())
+}
+
+func VerifyDependencies() error {
+	missing := []string{}
+	for _, binary := range []string{"ffprobe", "ffmpeg"} {
+		if _, err := execLookPath(binary); err != nil {
+			missing = append(missing, binary)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf(
+		"missing required tools: %s. Install FFmpeg (which includes ffmpeg + ffprobe) and ensure both are in PATH. Note: MediaInfo is useful for inspection, but it cannot extract subtitle streams",
+		strings.Join(missing, ", "),
+	)
+}
+
+// ExtractSingleSubtitle keeps backward compatibility by extracting only the first subtitle
+// and returning its file name and content.
+func ExtractSingleSubtitle(inputPath string) (string, []byte, error) {
+	return ExtractSelectedSubtitle(inputPath, Selector{})
+}
+
+// ExtractSelectedSubtitle extracts the preferred track matching sel next to the video.
+func ExtractSelectedSubtitle(inputPath string, sel Selector) (string, []byte, error) {
+	tracks, err := ProbeTracks(inputPath)
+	if err != nil {
+		return "", nil, err
+	}
+	track, err := SelectTrack(tracks, sel)
+	if err != nil {
+		return "", nil, err
+	}
+	return ExtractTrack(inputPath, track)
+}
+
+// ReadSingleSubtitle extracts the preferred track matching sel into a temporary directory
+// and returns the path ExtractSelectedSubtitle would have written, with the content.
+// Nothing is left next to the video (used by dry runs).
+func ReadSingleSubtitle(inputPath string, sel Selector) (string, []byte, error) {
+	tracks, err := ProbeTracks(inputPath)
+	if err != nil {
+		return "", nil, err
+	}
+	track, err := SelectTrack(tracks, sel)
+	if err != nil {
+		return "", nil, err
+	}
+	return ReadTrack(inputPath, track)
+}
+
+// ExtractOptions controls how ExtractMultipleSubtitles names and describes its outputs.
+type ExtractOptions struct {
+	Template NameTemplate // zero: DefaultNameTemplate
+	Sidecar  bool         // also write SUBTITLE.json with the source stream metadata
+	SkipSDH  bool         // leave out SDH tracks (by flag, title or content) that have a plain track in the same language
+	// Skipped is called with each track whose codec cannot be extracted; nil ignores them.
+	Skipped func(Track)
+}
+
+// ExtractMultipleSubtitles writes the first maxTracks subtitle tracks (all when 0), in
+// PreferredOrder, named by opts.Template. Tracks that would get the same name as an
+// earlier one get their stream index before the extension (Movie.en.3.srt).
+// It returns the first written path and the list of all of them.
+func ExtractMultipleSubtitles(inputPath string, maxTracks int, opts ExtractOptions) (first *string, list []string, err error) {
+	tracks, err := ProbeTracks(inputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if opts.SkipSDH {
+		AnalyzeTracks(inputPath, tracks)
+		tracks = withoutRedundantSDH(tracks)
+	}
+	PreferredOrder(tracks)
+	if maxTracks > 0 && maxTracks < len(tracks) {
+		tracks = tracks[:maxTracks]
+	}
+
+	used := map[string]bool{}
+	subtitleOutPaths := make([]string, 0, len(tracks))
+	for _, track := range tracks {
+		ext := track.Extension()
+		if ext == "" {
+			if opts.Skipped != nil {
+				opts.Skipped(track)
+			}
+			continue
+		}
+		outPath := opts.Template.Name(inputPath, track)
+		if used[strings.ToLower(outPath)] {
+			outPath = fmt.Sprintf("%s.%d%s", strings.TrimSuffix(outPath, ext), track.Index, ext)
+		}
+		used[strings.ToLower(outPath)] = true
+
+		data, err := readTrack(inputPath, track)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			return nil, nil, fmt.Errorf("create folder: %w", err)
+		}
+		if err := os.WriteFile(outPath, data, 0644); err != nil {
+			return nil, nil, fmt.Errorf("write extracted subtitle: %w", err)
+		}
+		if opts.Sidecar {
+			if err := WriteSidecar(inputPath, track, outPath); err != nil {
+				return nil, nil, err
+			}
+		}
+		subtitleOutPaths = append(subtitleOutPaths, outPath)
+	}
+	if len(subtitleOutPaths) == 0 {
+		return nil, nil, fmt.Errorf("no supported subtitle tracks found in %s", filepath.Base(inputPath))
+	}
+	return &subtitleOutPaths[0], subtitleOutPaths, nil
+}
+
+// withoutRedundantSDH drops SDH tracks when the same language has a full text track that
+// is neither SDH nor forced.
+func withoutRedundantSDH(tracks []Track) []Track {
+	plain := map[string]bool{}
+	for _, t := range tracks {
+		if t.IsText() && !t.SDH() && !t.IsForced() {
+			plain[t.Language] = true
+		}
+	}
+	out := tracks[:0:0]
+	for _, t := range tracks {
+		if !t.SDH() || !plain[t.Language] {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func BatchExtractSubtitles(inputPath string, opts ExtractOptions) (count int, err error) {
+	firstPath, paths, err := ExtractMultipleSubtitles(inputPath, 0, opts)
+	if err != nil {
+		return 0, err
+	}
+	if firstPath != nil {
+		count = 1
+	}
+	if paths != nil {
+		count = len(paths)
+	}
+	//return len(paths), nil
+	return count, nil
+}
+
+func probeSubtitleTracks(inputPath string) ([]subtitleTrack, error) {
+	cmd := exec.Command(
+		"ffprobe",
+		"-v", "error",
+		"-select_streams", "s",
+		"-show_entries", "stream=index,codec_name,codec_tag_string,codec_long_name"+
+			":stream_disposition=default,forced,hearing_impaired"+
+			":stream_tags=language,title,NUMBER_OF_FRAMES,NUMBER_OF_FRAMES-eng",
+		"-of", "json",
+		inputPath,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	var parsed ffprobeOutput
+	if err := json.Unmarshal(out, &parsed); err != nil {
+		return nil, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+	return parsed.Streams, nil
+}
+
+// runFFmpegExtractTrack copies the track stream to outPath, converting MP4 mov_text and
+// WebVTT to SRT.
+func runFFmpegExtractTrack(inputPath string, track Track, outPath string) error {
+	codec := "copy"
+	if track.Codec == "mov_text" || track.Codec == "webvtt" {
+		codec = "srt"
+	}
+	cmd := exec.Command(
+		"ffmpeg",
+		"-v", "error",
+		"-y",
+		"-i", inputPath,
+		"-map", fmt.Sprintf("0:%d", track.Index),
+		"-c:s", codec,
+		outPath,
+	)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("ffmpeg extract stream %d failed: %v (%s)", track.Index, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func subtitleExtension(track subtitleTrack) string {
+	codec := strings.ToLower(track.Codec)
+
+	switch {
+	case codec == "subrip" || codec == "srt" || codec == "mov_text" || codec == "webvtt":
+		return ".srt"
+	case codec == "ass" || codec == "ssa":
+		return ".ass"
+	case codec == "hdmv_pgs_subtitle":
+		return ".sup"
+	default:
+		// VobSub needs an .idx next to its .sub, which ffmpeg cannot write, and DVB
+		// has no standalone file format: both are skipped.
+		return ""
+	}
+}
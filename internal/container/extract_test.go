@@ -1,8 +1,11 @@
-package mkv
+package container
 
 import (
+	"fmt"
 	"sort"
 	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
 )
 
 func trackWith(lang, title string, index int) subtitleTrack {
@@ -19,11 +22,13 @@ func TestSubtitleExtension(t *testing.T) {
 		want  string
 	}{
 		{name: "subrip", track: subtitleTrack{Codec: "subrip"}, want: ".srt"},
+		{name: "mp4 mov_text", track: subtitleTrack{Codec: "mov_text"}, want: ".srt"},
+		{name: "dvb", track: subtitleTrack{Codec: "dvb_subtitle"}, want: ""},
 		{name: "ass", track: subtitleTrack{Codec: "ass"}, want: ".ass"},
 		{name: "ssa", track: subtitleTrack{Codec: "ssa"}, want: ".ass"},
-		{name: "vtt", track: subtitleTrack{Codec: "webvtt"}, want: ".vtt"},
+		{name: "vtt", track: subtitleTrack{Codec: "webvtt"}, want: ".srt"},
 		{name: "pgs", track: subtitleTrack{Codec: "hdmv_pgs_subtitle"}, want: ".sup"},
-		{name: "dvdsub from tag", track: subtitleTrack{CodecTag: "DVDS"}, want: ".sub"},
+		{name: "dvdsub", track: subtitleTrack{Codec: "dvd_subtitle", CodecTag: "DVDS"}, want: ""},
 		{name: "unknown", track: subtitleTrack{Codec: "unknown"}, want: ""},
 	}
 
@@ -118,3 +123,22 @@ func TestSubtitleTrackOrdersSortsSlice(t *testing.T) {
 		t.Fatalf("fourth track: want spa SDH")
 	}
 }
+
+func TestWithoutRedundantSDH(t *testing.T) {
+	t.Parallel()
+
+	tracks := []Track{
+		{Index: 2, Language: "eng", ext: ".srt"},
+		{Index: 3, Language: "eng", HI: &hi.Score{Level: hi.LevelSDH}, ext: ".srt"}, // detected by content
+		{Index: 4, Language: "spa", HearingImpaired: true, ext: ".srt"},             // spa has no full plain track
+		{Index: 5, Language: "eng", Title: "SDH", ext: ".sup"},                      // image, plain eng exists
+		{Index: 6, Language: "spa", Title: "Forced", HI: &hi.Score{}, ext: ".srt"},  // forced: not a replacement
+	}
+	var got []int
+	for _, tr := range withoutRedundantSDH(tracks) {
+		got = append(got, tr.Index)
+	}
+	if fmt.Sprint(got) != "[2 4 6]" {
+		t.Fatalf("withoutRedundantSDH = %v, want [2 4 6]", got)
+	}
+}
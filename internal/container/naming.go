@@ -0,0 +1,211 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// DefaultNameTemplate names extracted tracks the way Plex and Jellyfin expect:
+// Movie.en.srt, Movie.en.sdh.srt, Movie.pt.forced.srt.
+const DefaultNameTemplate = "{base}.{lang2}{.forced}{.sdh}.{ext}"
+
+// iso6391 maps ISO 639-2 codes (bibliographic and terminology forms) to ISO 639-1.
+var iso6391 = map[string]string{
+	"afr": "af", "alb": "sq", "sqi": "sq", "amh": "am", "ara": "ar", "arm": "hy", "hye": "hy",
+	"aze": "az", "baq": "eu", "eus": "eu", "bel": "be", "ben": "bn", "bos": "bs", "bul": "bg",
+	"bur": "my", "mya": "my", "cat": "ca", "chi": "zh", "zho": "zh", "hrv": "hr", "cze": "cs",
+	"ces": "cs", "dan": "da", "dut": "nl", "nld": "nl", "eng": "en", "epo": "eo", "est": "et",
+	"fil": "tl", "tgl": "tl", "fin": "fi", "fre": "fr", "fra": "fr", "glg": "gl", "geo": "ka",
+	"kat": "ka", "ger": "de", "deu": "de", "gre": "el", "ell": "el", "guj": "gu", "heb": "he",
+	"hin": "hi", "hun": "hu", "ice": "is", "isl": "is", "ind": "id", "gle": "ga", "ita": "it",
+	"jpn": "ja", "kan": "kn", "kaz": "kk", "khm": "km", "kor": "ko", "kur": "ku", "lao": "lo",
+	"lat": "la", "lav": "lv", "lit": "lt", "mac": "mk", "mkd": "mk", "may": "ms", "msa": "ms",
+	"mal": "ml", "mlt": "mt", "mar": "mr", "mon": "mn", "nep": "ne", "nor": "no", "nob": "nb",
+	"nno": "nn", "per": "fa", "fas": "fa", "pol": "pl", "por": "pt", "pan": "pa", "rum": "ro",
+	"ron": "ro", "rus": "ru", "srp": "sr", "sin": "si", "slo": "sk", "slk": "sk", "slv": "sl",
+	"som": "so", "spa": "es", "swa": "sw", "swe": "sv", "tam": "ta", "tel": "te", "tha": "th",
+	"tib": "bo", "bod": "bo", "tur": "tr", "ukr": "uk", "urd": "ur", "uzb": "uz", "vie": "vi",
+	"wel": "cy", "cym": "cy", "yid": "yi", "zul": "zu",
+}
+
+// Lang2 returns the ISO 639-1 code of an ISO 639-2 language ("eng" -> "en"). Two-letter
+// codes pass through; codes without a 639-1 form are kept as they are.
+func Lang2(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if two, ok := iso6391[lang]; ok {
+		return two
+	}
+	return lang
+}
+
+// templateFields are the placeholders a NameTemplate may use. Each can also be written
+// as {.field}: a dot followed by the value, or nothing when the value is empty.
+var templateFields = map[string]func(base string, t Track) string{
+	"base":  func(base string, _ Track) string { return base },
+	"lang":  func(_ string, t Track) string { return t.Language },
+	"lang2": func(_ string, t Track) string { return Lang2(t.Language) },
+	"index": func(_ string, t Track) string { return strconv.Itoa(t.Index) },
+	"codec": func(_ string, t Track) string { return t.Codec },
+	"title": func(_ string, t Track) string { return safeName(t.Title) },
+	"ext":   func(_ string, t Track) string { return strings.TrimPrefix(t.Extension(), ".") },
+	"forced": func(_ string, t Track) string {
+		if t.IsForced() {
+			return "forced"
+		}
+		return ""
+	},
+	"sdh": func(_ string, t Track) string {
+		if t.SDH() {
+			return "sdh"
+		}
+		return ""
+	},
+	"default": func(_ string, t Track) string {
+		if t.Default {
+			return "default"
+		}
+		return ""
+	},
+}
+
+// NameTemplate names the files ExtractMultipleSubtitles writes, relative to the video
+// directory (eg: "{base}.{lang2}{.forced}{.sdh}.{ext}", "Subs/{base}.{index}.{lang}.{ext}").
+type NameTemplate struct {
+	tmpl string
+}
+
+// ParseNameTemplate validates tmpl; empty means DefaultNameTemplate.
+func ParseNameTemplate(tmpl string) (NameTemplate, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		tmpl = DefaultNameTemplate
+	}
+	rest := tmpl
+	for {
+		open := strings.IndexByte(rest, '{')
+		literal := rest
+		if open >= 0 {
+			literal = rest[:open]
+		}
+		if strings.ContainsRune(literal, '}') {
+			return NameTemplate{}, fmt.Errorf("name template %q: unmatched }", tmpl)
+		}
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return NameTemplate{}, fmt.Errorf("name template %q: unclosed {", tmpl)
+		}
+		field := strings.TrimPrefix(rest[open+1:open+end], ".")
+		if _, ok := templateFields[field]; !ok {
+			return NameTemplate{}, fmt.Errorf("name template %q: unknown field {%s}", tmpl, rest[open+1:open+end])
+		}
+		rest = rest[open+end+1:]
+	}
+	if !strings.Contains(tmpl, "{ext}") && !strings.Contains(tmpl, "{.ext}") {
+		return NameTemplate{}, fmt.Errorf("name template %q: missing {ext}", tmpl)
+	}
+	return NameTemplate{tmpl: tmpl}, nil
+}
+
+func (n NameTemplate) String() string {
+	if n.tmpl == "" {
+		return DefaultNameTemplate
+	}
+	return n.tmpl
+}
+
+// Name renders the template for track of the video at inputPath. Dots left over by empty
+// fields are collapsed ("{base}.{lang2}.{ext}" without a language gives Movie.srt).
+func (n NameTemplate) Name(inputPath string, track Track) string {
+	const baseMark = "\x00"
+	var sb strings.Builder
+	rest := n.String()
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := open + strings.IndexByte(rest[open:], '}')
+		sb.WriteString(rest[:open])
+		field := rest[open+1 : end]
+		value := baseMark // the base name keeps its own dots
+		if name := strings.TrimPrefix(field, "."); name != "base" {
+			value = templateFields[name]("", track)
+		}
+		if strings.HasPrefix(field, ".") && value != "" {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(value)
+		rest = rest[end+1:]
+	}
+
+	name := filepath.FromSlash(sb.String())
+	for strings.Contains(name, "..") {
+		name = strings.ReplaceAll(name, "..", ".")
+	}
+	var parts []string
+	for part := range strings.SplitSeq(name, string(filepath.Separator)) {
+		if part = strings.Trim(part, ". "); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	name = strings.ReplaceAll(filepath.Join(parts...), baseMark, filepath.Base(trimExt(inputPath)))
+	return filepath.Join(filepath.Dir(inputPath), name)
+}
+
+// safeName replaces characters that are not allowed in file names.
+func safeName(s string) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < ' ' {
+			return '_'
+		}
+		return r
+	}, s))
+}
+
+// Sidecar records where an extracted subtitle came from (written as SUBTITLE.json).
+type Sidecar struct {
+	Source          string `json:"source"`
+	Index           int    `json:"index"`
+	Codec           string `json:"codec"`
+	Language        string `json:"language,omitempty"`
+	Title           string `json:"title,omitempty"`
+	Default         bool   `json:"default"`
+	Forced          bool   `json:"forced"`
+	HearingImpaired bool   `json:"hearingImpaired"`
+	Cues            int    `json:"cues,omitempty"`
+}
+
+// SidecarPath is the metadata file of an extracted subtitle: Movie.en.srt -> Movie.en.srt.json.
+func SidecarPath(subtitlePath string) string {
+	return subtitlePath + ".json"
+}
+
+// WriteSidecar writes the Sidecar of track, extracted from inputPath, next to subtitlePath.
+func WriteSidecar(inputPath string, track Track, subtitlePath string) error {
+	s := Sidecar{
+		Source:          filepath.Base(inputPath),
+		Index:           track.Index,
+		Codec:           track.Codec,
+		Language:        track.Language,
+		Title:           track.Title,
+		Default:         track.Default,
+		Forced:          track.Forced,
+		HearingImpaired: track.HearingImpaired,
+		Cues:            max(track.Cues, 0),
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal sidecar: %w", err)
+	}
+	if err := os.WriteFile(SidecarPath(subtitlePath), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("write sidecar: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLang2(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{"eng": "en", "POR": "pt", "ger": "de", "deu": "de", "pt": "pt", "tlh": "tlh", "": ""}
+	for in, want := range tests {
+		if got := Lang2(in); got != want {
+			t.Fatalf("Lang2(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestNameTemplate(t *testing.T) {
+	t.Parallel()
+
+	video := filepath.Join("media", "Movie.2020.mkv")
+	tests := []struct {
+		tmpl  string
+		track Track
+		want  string
+	}{
+		{tmpl: "", track: Track{Language: "eng", ext: ".srt"}, want: "Movie.2020.en.srt"},
+		{tmpl: "", track: Track{Language: "eng", HearingImpaired: true, ext: ".srt"}, want: "Movie.2020.en.sdh.srt"},
+		{tmpl: "", track: Track{Language: "por", Forced: true, ext: ".ass"}, want: "Movie.2020.pt.forced.ass"},
+		{tmpl: "", track: Track{ext: ".sup"}, want: "Movie.2020.sup"},
+		{tmpl: "{base}.{index}.{lang}{.title}.{ext}", track: Track{Index: 3, Language: "spa", Title: "Signs: 1/2", ext: ".srt"}, want: "Movie.2020.3.spa.Signs_ 1_2.srt"},
+		{tmpl: "Subs/{lang2}{.default}.{ext}", track: Track{Language: "fre", Default: true, ext: ".srt"}, want: filepath.Join("Subs", "fr.default.srt")},
+	}
+	for _, tc := range tests {
+		tmpl, err := ParseNameTemplate(tc.tmpl)
+		if err != nil {
+			t.Fatalf("ParseNameTemplate(%q): %v", tc.tmpl, err)
+		}
+		if got := tmpl.Name(video, tc.track); got != filepath.Join("media", tc.want) {
+			t.Fatalf("%q.Name(%+v) = %q, want %q", tc.tmpl, tc.track, got, tc.want)
+		}
+	}
+
+	// the zero template is the default one
+	if got := (NameTemplate{}).Name(video, Track{Language: "eng", ext: ".srt"}); got != filepath.Join("media", "Movie.2020.en.srt") {
+		t.Fatalf("NameTemplate{}.Name = %q", got)
+	}
+
+	for _, bad := range []string{"{base}.{language}.{ext}", "{base}.{lang2", "{base}}.{ext}", "{base}.{lang2}"} {
+		if _, err := ParseNameTemplate(bad); err == nil {
+			t.Fatalf("ParseNameTemplate(%q) expected error", bad)
+		}
+	}
+}
+
+func TestExtractMultipleSubtitles_templateAndSidecar(t *testing.T) {
+	original := execLookPath
+	t.Cleanup(func() { execLookPath = original })
+	execLookPath = func(file string) (string, error) { return "", errors.New("not found") }
+
+	data, err := os.ReadFile(filepath.Join("testdata", "sample.mkv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	video := filepath.Join(t.TempDir(), "sample.mkv")
+	if err := os.WriteFile(video, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl, _ := ParseNameTemplate("{base}.{lang2}.{ext}")
+	_, paths, err := ExtractMultipleSubtitles(video, 0, ExtractOptions{Template: tmpl, Sidecar: true})
+	if err != nil {
+		t.Fatalf("ExtractMultipleSubtitles: %v", err)
+	}
+	dir := filepath.Dir(video)
+	want := []string{filepath.Join(dir, "sample.en.ass"), filepath.Join(dir, "sample.es.srt")}
+	if len(paths) != len(want) || paths[0] != want[0] || paths[1] != want[1] {
+		t.Fatalf("paths = %q, want %q", paths, want)
+	}
+
+	raw, err := os.ReadFile(SidecarPath(paths[1]))
+	if err != nil {
+		t.Fatalf("read sidecar: %v", err)
+	}
+	var got Sidecar
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("parse sidecar: %v", err)
+	}
+	wantSidecar := Sidecar{Source: "sample.mkv", Index: 1, Codec: "subrip", Language: "spa", HearingImpaired: true, Cues: 2}
+	if got.Source != wantSidecar.Source || got.Index != wantSidecar.Index || got.Codec != wantSidecar.Codec ||
+		got.Language != wantSidecar.Language || got.HearingImpaired != wantSidecar.HearingImpaired || got.Cues != wantSidecar.Cues {
+		t.Fatalf("sidecar = %+v, want %+v", got, wantSidecar)
+	}
+
+}
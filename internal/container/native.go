@@ -0,0 +1,113 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/matroska"
+)
+
+// openMatroska opens inputPath with the native demuxer. The caller closes the file.
+func openMatroska(inputPath string) (*matroska.File, *os.File, error) {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	mf, err := matroska.Open(file, info.Size())
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return mf, file, nil
+}
+
+// probeNative lists the subtitle tracks without ffprobe.
+func probeNative(inputPath string) ([]Track, error) {
+	mf, file, err := openMatroska(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tracks []Track
+	for _, t := range mf.SubtitleTracks() {
+		ext := t.Extension()
+		if ext == "" {
+			// codecs ffmpeg extracts but the demuxer does not rebuild
+			ext = subtitleExtension(subtitleTrack{Codec: t.CodecName()})
+		}
+		tracks = append(tracks, Track{
+			Index:           t.Index,
+			Codec:           t.CodecName(),
+			Language:        trackLanguage(subtitleTrack{Tags: Tag{Language: t.Language}}),
+			Title:           t.Name,
+			Default:         t.Default,
+			Forced:          t.Forced,
+			HearingImpaired: t.HearingImpaired,
+			Cues:            t.Frames,
+			ext:             ext,
+		})
+	}
+	return tracks, nil
+}
+
+// readNative demuxes the track with stream index index. ok is false when the codec
+// has no native extraction (the caller falls back to ffmpeg).
+func readNative(inputPath string, index int) (data []byte, ok bool, err error) {
+	mf, file, err := openMatroska(inputPath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer file.Close()
+
+	for _, t := range mf.SubtitleTracks() {
+		if t.Index != index {
+			continue
+		}
+		if t.Extension() == "" {
+			return nil, false, nil
+		}
+		data, err := mf.ExtractSubtitle(t)
+		return data, true, err
+	}
+	return nil, false, fmt.Errorf("no subtitle track with index %d", index)
+}
+
+// readNativeTracks demuxes the tracks with the given stream indexes in one walk over the
+// clusters. Tracks without native extraction, or that fail to render, are left out.
+func readNativeTracks(inputPath string, indexes []int) (map[int][]byte, error) {
+	mf, file, err := openMatroska(inputPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var tracks []matroska.Track
+	var numbers []uint64
+	for _, t := range mf.SubtitleTracks() {
+		if t.Extension() != "" && slices.Contains(indexes, t.Index) {
+			tracks = append(tracks, t)
+			numbers = append(numbers, t.Number)
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, nil
+	}
+	blocks, err := mf.Blocks(numbers...)
+	if err != nil {
+		return nil, err
+	}
+	out := map[int][]byte{}
+	for _, t := range tracks {
+		if data, err := matroska.Render(t, blocks[t.Number]); err == nil {
+			out[t.Index] = data
+		}
+	}
+	return out, nil
+}
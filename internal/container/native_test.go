@@ -0,0 +1,50 @@
+package container
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sample.mkv: video (0), spa SDH S_TEXT/UTF8 (1, 2 frames tag), eng forced "Signs" zlib S_TEXT/ASS (2).
+func TestNativeWithoutFFmpeg(t *testing.T) {
+	original := execLookPath
+	t.Cleanup(func() { execLookPath = original })
+	execLookPath = func(file string) (string, error) { return "", errors.New("not found") }
+
+	path := filepath.Join("testdata", "sample.mkv")
+	tracks, err := ProbeTracks(path)
+	if err != nil {
+		t.Fatalf("ProbeTracks: %v", err)
+	}
+	if len(tracks) != 2 {
+		t.Fatalf("tracks = %+v, want 2", tracks)
+	}
+	if got := tracks[0]; got.Index != 1 || got.Codec != "subrip" || got.Language != "spa" || !got.SDH() || got.Cues != 2 || got.Extension() != ".srt" {
+		t.Fatalf("track 1 = %+v", got)
+	}
+	if got := tracks[1]; got.Index != 2 || got.Codec != "ass" || !got.IsForced() || got.Cues != -1 || got.Extension() != ".ass" {
+		t.Fatalf("track 2 = %+v", got)
+	}
+
+	sel, _ := ParseSelector("lang=spa")
+	out, data, err := ReadSingleSubtitle(path, sel)
+	if err != nil {
+		t.Fatalf("ReadSingleSubtitle: %v", err)
+	}
+	if out != filepath.Join("testdata", "sample.srt") {
+		t.Fatalf("path = %q", out)
+	}
+	if !strings.Contains(string(data), "00:00:01,500 --> 00:00:03,000\nHola") {
+		t.Fatalf("srt = %q", data)
+	}
+
+	AnalyzeTracks(path, tracks)
+	for _, tr := range tracks {
+		if tr.HI == nil || tr.HI.Cues == 0 {
+			t.Fatalf("track %d not analyzed: %+v", tr.Index, tr.HI)
+		}
+	}
+
+}
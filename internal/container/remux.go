@@ -0,0 +1,155 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// RemuxMode says what happens to the source subtitle track in the remuxed MKV.
+type RemuxMode string
+
+const (
+	RemuxAdd     RemuxMode = "add"     // keep the source track; the clean one takes its default flag
+	RemuxReplace RemuxMode = "replace" // drop the source track
+)
+
+// ParseRemuxMode validates a --remux value.
+func ParseRemuxMode(s string) (RemuxMode, error) {
+	switch m := RemuxMode(strings.ToLower(strings.TrimSpace(s))); m {
+	case RemuxAdd, RemuxReplace:
+		return m, nil
+	}
+	return "", fmt.Errorf("unknown remux mode %q (add, replace)", s)
+}
+
+// languageNames names the common ISO 639-2 codes for clean track titles.
+var languageNames = map[string]string{
+	"eng": "English", "spa": "Spanish", "por": "Portuguese", "fre": "French", "fra": "French",
+	"ger": "German", "deu": "German", "ita": "Italian", "dut": "Dutch", "nld": "Dutch",
+	"jpn": "Japanese", "kor": "Korean", "chi": "Chinese", "zho": "Chinese", "rus": "Russian",
+	"pol": "Polish", "swe": "Swedish", "nor": "Norwegian", "dan": "Danish", "fin": "Finnish",
+	"tur": "Turkish", "ara": "Arabic", "heb": "Hebrew", "hin": "Hindi", "gre": "Greek", "ell": "Greek",
+}
+
+// CleanTitle is the title of the remuxed track: the source title, or the language name,
+// followed by " (clean)". eg: "English (clean)", "Full (clean)".
+func CleanTitle(source Track) string {
+	title := strings.TrimSpace(source.Title)
+	if title == "" {
+		title = languageNames[source.Language]
+	}
+	if title == "" {
+		title = "Subtitle"
+	}
+	return title + " (clean)"
+}
+
+// RemuxOutputPath is the file Remux writes for inputPath: file.mkv -> file.clean.mkv.
+// Other containers become Matroska too (file.mp4 -> file.clean.mkv), WebM stays WebM.
+func RemuxOutputPath(inputPath string) string {
+	ext := strings.ToLower(filepath.Ext(inputPath))
+	if ext != ".webm" {
+		ext = ".mkv"
+	}
+	return trimExt(inputPath) + ".clean" + ext
+}
+
+// Remux writes RemuxOutputPath(inputPath) with the sanitized subtitle file added as a
+// new track (or replacing source), keeping every other stream. The new track gets the
+// language and default/forced flags of source and the CleanTitle. mkvmerge is used when
+// installed, ffmpeg otherwise (Matroska inputs only: MP4 text tracks cannot be copied).
+func Remux(inputPath string, source Track, subtitlePath string, mode RemuxMode) (string, error) {
+	out := RemuxOutputPath(inputPath)
+	var tool string
+	var args []string
+	if _, err := execLookPath("mkvmerge"); err == nil {
+		tool, args = "mkvmerge", mkvmergeRemuxArgs(inputPath, source, subtitlePath, mode, out)
+	} else if _, err := execLookPath("ffmpeg"); err == nil && isMatroska(inputPath) {
+		tracks, err := ProbeTracks(inputPath)
+		if err != nil {
+			return "", err
+		}
+		tool, args = "ffmpeg", ffmpegRemuxArgs(inputPath, tracks, source, subtitlePath, mode, out)
+	} else {
+		return "", errors.New("remux needs mkvmerge in PATH (or ffmpeg for Matroska inputs)")
+	}
+	if combined, err := exec.Command(tool, args...).CombinedOutput(); err != nil {
+		// mkvmerge exits with 1 when it only printed warnings; the output is complete.
+		var exitErr *exec.ExitError
+		if tool != "mkvmerge" || !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+			_ = os.Remove(out)
+			return "", fmt.Errorf("%s remux failed: %v (%s)", tool, err, strings.TrimSpace(string(combined)))
+		}
+	}
+	return out, nil
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// mkvmergeRemuxArgs: mkvmerge track IDs match the stream indexes of a Matroska input.
+func mkvmergeRemuxArgs(inputPath string, source Track, subtitlePath string, mode RemuxMode, out string) []string {
+	id := strconv.Itoa(source.Index)
+	args := []string{"-q", "-o", out}
+	if mode == RemuxReplace {
+		args = append(args, "--subtitle-tracks", "!"+id)
+	} else if source.Default {
+		args = append(args, "--default-track-flag", id+":no")
+	}
+	args = append(args, inputPath)
+	if source.Language != "" {
+		args = append(args, "--language", "0:"+source.Language)
+	}
+	return append(args,
+		"--track-name", "0:"+CleanTitle(source),
+		"--default-track-flag", "0:"+yesNo(source.Default),
+		"--forced-display-flag", "0:"+yesNo(source.Forced),
+		subtitlePath,
+	)
+}
+
+// ffmpegRemuxArgs maps every input stream (minus source when replacing) and the clean
+// subtitle last; its metadata is addressed by its position among output subtitle streams.
+func ffmpegRemuxArgs(inputPath string, tracks []Track, source Track, subtitlePath string, mode RemuxMode, out string) []string {
+	args := []string{"-v", "error", "-y", "-i", inputPath, "-i", subtitlePath, "-map", "0"}
+	kept := len(tracks)
+	if mode == RemuxReplace {
+		args = append(args, "-map", fmt.Sprintf("-0:%d", source.Index))
+		kept--
+	}
+	args = append(args, "-map", "1:0", "-c", "copy")
+	if mode == RemuxAdd && source.Default {
+		for pos, t := range tracks {
+			if t.Index == source.Index {
+				args = append(args, fmt.Sprintf("-disposition:s:%d", pos), "0")
+			}
+		}
+	}
+	disposition := "0"
+	switch {
+	case source.Default && source.Forced:
+		disposition = "default+forced"
+	case source.Default:
+		disposition = "default"
+	case source.Forced:
+		disposition = "forced"
+	}
+	clean := fmt.Sprintf("s:%d", kept)
+	if source.Language != "" {
+		args = append(args, "-metadata:s:"+clean, "language="+source.Language)
+	}
+	return append(args,
+		"-metadata:s:"+clean, "title="+CleanTitle(source),
+		"-disposition:"+clean, disposition,
+		out,
+	)
+}
@@ -0,0 +1,81 @@
+package container
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCleanTitle(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		track Track
+		want  string
+	}{
+		{track: Track{Language: "eng"}, want: "English (clean)"},
+		{track: Track{Language: "eng", Title: "Full"}, want: "Full (clean)"},
+		{track: Track{Language: "xyz"}, want: "Subtitle (clean)"},
+	}
+	for _, tc := range tests {
+		if got := CleanTitle(tc.track); got != tc.want {
+			t.Fatalf("CleanTitle(%+v) = %q, want %q", tc.track, got, tc.want)
+		}
+	}
+}
+
+func TestRemuxArgs(t *testing.T) {
+	t.Parallel()
+
+	tracks := []Track{
+		{Index: 2, Language: "eng", Default: true},
+		{Index: 3, Language: "spa"},
+	}
+	source := tracks[0]
+
+	tests := []struct {
+		name string
+		got  []string
+		want string
+	}{
+		{
+			name: "mkvmerge add",
+			got:  mkvmergeRemuxArgs("in.mkv", source, "in-his.srt", RemuxAdd, "in.clean.mkv"),
+			want: "-q -o in.clean.mkv --default-track-flag 2:no in.mkv --language 0:eng --track-name 0:English (clean) " +
+				"--default-track-flag 0:yes --forced-display-flag 0:no in-his.srt",
+		},
+		{
+			name: "mkvmerge replace",
+			got:  mkvmergeRemuxArgs("in.mkv", source, "in-his.srt", RemuxReplace, "in.clean.mkv"),
+			want: "-q -o in.clean.mkv --subtitle-tracks !2 in.mkv --language 0:eng --track-name 0:English (clean) " +
+				"--default-track-flag 0:yes --forced-display-flag 0:no in-his.srt",
+		},
+		{
+			name: "ffmpeg add",
+			got:  ffmpegRemuxArgs("in.mkv", tracks, source, "in-his.srt", RemuxAdd, "in.clean.mkv"),
+			want: "-v error -y -i in.mkv -i in-his.srt -map 0 -map 1:0 -c copy -disposition:s:0 0 " +
+				"-metadata:s:s:2 language=eng -metadata:s:s:2 title=English (clean) -disposition:s:2 default in.clean.mkv",
+		},
+		{
+			name: "ffmpeg replace",
+			got:  ffmpegRemuxArgs("in.mkv", tracks, source, "in-his.srt", RemuxReplace, "in.clean.mkv"),
+			want: "-v error -y -i in.mkv -i in-his.srt -map 0 -map -0:2 -map 1:0 -c copy " +
+				"-metadata:s:s:1 language=eng -metadata:s:s:1 title=English (clean) -disposition:s:1 default in.clean.mkv",
+		},
+	}
+	for _, tc := range tests {
+		if got := strings.Join(tc.got, " "); got != tc.want {
+			t.Fatalf("%s:\n got %s\nwant %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseRemuxMode(t *testing.T) {
+	t.Parallel()
+
+	if m, err := ParseRemuxMode("Replace"); err != nil || m != RemuxReplace {
+		t.Fatalf("ParseRemuxMode(Replace) = %q, %v", m, err)
+	}
+	if _, err := ParseRemuxMode("merge"); err == nil {
+		t.Fatal("ParseRemuxMode(merge) expected error")
+	}
+}
@@ -0,0 +1,132 @@
+package container
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Selector is a parsed --track expression: comma-separated terms that must all match.
+//
+//	index=3           stream index 3
+//	lang=spa          language tag (alternatives with |: lang=por|spa)
+//	title=signs       case-insensitive substring of the track title
+//	codec=ass         ffprobe codec name
+//	sdh, forced, default, text
+//	!term             negates any term (eg: lang=eng,!sdh,!forced)
+type Selector struct {
+	expr  string
+	terms []selectorTerm
+	sdh   bool // a term tests Track.SDH, which reads the HI scores
+}
+
+type selectorTerm struct {
+	negate bool
+	match  func(Track) bool
+}
+
+// ParseSelector parses a --track expression; an empty expression matches every track.
+func ParseSelector(expr string) (Selector, error) {
+	sel := Selector{expr: strings.TrimSpace(expr)}
+	if sel.expr == "" {
+		return sel, nil
+	}
+	for raw := range strings.SplitSeq(sel.expr, ",") {
+		term := strings.TrimSpace(raw)
+		negate := false
+		if rest, ok := strings.CutPrefix(term, "!"); ok {
+			negate, term = true, strings.TrimSpace(rest)
+		}
+		match, err := parseSelectorTerm(term)
+		if err != nil {
+			return Selector{}, fmt.Errorf("track selector %q: %w", expr, err)
+		}
+		switch strings.ToLower(term) {
+		case "sdh", "hi":
+			sel.sdh = true
+		}
+		sel.terms = append(sel.terms, selectorTerm{negate: negate, match: match})
+	}
+	return sel, nil
+}
+
+func parseSelectorTerm(term string) (func(Track) bool, error) {
+	key, value, hasValue := strings.Cut(term, "=")
+	key = strings.ToLower(strings.TrimSpace(key))
+	value = strings.TrimSpace(value)
+	if hasValue && value == "" {
+		return nil, fmt.Errorf("empty value for %q", key)
+	}
+
+	switch {
+	case key == "":
+		return nil, fmt.Errorf("empty term")
+	case !hasValue:
+		switch key {
+		case "sdh", "hi":
+			return Track.SDH, nil
+		case "forced":
+			return Track.IsForced, nil
+		case "default":
+			return func(t Track) bool { return t.Default }, nil
+		case "text":
+			return Track.IsText, nil
+		}
+		return nil, fmt.Errorf("unknown flag %q (sdh, forced, default, text)", key)
+	case key == "index" || key == "track":
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("index %q is not a number", value)
+		}
+		return func(t Track) bool { return t.Index == n }, nil
+	case key == "lang" || key == "language":
+		langs := strings.Split(strings.ToLower(value), "|")
+		return func(t Track) bool {
+			for _, l := range langs {
+				if t.Language == strings.TrimSpace(l) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	case key == "title":
+		needle := strings.ToLower(value)
+		return func(t Track) bool { return strings.Contains(strings.ToLower(t.Title), needle) }, nil
+	case key == "codec":
+		codec := strings.ToLower(value)
+		return func(t Track) bool { return strings.ToLower(t.Codec) == codec }, nil
+	}
+	return nil, fmt.Errorf("unknown key %q (index, lang, title, codec)", key)
+}
+
+// Match reports whether t satisfies every term.
+func (s Selector) Match(t Track) bool {
+	for _, term := range s.terms {
+		if term.match(t) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// NeedsAnalysis reports whether picking among tracks with s depends on their HI scores
+// (AnalyzeTracks): a term tests sdh, or several text tracks match and PreferredOrder
+// ranks the SDH ones last.
+func (s Selector) NeedsAnalysis(tracks []Track) bool {
+	if s.sdh {
+		return true
+	}
+	text := 0
+	for _, t := range tracks {
+		if t.IsText() && s.Match(t) {
+			text++
+		}
+	}
+	return text > 1
+}
+
+// IsZero reports whether the selector has no terms.
+func (s Selector) IsZero() bool { return len(s.terms) == 0 }
+
+// String returns the original expression.
+func (s Selector) String() string { return s.expr }
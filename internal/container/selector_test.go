@@ -0,0 +1,139 @@
+package container
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseSelector(t *testing.T) {
+	t.Parallel()
+
+	full := Track{Index: 2, Codec: "ass", Language: "eng", Title: "Full", Default: true, ext: ".ass"}
+	signs := Track{Index: 3, Codec: "ass", Language: "eng", Title: "Signs & Songs", Forced: true, ext: ".ass"}
+	spaSDH := Track{Index: 4, Codec: "subrip", Language: "spa", Title: "SDH", ext: ".srt"}
+	spa := Track{Index: 5, Codec: "subrip", Language: "spa", ext: ".srt"}
+	pgs := Track{Index: 6, Codec: "hdmv_pgs_subtitle", Language: "por", HearingImpaired: true, ext: ".sup"}
+	tracks := []Track{full, signs, spaSDH, spa, pgs}
+
+	tests := []struct {
+		expr string
+		want []int // matching stream indexes
+	}{
+		{expr: "", want: []int{2, 3, 4, 5, 6}},
+		{expr: "index=3", want: []int{3}},
+		{expr: "lang=spa,!sdh", want: []int{5}},
+		{expr: "lang=SPA|por", want: []int{4, 5, 6}},
+		{expr: "title=signs", want: []int{3}},
+		{expr: "lang=eng, !forced", want: []int{2}},
+		{expr: "sdh", want: []int{4, 6}},
+		{expr: "text,!default", want: []int{3, 4, 5}},
+		{expr: "codec=subrip", want: []int{4, 5}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.expr, func(t *testing.T) {
+			t.Parallel()
+			sel, err := ParseSelector(tc.expr)
+			if err != nil {
+				t.Fatalf("ParseSelector(%q) error: %v", tc.expr, err)
+			}
+			var got []int
+			for _, tr := range tracks {
+				if sel.Match(tr) {
+					got = append(got, tr.Index)
+				}
+			}
+			if len(got) != len(tc.want) {
+				t.Fatalf("matches = %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Fatalf("matches = %v, want %v", got, tc.want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseSelector_errors(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"index=x", "lang=", "bogus", "color=red", "lang=eng,,sdh"} {
+		if _, err := ParseSelector(expr); err == nil {
+			t.Fatalf("ParseSelector(%q) expected error", expr)
+		}
+	}
+}
+
+func TestSelectTrack_prefersAmongMatches(t *testing.T) {
+	t.Parallel()
+
+	tracks := []Track{
+		{Index: 2, Language: "spa", Title: "SDH"},
+		{Index: 3, Language: "eng", Title: "Signs", Forced: true},
+		{Index: 4, Language: "spa"},
+		{Index: 5, Language: "eng", Title: "Full"},
+	}
+	got, err := SelectTrack(tracks, Selector{})
+	if err != nil || got.Index != 5 {
+		t.Fatalf("SelectTrack(all) = %d, %v; want 5", got.Index, err)
+	}
+	sel, _ := ParseSelector("lang=spa")
+	if got, _ := SelectTrack(tracks, sel); got.Index != 4 {
+		t.Fatalf("SelectTrack(lang=spa) = %d, want 4", got.Index)
+	}
+	sel, _ = ParseSelector("lang=jpn")
+	if _, err := SelectTrack(tracks, sel); err == nil {
+		t.Fatal("SelectTrack(lang=jpn) expected error")
+	}
+
+	// text tracks win over image tracks that would need OCR
+	tracks = []Track{
+		{Index: 2, Language: "eng", Codec: "hdmv_pgs_subtitle", ext: ".sup"},
+		{Index: 3, Language: "eng", HearingImpaired: true, ext: ".srt"},
+	}
+	if got, _ := SelectTrack(tracks, Selector{}); got.Index != 3 {
+		t.Fatalf("SelectTrack(pgs, srt) = %d, want 3", got.Index)
+	}
+}
+
+func TestSelector_NeedsAnalysis(t *testing.T) {
+	t.Parallel()
+
+	tracks := []Track{
+		{Index: 2, Language: "eng", ext: ".srt"},
+		{Index: 3, Language: "eng", ext: ".ass"},
+		{Index: 4, Language: "spa", ext: ".srt"},
+		{Index: 5, Language: "spa", Codec: "hdmv_pgs_subtitle", ext: ".sup"},
+	}
+	for expr, want := range map[string]bool{
+		"":            true, // two text tracks to order
+		"lang=eng":    true,
+		"lang=spa":    false, // one text track: the image track comes after it anyway
+		"index=3":     false,
+		"!sdh":        true,
+		"index=2,!hi": true,
+	} {
+		sel, _ := ParseSelector(expr)
+		if got := sel.NeedsAnalysis(tracks); got != want {
+			t.Errorf("NeedsAnalysis(%q) = %t, want %t", expr, got, want)
+		}
+	}
+}
+
+func TestSubtitleTrack_track(t *testing.T) {
+	t.Parallel()
+
+	raw := `{"index": 3, "codec_name": "ass", "disposition": {"default": 0, "forced": 1, "hearing_impaired": 0},
+		"tags": {"language": "ENG", "title": "Signs", "NUMBER_OF_FRAMES-eng": "214"}}`
+	var s subtitleTrack
+	if err := json.Unmarshal([]byte(raw), &s); err != nil {
+		t.Fatal(err)
+	}
+	got := s.track()
+	if got.Index != 3 || got.Language != "eng" || !got.Forced || got.Default || got.Cues != 214 || got.Extension() != ".ass" {
+		t.Fatalf("track() = %+v", got)
+	}
+	if (subtitleTrack{}).track().Cues != -1 {
+		t.Fatal("missing NUMBER_OF_FRAMES should give Cues -1")
+	}
+}
@@ -0,0 +1,248 @@
+package container
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+// Track describes one subtitle stream of a container, as listed by the track picker.
+type Track struct {
+	Index           int    // stream index (ffmpeg -map 0:N)
+	Codec           string // ffprobe codec name (subrip, ass, hdmv_pgs_subtitle, ...)
+	Language        string // ISO 639-2 tag, lower-case; "" when untagged
+	Title           string
+	Default         bool
+	Forced          bool
+	HearingImpaired bool
+	Cues            int       // NUMBER_OF_FRAMES statistics tag; -1 when unknown
+	HI              *hi.Score // content analysis (AnalyzeTracks); nil when not analyzed
+	ext             string
+}
+
+// Extension is the file extension the track is extracted with ("" when unsupported).
+func (t Track) Extension() string { return t.ext }
+
+// IsText reports whether the track can be sanitized directly (SRT/ASS; WebVTT and
+// mov_text are extracted as SRT).
+func (t Track) IsText() bool {
+	switch t.ext {
+	case ".srt", ".ass":
+		return true
+	}
+	return false
+}
+
+// SDH reports whether the track is flagged or titled as hearing impaired.
+func (t Track) SDH() bool {
+	return t.HearingImpaired || titleContainsSDH(t.Title) || (t.HI != nil && t.HI.IsSDH())
+}
+
+// IsForced reports whether the track is flagged or titled as forced.
+func (t Track) IsForced() bool { return t.Forced || titleContainsForced(t.Title) }
+
+func (s subtitleTrack) track() Track {
+	cues := -1
+	for _, v := range []string{s.Tags.NumberOfFrames, s.Tags.NumberOfFramesEng} {
+		if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+			cues = n
+			break
+		}
+	}
+	return Track{
+		Index:           s.Index,
+		Codec:           s.Codec,
+		Language:        trackLanguage(s),
+		Title:           s.Tags.Title,
+		Default:         s.Disposition.Default != 0,
+		Forced:          s.Disposition.Forced != 0,
+		HearingImpaired: s.Disposition.HearingImpaired != 0,
+		Cues:            cues,
+		ext:             subtitleExtension(s),
+	}
+}
+
+// ProbeTracks lists the subtitle streams of inputPath in stream order: with the native
+// demuxer for Matroska/WebM, with ffprobe for other containers or when the native
+// demuxer cannot read the file.
+func ProbeTracks(inputPath string) ([]Track, error) {
+	if !IsContainer(inputPath) {
+		return nil, fmt.Errorf("unsupported extension: %s (%s)", filepath.Ext(inputPath), strings.Join(Extensions, ", "))
+	}
+	var tracks []Track
+	err := fmt.Errorf("%s needs ffprobe (not found in PATH)", filepath.Ext(inputPath))
+	if isMatroska(inputPath) {
+		tracks, err = probeNative(inputPath)
+	}
+	if err != nil {
+		if _, lookErr := execLookPath("ffprobe"); lookErr != nil {
+			return nil, err
+		}
+		streams, err := probeSubtitleTracks(inputPath)
+		if err != nil {
+			return nil, err
+		}
+		tracks = make([]Track, len(streams))
+		for i, s := range streams {
+			tracks[i] = s.track()
+		}
+	}
+	if len(tracks) == 0 {
+		return nil, fmt.Errorf("no subtitle tracks found in %s", filepath.Base(inputPath))
+	}
+	return tracks, nil
+}
+
+// AnalyzeTracks scores the text tracks of a Matroska/WebM file for hearing-impaired
+// content (Track.HI), so SDH tracks without a flag or title are recognized too. The
+// tracks are demuxed together, in one pass over the file. Other containers would need
+// one ffmpeg run per track and are left as probed; so are tracks that cannot be read or
+// parsed. Selector.NeedsAnalysis tells when picking a track needs the scores.
+func AnalyzeTracks(inputPath string, tracks []Track) {
+	if !isMatroska(inputPath) {
+		return
+	}
+	var indexes []int
+	for _, t := range tracks {
+		if t.IsText() && subtitle.FormatFromPath(t.ext) != model.SubtitleFormatUnknown {
+			indexes = append(indexes, t.Index)
+		}
+	}
+	if len(indexes) == 0 {
+		return
+	}
+	contents, err := readNativeTracks(inputPath, indexes)
+	if err != nil {
+		return
+	}
+	for i, t := range tracks {
+		data, ok := contents[t.Index]
+		if !ok {
+			continue
+		}
+		doc, err := subtitle.Parse(data, subtitle.FormatFromPath(t.ext))
+		if err != nil {
+			continue
+		}
+		score := hi.Analyze(*doc)
+		tracks[i].HI = &score
+	}
+}
+
+// PreferredOrder sorts tracks as ExtractSingleSubtitle picks them: English first, then
+// text over image tracks, tracks that are neither SDH nor forced, then stream index.
+func PreferredOrder(tracks []Track) {
+	sort.SliceStable(tracks, func(i, j int) bool { return trackOrders(tracks[i], tracks[j]) })
+}
+
+func trackOrders(a, b Track) bool {
+	if engA, engB := a.Language == "eng", b.Language == "eng"; engA != engB {
+		return engA
+	}
+	if a.IsText() != b.IsText() { // image tracks need OCR
+		return a.IsText()
+	}
+	if a.SDH() != b.SDH() {
+		return !a.SDH()
+	}
+	if a.IsForced() != b.IsForced() {
+		return !a.IsForced()
+	}
+	return a.Index < b.Index
+}
+
+// SelectTrack returns the preferred track among those matching sel (an empty selector
+// matches every track).
+func SelectTrack(tracks []Track, sel Selector) (Track, error) {
+	var matches []Track
+	for _, t := range tracks {
+		if sel.Match(t) {
+			matches = append(matches, t)
+		}
+	}
+	if len(matches) == 0 {
+		return Track{}, fmt.Errorf("no subtitle track matches %q", sel.String())
+	}
+	PreferredOrder(matches)
+	return matches[0], nil
+}
+
+// ExtractTrack writes track next to the video (file.srt, file.ass, ...) and returns the
+// path and content.
+func ExtractTrack(inputPath string, track Track) (string, []byte, error) {
+	data, err := readTrack(inputPath, track)
+	if err != nil {
+		return "", nil, err
+	}
+	out := extractedPath(inputPath, track)
+	if err := os.WriteFile(out, data, 0644); err != nil {
+		return "", nil, fmt.Errorf("write extracted subtitle: %w", err)
+	}
+	return out, data, nil
+}
+
+// ReadTrack returns the content of track and the path ExtractTrack would have written.
+// Nothing is left next to the video.
+func ReadTrack(inputPath string, track Track) (string, []byte, error) {
+	data, err := readTrack(inputPath, track)
+	if err != nil {
+		return "", nil, err
+	}
+	return extractedPath(inputPath, track), data, nil
+}
+
+// extractedPath names the extracted track after the video (movie.mp4 -> movie.srt); the
+// stream index is added when that would overwrite the input (movie.mks -> movie.3.mks).
+func extractedPath(inputPath string, track Track) string {
+	out := trimExt(inputPath) + track.ext
+	if strings.EqualFold(out, inputPath) {
+		out = fmt.Sprintf("%s.%d%s", trimExt(inputPath), track.Index, track.ext)
+	}
+	return out
+}
+
+// readTrack demuxes Matroska tracks natively, and uses ffmpeg (through a temporary
+// file) for other containers, codecs the demuxer cannot rebuild or files it cannot read.
+func readTrack(inputPath string, track Track) ([]byte, error) {
+	if track.ext == "" {
+		return nil, fmt.Errorf("subtitle codec %q of track %d is not supported", track.Codec, track.Index)
+	}
+	var data []byte
+	var err error
+	if isMatroska(inputPath) {
+		var ok bool
+		data, ok, err = readNative(inputPath, track.Index)
+		if ok && err == nil {
+			return data, nil
+		}
+	}
+	if _, lookErr := execLookPath("ffmpeg"); lookErr != nil {
+		if err == nil {
+			err = fmt.Errorf("subtitle codec %q of track %d needs ffmpeg (not found in PATH)", track.Codec, track.Index)
+		}
+		return nil, err
+	}
+
+	tmp, err := os.MkdirTemp("", "subtitle-sanitizer-")
+	if err != nil {
+		return nil, fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	tmpOut := filepath.Join(tmp, "track"+track.ext)
+	if err := runFFmpegExtractTrack(inputPath, track, tmpOut); err != nil {
+		return nil, err
+	}
+	data, err = os.ReadFile(tmpOut)
+	if err != nil {
+		return nil, fmt.Errorf("read extracted subtitle: %w", err)
+	}
+	return data, nil
+}
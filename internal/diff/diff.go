@@ -0,0 +1,135 @@
+// Package diff computes minimal edit scripts (Myers' algorithm) between line or rune
+// sequences and renders line edits as unified diffs.
+package diff
+
+import "strings"
+
+// Kind of an edit.
+type Kind int
+
+const (
+	Equal Kind = iota
+	Delete
+	Insert
+)
+
+// Edit is one step of an edit script. A and B are 0-based positions in the old and new
+// sequence; for Insert, A is where the element goes in the old sequence, and for Delete,
+// B is where it would have been in the new one.
+type Edit struct {
+	Kind Kind
+	A, B int
+}
+
+// Script returns a shortest edit script turning a into b, in time O((N+M)D) and space
+// linear in N+M (Myers' divide-and-conquer search for the middle snake).
+func Script[T comparable](a, b []T) []Edit {
+	edits := make([]Edit, 0, max(len(a), len(b)))
+	return script(edits, a, b, 0, 0)
+}
+
+// script appends the edits turning a into b, which start at aOff and bOff in the whole
+// sequences.
+func script[T comparable](edits []Edit, a, b []T, aOff, bOff int) []Edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		edits = append(edits, Edit{Kind: Equal, A: aOff + prefix, B: bOff + prefix})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	aOff, bOff = aOff+prefix, bOff+prefix
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for j := range b {
+			edits = append(edits, Edit{Kind: Insert, A: aOff, B: bOff + j})
+		}
+	case len(b) == 0:
+		for i := range a {
+			edits = append(edits, Edit{Kind: Delete, A: aOff + i, B: bOff})
+		}
+	default:
+		// Without a common prefix or suffix the distance is at least 2, so both halves
+		// around the middle snake are smaller problems.
+		x, y, u, v := middleSnake(a, b)
+		edits = script(edits, a[:x], b[:y], aOff, bOff)
+		for i := range u - x {
+			edits = append(edits, Edit{Kind: Equal, A: aOff + x + i, B: bOff + y + i})
+		}
+		edits = script(edits, a[u:], b[v:], aOff+u, bOff+v)
+	}
+	for i := range suffix {
+		edits = append(edits, Edit{Kind: Equal, A: aOff + len(a) + i, B: bOff + len(b) + i})
+	}
+	return edits
+}
+
+// middleSnake runs the greedy search from both ends of a and b at once until the paths
+// overlap, and returns the snake (diagonal run of equal elements) from x,y to u,v on
+// which they meet: it lies on a shortest edit script.
+func middleSnake[T comparable](a, b []T) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	off := maxD + 1
+	fwd := make([]int, 2*maxD+3) // diagonal k = x-y -> furthest x from the start
+	bwd := make([]int, 2*maxD+3) // diagonal k = (n-x)-(m-y) -> furthest n-x from the end
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && fwd[off+k-1] < fwd[off+k+1]) {
+				x = fwd[off+k+1]
+			} else {
+				x = fwd[off+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			fwd[off+k] = x
+			if kb := delta - k; odd && kb >= -(d-1) && kb <= d-1 && x+bwd[off+kb] >= n {
+				return x0, y0, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && bwd[off+k-1] < bwd[off+k+1]) {
+				x = bwd[off+k+1]
+			} else {
+				x = bwd[off+k-1] + 1
+			}
+			y := x - k
+			x0, y0 := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			bwd[off+k] = x
+			if kf := delta - k; !odd && kf >= -d && kf <= d && x+fwd[off+kf] >= n {
+				return n - x, m - y, n - x0, m - y0
+			}
+		}
+	}
+	panic("diff: no middle snake") // unreachable: the searches meet by d = (n+m+1)/2
+}
+
+// SplitLines splits text into lines for diffing: a leading BOM is dropped, CRLF is read
+// as LF and a final newline does not produce an empty last line.
+func SplitLines(s string) []string {
+	s = strings.TrimPrefix(s, "\ufeff")
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,218 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand/v2"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// apply rebuilds b from a and the script, checking positions along the way.
+func apply(t *testing.T, a, b []rune, edits []Edit) string {
+	t.Helper()
+	var out []rune
+	ai, bi := 0, 0
+	for _, e := range edits {
+		if e.A != ai || e.B != bi {
+			t.Fatalf("edit %+v out of position (a=%d b=%d)", e, ai, bi)
+		}
+		switch e.Kind {
+		case Equal:
+			if a[e.A] != b[e.B] {
+				t.Fatalf("equal edit on different elements: %+v", e)
+			}
+			out = append(out, a[e.A])
+			ai++
+			bi++
+		case Delete:
+			ai++
+		case Insert:
+			out = append(out, b[e.B])
+			bi++
+		}
+	}
+	if ai != len(a) || bi != len(b) {
+		t.Fatalf("script stops at a=%d b=%d", ai, bi)
+	}
+	return string(out)
+}
+
+func TestScript(t *testing.T) {
+	tests := []struct {
+		a, b    string
+		changes int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"ABCABBA", "CBABAC", 5},
+		{"[DOOR] Hello", "Hello", 7},
+		{"JOHN: Olá mundo", "Olá mundo!", 7},
+	}
+	for _, tt := range tests {
+		a, b := []rune(tt.a), []rune(tt.b)
+		edits := Script(a, b)
+		if got := apply(t, a, b, edits); got != tt.b {
+			t.Fatalf("Script(%q, %q) rebuilds %q", tt.a, tt.b, got)
+		}
+		changes := 0
+		for _, e := range edits {
+			if e.Kind != Equal {
+				changes++
+			}
+		}
+		if changes != tt.changes {
+			t.Fatalf("Script(%q, %q): %d changes, want %d", tt.a, tt.b, changes, tt.changes)
+		}
+	}
+}
+
+// distance is the edit distance (inserts and deletes) by dynamic programming.
+func distance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1]
+			} else {
+				cur[j] = 1 + min(prev[j], cur[j-1])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func TestScript_shortest(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	word := func() []rune {
+		r := make([]rune, rng.IntN(30))
+		for i := range r {
+			r[i] = rune('a' + rng.IntN(4))
+		}
+		return r
+	}
+	for range 2000 {
+		a, b := word(), word()
+		edits := Script(a, b)
+		if got := apply(t, a, b, edits); got != string(b) {
+			t.Fatalf("Script(%q, %q) rebuilds %q", string(a), string(b), got)
+		}
+		changes := 0
+		for _, e := range edits {
+			if e.Kind != Equal {
+				changes++
+			}
+		}
+		if want := distance(a, b); changes != want {
+			t.Fatalf("Script(%q, %q): %d changes, want %d", string(a), string(b), changes, want)
+		}
+	}
+}
+
+// TestScript_linearSpace diffs two files with nothing in common (eg: ASS against the
+// SRT made from it), whose distance is the sum of their lengths.
+func TestScript_linearSpace(t *testing.T) {
+	a, b := make([]string, 5000), make([]string, 5000)
+	for i := range a {
+		a[i], b[i] = fmt.Sprintf("Dialogue: %d", i), fmt.Sprintf("%d", i)
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	edits := Script(a, b)
+	runtime.ReadMemStats(&after)
+	if len(edits) != len(a)+len(b) {
+		t.Fatalf("%d edits, want %d", len(edits), len(a)+len(b))
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 16<<20 {
+		t.Fatalf("allocated %d MiB", alloc>>20)
+	}
+}
+
+func TestUnified(t *testing.T) {
+	a := SplitLines("\ufeff1\r\n00:00:01,000 --> 00:00:02,000\r\n[DOOR SLAMS]\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nJOHN: Hello\r\n\r\n3\r\n00:00:05,000 --> 00:00:06,000\r\nBye\r\n")
+	b := SplitLines("1\n00:00:03,000 --> 00:00:04,000\nHello\n\n2\n00:00:05,000 --> 00:00:06,000\nBye\n")
+
+	var buf bytes.Buffer
+	changed, err := Unified(&buf, "a.srt", "a-his.srt", a, b, UnifiedOptions{Context: 1})
+	if err != nil || !changed {
+		t.Fatalf("changed=%v err=%v", changed, err)
+	}
+	want := strings.Join([]string{
+		"--- a.srt",
+		"+++ a-his.srt",
+		"@@ -1,10 +1,6 @@",
+		" 1",
+		"-00:00:01,000 --> 00:00:02,000",
+		"-[DOOR SLAMS]",
+		"-",
+		"-2",
+		" 00:00:03,000 --> 00:00:04,000",
+		"-JOHN: Hello",
+		"+Hello",
+		" ",
+		"-3",
+		"+2",
+		" 00:00:05,000 --> 00:00:06,000",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Fatalf("unified diff:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestUnified_separateHunks(t *testing.T) {
+	var a []string
+	for i := range 20 {
+		a = append(a, string(rune('a'+i)))
+	}
+	b := append([]string{"NEW"}, a...)
+	b[len(b)-1] = "LAST"
+
+	var buf bytes.Buffer
+	if _, err := Unified(&buf, "a", "b", a, b, UnifiedOptions{Context: -1}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "@@ -1,3 +1,4 @@") {
+		t.Fatalf("first hunk header missing:\n%s", out)
+	}
+	if strings.Count(out, "@@ -") != 2 {
+		t.Fatalf("expected two hunks:\n%s", out)
+	}
+	if !strings.Contains(out, "@@ -17,4 +18,4 @@") {
+		t.Fatalf("last hunk header wrong:\n%s", out)
+	}
+}
+
+func TestUnified_emptyRange(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := Unified(&buf, "a", "b", nil, []string{"x"}, UnifiedOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "@@ -0,0 +1 @@") {
+		t.Fatalf("header:\n%s", buf.String())
+	}
+}
+
+func TestUnified_equalAndColor(t *testing.T) {
+	var buf bytes.Buffer
+	changed, err := Unified(&buf, "a", "b", []string{"x"}, []string{"x"}, UnifiedOptions{})
+	if err != nil || changed || buf.Len() != 0 {
+		t.Fatalf("equal inputs: changed=%v err=%v out=%q", changed, err, buf.String())
+	}
+	if _, err := Unified(&buf, "a", "b", []string{"x"}, []string{"y"}, UnifiedOptions{Color: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), colorRed+"-x"+colorReset) || !strings.Contains(buf.String(), colorGreen+"+y"+colorReset) {
+		t.Fatalf("colors missing: %q", buf.String())
+	}
+}
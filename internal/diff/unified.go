@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change (as diff -u).
+const DefaultContext = 3
+
+// ANSI colors used when UnifiedOptions.Color is set (same scheme as git diff).
+const (
+	colorBold  = "\x1b[1m"
+	colorCyan  = "\x1b[36m"
+	colorRed   = "\x1b[31m"
+	colorGreen = "\x1b[32m"
+	colorReset = "\x1b[0m"
+)
+
+// UnifiedOptions controls Unified rendering.
+type UnifiedOptions struct {
+	Context int  // lines of context; negative means DefaultContext
+	Color   bool // wrap headers and changed lines in ANSI colors
+}
+
+// Unified writes the unified diff of a and b with aName/bName in the file headers.
+// Nothing is written when the inputs are equal; the return reports whether they differ.
+func Unified(w io.Writer, aName, bName string, a, b []string, opts UnifiedOptions) (bool, error) {
+	edits := Script(a, b)
+	hunks := hunks(edits, opts.Context)
+	if len(hunks) == 0 {
+		return false, nil
+	}
+	paint := func(color, s string) string {
+		if !opts.Color {
+			return s
+		}
+		return color + s + colorReset
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, paint(colorBold, "--- "+aName))
+	fmt.Fprintln(bw, paint(colorBold, "+++ "+bName))
+	for _, h := range hunks {
+		part := edits[h[0]:h[1]]
+		aStart, aLen, bStart, bLen := hunkRange(part)
+		fmt.Fprintln(bw, paint(colorCyan, fmt.Sprintf("@@ -%s +%s @@", formatRange(aStart, aLen), formatRange(bStart, bLen))))
+		for _, e := range part {
+			switch e.Kind {
+			case Equal:
+				fmt.Fprintln(bw, " "+a[e.A])
+			case Delete:
+				fmt.Fprintln(bw, paint(colorRed, "-"+a[e.A]))
+			case Insert:
+				fmt.Fprintln(bw, paint(colorGreen, "+"+b[e.B]))
+			}
+		}
+	}
+	return true, bw.Flush()
+}
+
+// hunks returns [start, end) edit ranges covering every change plus context lines;
+// changes separated by at most 2*context equal lines share a hunk.
+func hunks(edits []Edit, context int) [][2]int {
+	if context < 0 {
+		context = DefaultContext
+	}
+	var out [][2]int
+	for i, e := range edits {
+		if e.Kind == Equal {
+			continue
+		}
+		start := max(0, i-context)
+		end := min(len(edits), i+1+context)
+		if n := len(out); n > 0 && start <= out[n-1][1] {
+			out[n-1][1] = end
+			continue
+		}
+		out = append(out, [2]int{start, end})
+	}
+	return out
+}
+
+// hunkRange returns 0-based starts and lengths of a hunk in the old and new file.
+func hunkRange(part []Edit) (aStart, aLen, bStart, bLen int) {
+	aStart, bStart = part[0].A, part[0].B
+	for _, e := range part {
+		if e.Kind != Insert {
+			aLen++
+		}
+		if e.Kind != Delete {
+			bLen++
+		}
+	}
+	return aStart, aLen, bStart, bLen
+}
+
+// formatRange prints a 0-based start as diff -u does: 1-based, and an empty range names
+// the line before it.
+func formatRange(start, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
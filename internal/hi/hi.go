@@ -0,0 +1,129 @@
+// Package hi scores subtitle documents for hearing-impaired (SDH) content: bracketed
+// sound descriptions, speaker labels, music notes and all-caps sound effects.
+package hi
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+)
+
+// Level is the verdict of Analyze.
+type Level string
+
+const (
+	LevelClean Level = "clean" // no markers: nothing to sanitize
+	LevelLight Level = "light" // a few markers (songs, the odd sound), typical of regular tracks
+	LevelSDH   Level = "sdh"   // full SDH/HI track
+)
+
+// SDHRatio is the share of marked cues from which a document is LevelSDH.
+const SDHRatio = 0.1
+
+var (
+	reBrackets  = regexp.MustCompile(`\[[^\]]*\]|\([^)]*\)`)
+	reSpeaker   = regexp.MustCompile(`(?m)^\s*-?\s*[A-Z][A-Z0-9 .'-]*:`)
+	reMusicNote = regexp.MustCompile(`[♪♫]`)
+	reTags      = regexp.MustCompile(`<[^>]*>|{\\[^}]*}`)
+)
+
+// Score counts the cues carrying each kind of marker (a cue may count in several).
+type Score struct {
+	Cues         int     `json:"cues"`
+	Marked       int     `json:"marked"` // cues with any marker
+	Bracketed    int     `json:"bracketed"`
+	Speakers     int     `json:"speakers"`
+	Music        int     `json:"music"`
+	SoundEffects int     `json:"soundEffects"`
+	Value        float64 `json:"score"` // Marked / Cues
+	Level        Level   `json:"level"`
+}
+
+// Analyze scores doc.
+func Analyze(doc model.Document) Score {
+	var s Score
+	for _, cue := range doc.Cues {
+		text := strings.ReplaceAll(reTags.ReplaceAllString(cue.Lines, ""), `\N`, "\n")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		s.Cues++
+		marked := false
+		count := func(hit bool, n *int) {
+			if hit {
+				*n++
+				marked = true
+			}
+		}
+		count(reBrackets.MatchString(text), &s.Bracketed)
+		count(reSpeaker.MatchString(text), &s.Speakers)
+		count(reMusicNote.MatchString(text), &s.Music)
+		count(hasSoundEffect(text), &s.SoundEffects)
+		if marked {
+			s.Marked++
+		}
+	}
+	s.Level = LevelClean
+	if s.Cues > 0 {
+		s.Value = float64(s.Marked) / float64(s.Cues)
+	}
+	switch {
+	case s.Value >= SDHRatio:
+		s.Level = LevelSDH
+	case s.Marked > 0:
+		s.Level = LevelLight
+	}
+	return s
+}
+
+// NeedsSanitizing reports whether any marker was found.
+func (s Score) NeedsSanitizing() bool { return s.Level != LevelClean }
+
+// IsSDH reports whether the document reads as a full SDH track.
+func (s Score) IsSDH() bool { return s.Level == LevelSDH }
+
+// String is a one-line summary: "sdh 34% (120/350 cues: 80 bracketed, 30 speakers, 12 music, 5 effects)".
+func (s Score) String() string {
+	if s.Marked == 0 {
+		return fmt.Sprintf("%s (no HI markers in %d cues)", s.Level, s.Cues)
+	}
+	return fmt.Sprintf("%s %.0f%% (%d/%d cues: %d bracketed, %d speakers, %d music, %d effects)",
+		s.Level, s.Value*100, s.Marked, s.Cues, s.Bracketed, s.Speakers, s.Music, s.SoundEffects)
+}
+
+// Marker returns the first bracketed text, speaker label or music note in text ("" if none).
+func Marker(text string) string {
+	for _, re := range []*regexp.Regexp{reBrackets, reSpeaker, reMusicNote} {
+		if m := re.FindString(text); m != "" {
+			return strings.TrimSpace(m)
+		}
+	}
+	return ""
+}
+
+// hasSoundEffect reports an all-caps line of at least two words (DOOR SLAMS), leaving
+// out questions and exclamations, which are more often shouted dialogue.
+func hasSoundEffect(text string) bool {
+	for line := range strings.SplitSeq(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasSuffix(line, "?") || strings.HasSuffix(line, "!") {
+			continue
+		}
+		words, caps := 0, true
+		for w := range strings.FieldsFuncSeq(line, func(r rune) bool { return !unicode.IsLetter(r) }) {
+			for _, r := range w {
+				caps = caps && unicode.IsUpper(r)
+			}
+			if len([]rune(w)) > 2 {
+				words++
+			}
+		}
+		if caps && words >= 2 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package hi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+)
+
+func doc(lines ...string) model.Document {
+	d := model.Document{Format: model.SubtitleFormatSRT}
+	for i, l := range lines {
+		d.Cues = append(d.Cues, &model.Cue{Index: i + 1, Lines: l})
+	}
+	return d
+}
+
+func TestAnalyze(t *testing.T) {
+	t.Parallel()
+
+	plain := strings.Split("Hello.|How are you?|Fine, thanks.|Let's go.|Where?|Now!|OK.|Wait for me.|Sure.|Come on.|Bye.", "|")
+	tests := []struct {
+		name  string
+		doc   model.Document
+		want  Score
+		level Level
+	}{
+		{name: "clean", doc: doc(plain...), want: Score{Cues: 11}, level: LevelClean},
+		{name: "light", doc: doc(append([]string{"♪ Happy birthday ♪"}, plain...)...),
+			want: Score{Cues: 12, Marked: 1, Music: 1}, level: LevelLight},
+		{name: "sdh", doc: doc("[door slams]", "<i>JOHN:</i> Hi.", "DOOR CREAKS OPEN", "(sighs) Fine.", "Hello.", "-MARY: Bye.\n-Bye."),
+			want: Score{Cues: 6, Marked: 5, Bracketed: 2, Speakers: 2, SoundEffects: 1}, level: LevelSDH},
+		{name: "shouting is not an effect", doc: doc("GET OUT OF HERE!", "WHAT ARE YOU DOING?", "OK"),
+			want: Score{Cues: 3}, level: LevelClean},
+		{name: "ass tags", doc: doc(`{\i1}[music]{\i0}`, `Hi\Nthere`),
+			want: Score{Cues: 2, Marked: 1, Bracketed: 1}, level: LevelSDH},
+		{name: "empty", doc: doc("", " "), want: Score{}, level: LevelClean},
+	}
+	for _, tc := range tests {
+		got := Analyze(tc.doc)
+		tc.want.Level = tc.level
+		if tc.want.Cues > 0 {
+			tc.want.Value = float64(tc.want.Marked) / float64(tc.want.Cues)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: Analyze = %+v, want %+v", tc.name, got, tc.want)
+		}
+		if got.NeedsSanitizing() != (tc.level != LevelClean) || got.IsSDH() != (tc.level == LevelSDH) {
+			t.Fatalf("%s: NeedsSanitizing/IsSDH mismatch for %s", tc.name, got.Level)
+		}
+	}
+}
+
+func TestScoreString(t *testing.T) {
+	t.Parallel()
+
+	s := Analyze(doc("[door slams]", "Hello.", "♪ la la ♪", "Bye."))
+	if got, want := s.String(), "sdh 50% (2/4 cues: 1 bracketed, 0 speakers, 1 music, 0 effects)"; got != want {
+		t.Fatalf("String = %q, want %q", got, want)
+	}
+	if got, want := Analyze(doc("Hello.")).String(), "clean (no HI markers in 1 cues)"; got != want {
+		t.Fatalf("String = %q, want %q", got, want)
+	}
+}
+
+func TestMarker(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{"[door slams] Hi": "[door slams]", "JOHN: Hi": "JOHN:", "♪ la la ♪": "♪", "Hi there": ""}
+	for in, want := range tests {
+		if got := Marker(in); got != want {
+			t.Fatalf("Marker(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
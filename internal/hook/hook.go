@@ -0,0 +1,223 @@
+// Package hook reads the post-processing calls of media tools (Bazarr, Sonarr, Radarr,
+// Jellyfin) into the subtitles they are about, with the language of each, and keeps the
+// backups of subtitles rewritten in place.
+package hook
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/container"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+// Tool is a program calling the hook.
+type Tool string
+
+const (
+	Bazarr   Tool = "bazarr"
+	Sonarr   Tool = "sonarr"
+	Radarr   Tool = "radarr"
+	Jellyfin Tool = "jellyfin"
+)
+
+// Events of Sonarr and Radarr custom scripts the hook knows. Other events (Grab, Rename,
+// HealthIssue...) are accepted and ignored.
+const (
+	EventTest     = "Test"     // sent when the connection is saved; must exit 0
+	EventDownload = "Download" // a file was imported (or upgraded), with its subtitles
+)
+
+// Input is what a tool hands the hook: Bazarr substitutes variables in the command line,
+// Sonarr and Radarr set environment variables, Jellyfin (and manual runs) pass paths.
+type Input struct {
+	Subtitle string              // Bazarr {{subtitles}}
+	Language string              // Bazarr {{subtitles_language_code2}} or code3 (default: from the file name)
+	Media    string              // Bazarr {{episode}}: the episode or movie file
+	Paths    []string            // Jellyfin: subtitle files, or media files whose subtitles to take
+	Getenv   func(string) string // Sonarr, Radarr (default: os.Getenv)
+}
+
+// Call is one post-processing call: the subtitles to sanitize, in order.
+type Call struct {
+	Tool      Tool
+	Event     string // Sonarr and Radarr
+	Media     string // the episode or movie file, when the tool names one
+	Subtitles []Subtitle
+}
+
+// Subtitle is a subtitle file and its language (ISO 639-1 when it has one, "" unknown).
+type Subtitle struct {
+	Path     string
+	Language string
+}
+
+// IsTest reports whether the call is a connection test, which has nothing to sanitize.
+func (c Call) IsTest() bool { return c.Event == EventTest }
+
+// Read turns the input of tool into a Call. A Sonarr or Radarr event other than Download
+// gives a Call without subtitles.
+func Read(tool Tool, in Input) (Call, error) {
+	call := Call{Tool: tool}
+	switch tool {
+	case Bazarr:
+		if in.Subtitle == "" {
+			return call, errors.New("bazarr: no subtitle path (pass {{subtitles}})")
+		}
+		call.Media = in.Media
+		lang := normalizeLanguage(in.Language)
+		if lang == "" {
+			lang = Language(in.Subtitle, in.Media)
+		}
+		call.Subtitles = []Subtitle{{Path: in.Subtitle, Language: lang}}
+		return call, nil
+
+	case Sonarr, Radarr:
+		getenv := in.Getenv
+		if getenv == nil {
+			getenv = os.Getenv
+		}
+		prefix := string(tool) + "_"
+		call.Event = getenv(prefix + "eventtype")
+		if call.Event == "" {
+			return call, fmt.Errorf("%s: %seventtype is not set (run the hook from a %s custom script connection)", tool, prefix, tool)
+		}
+		if call.Event != EventDownload {
+			return call, nil
+		}
+		key := prefix + "episodefile_path"
+		if tool == Radarr {
+			key = prefix + "moviefile_path"
+		}
+		if call.Media = getenv(key); call.Media == "" {
+			return call, fmt.Errorf("%s: %s is not set", tool, key)
+		}
+		var err error
+		call.Subtitles, err = Sidecars(call.Media)
+		return call, err
+
+	case Jellyfin:
+		if len(in.Paths) == 0 {
+			return call, errors.New("jellyfin: no paths")
+		}
+		for _, path := range in.Paths {
+			if subtitle.FormatFromPath(path) != model.SubtitleFormatUnknown {
+				call.Subtitles = append(call.Subtitles, Subtitle{Path: path, Language: Language(path, "")})
+				continue
+			}
+			subs, err := Sidecars(path)
+			if err != nil {
+				return call, err
+			}
+			call.Subtitles = append(call.Subtitles, subs...)
+		}
+		return call, nil
+	}
+	return call, fmt.Errorf("unknown tool %q (bazarr, sonarr, radarr, jellyfin)", tool)
+}
+
+// Sidecars returns the text subtitles next to media that belong to it by name (eg:
+// "Show - S01E01.en.srt" for "Show - S01E01.mkv"), sorted by path.
+func Sidecars(media string) ([]Subtitle, error) {
+	dir := filepath.Dir(media)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("subtitles of %s: %w", media, err)
+	}
+	base := strings.TrimSuffix(filepath.Base(media), filepath.Ext(media))
+	var subs []Subtitle
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, base+".") || subtitle.FormatFromPath(name) == model.SubtitleFormatUnknown {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		subs = append(subs, Subtitle{Path: path, Language: Language(path, media)})
+	}
+	slices.SortFunc(subs, func(a, b Subtitle) int { return strings.Compare(a.Path, b.Path) })
+	return subs, nil
+}
+
+// nameFlags are the file name parts after the language that mark a variant of the track.
+// "hi" is read as hearing impaired (Bazarr's suffix), never as Hindi.
+var nameFlags = []string{"forced", "sdh", "hi", "cc", "default", "foreign"}
+
+// Language returns the language of a subtitle file from its name, as the media tools
+// write them: "Movie.en.srt", "Movie.pt-BR.forced.srt", "Movie.eng.hi.ass". Media is the
+// video the subtitle belongs to ("" unknown); only the name parts after its name count.
+// It returns "" when no part is a language code.
+func Language(path, media string) string {
+	name := filepath.Base(path)
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if media != "" {
+		mediaBase := filepath.Base(media)
+		mediaBase = strings.TrimSuffix(mediaBase, filepath.Ext(mediaBase))
+		name = strings.TrimPrefix(name, mediaBase)
+	}
+	parts := strings.Split(name, ".")
+	if media == "" {
+		parts = parts[1:] // the title
+	}
+	for i := len(parts) - 1; i >= 0; i-- {
+		part := strings.ToLower(parts[i])
+		if slices.Contains(nameFlags, part) {
+			continue
+		}
+		return normalizeLanguage(part)
+	}
+	return ""
+}
+
+// normalizeLanguage returns the ISO 639-1 code of an ISO 639-1/2 code or a tag such as
+// "pt-BR", or "" when code is none of those.
+func normalizeLanguage(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if i := strings.IndexAny(code, "-_"); i > 0 {
+		code = code[:i]
+	}
+	for _, r := range code {
+		if r < 'a' || r > 'z' {
+			return ""
+		}
+	}
+	switch len(code) {
+	case 2:
+		return code
+	case 3:
+		if two := container.Lang2(code); len(two) == 2 {
+			return two
+		}
+	}
+	return ""
+}
+
+// Backup writes original, the content path has before being rewritten, to path+suffix
+// and returns that path. An existing backup is kept (it holds the first original) and
+// an empty suffix keeps none; both return "".
+func Backup(path string, original []byte, suffix string) (string, error) {
+	if suffix == "" {
+		return "", nil
+	}
+	dst := path + suffix
+	f, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if errors.Is(err, fs.ErrExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("backup: %w", err)
+	}
+	if _, err := f.Write(original); err != nil {
+		f.Close()
+		return "", errors.Join(fmt.Errorf("backup: %w", err), os.Remove(dst))
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("backup: %w", err)
+	}
+	return dst, nil
+}
@@ -0,0 +1,123 @@
+package hook
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestRead replays the calls in testdata/calls.json (Bazarr command line variables,
+// Sonarr and Radarr environments, Jellyfin paths) against the media in testdata/library.
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "calls.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	library, err := filepath.Abs(filepath.Join("testdata", "library"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expand := func(s string) string {
+		if !strings.Contains(s, "$LIBRARY") {
+			return s
+		}
+		return filepath.FromSlash(strings.ReplaceAll(s, "$LIBRARY", filepath.ToSlash(library)))
+	}
+
+	var calls []struct {
+		Name  string            `json:"name"`
+		Tool  Tool              `json:"tool"`
+		Input Input             `json:"input"`
+		Env   map[string]string `json:"env"`
+		Want  struct {
+			Event     string `json:"event"`
+			Media     string `json:"media"`
+			Subtitles []struct {
+				Path     string `json:"path"`
+				Language string `json:"language"`
+			} `json:"subtitles"`
+		} `json:"want"`
+		Err string `json:"err"`
+	}
+	if err := json.Unmarshal(data, &calls); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range calls {
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			in := tc.Input
+			in.Subtitle, in.Media = expand(in.Subtitle), expand(in.Media)
+			for i, p := range in.Paths {
+				in.Paths[i] = expand(p)
+			}
+			in.Getenv = func(key string) string { return expand(tc.Env[key]) }
+
+			call, err := Read(tc.Tool, in)
+			if tc.Err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), expand(tc.Err)) {
+					t.Fatalf("err = %v, want %s", err, expand(tc.Err))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := Call{Tool: tc.Tool, Event: tc.Want.Event, Media: expand(tc.Want.Media)}
+			for _, s := range tc.Want.Subtitles {
+				want.Subtitles = append(want.Subtitles, Subtitle{Path: expand(s.Path), Language: s.Language})
+			}
+			if call.Tool != want.Tool || call.Event != want.Event || call.Media != want.Media || !slices.Equal(call.Subtitles, want.Subtitles) {
+				t.Fatalf("got %+v\nwant %+v", call, want)
+			}
+			if call.IsTest() != (tc.Want.Event == EventTest) {
+				t.Errorf("IsTest = %t", call.IsTest())
+			}
+		})
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct{ path, media, want string }{
+		{"Movie.en.srt", "", "en"},
+		{"Movie.EN.forced.srt", "", "en"},
+		{"Movie.spa.sdh.ass", "", "es"},
+		{"Movie.zh-Hant.srt", "", "zh"},
+		{"Movie.hi.srt", "", ""}, // hearing impaired, not Hindi
+		{"Movie.2020.srt", "", ""},
+		{"Movie.srt", "", ""},
+		{"Some.Show.S01E01.fr.srt", "Some.Show.S01E01.mkv", "fr"},
+		{"Some.Show.S01E01.srt", "Some.Show.S01E01.mkv", ""},
+	}
+	for _, tt := range tests {
+		if got := Language(tt.path, tt.media); got != tt.want {
+			t.Errorf("Language(%q, %q) = %q, want %q", tt.path, tt.media, got, tt.want)
+		}
+	}
+}
+
+func TestBackup(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "a.en.srt")
+	got, err := Backup(path, []byte("first"), ".bak")
+	if err != nil || got != path+".bak" {
+		t.Fatalf("Backup = %q, %v", got, err)
+	}
+	// A second run keeps the first original.
+	if got, err := Backup(path, []byte("second"), ".bak"); err != nil || got != "" {
+		t.Fatalf("second Backup = %q, %v", got, err)
+	}
+	if data, _ := os.ReadFile(path + ".bak"); string(data) != "first" {
+		t.Fatalf("backup holds %q", data)
+	}
+	if got, err := Backup(path, []byte("x"), ""); err != nil || got != "" {
+		t.Fatalf("Backup without suffix = %q, %v", got, err)
+	}
+}
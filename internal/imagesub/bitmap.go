@@ -0,0 +1,172 @@
+package imagesub
+
+import (
+	"image"
+	"image/color"
+	"strings"
+)
+
+// Bitmap is a binarized image: true pixels are text ink.
+type Bitmap struct {
+	W, H int
+	Pix  []bool
+}
+
+func (b Bitmap) at(x, y int) bool { return b.Pix[y*b.W+x] }
+
+// Binarize separates the text fill from the outline and the transparent background:
+// opaque pixels brighter than the midpoint of the opaque luma range are ink (all
+// opaque pixels when they share one brightness).
+func Binarize(img image.Image) Bitmap {
+	r := img.Bounds()
+	bm := Bitmap{W: r.Dx(), H: r.Dy(), Pix: make([]bool, r.Dx()*r.Dy())}
+	luma := make([]int, len(bm.Pix))
+	lo, hi := 256, -1
+	for y := 0; y < bm.H; y++ {
+		for x := 0; x < bm.W; x++ {
+			c := color.NRGBAModel.Convert(img.At(r.Min.X+x, r.Min.Y+y)).(color.NRGBA)
+			i := y*bm.W + x
+			luma[i] = -1
+			if c.A < 128 {
+				continue
+			}
+			l := (299*int(c.R) + 587*int(c.G) + 114*int(c.B)) / 1000
+			luma[i] = l
+			lo, hi = min(lo, l), max(hi, l)
+		}
+	}
+	mid := (lo + hi) / 2
+	for i, l := range luma {
+		bm.Pix[i] = l >= 0 && (l > mid || lo == hi)
+	}
+	return bm
+}
+
+// ToImage renders ink black on white (the input tesseract expects) with a margin.
+func (b Bitmap) ToImage(margin int) *image.Gray {
+	img := image.NewGray(image.Rect(0, 0, b.W+2*margin, b.H+2*margin))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	for y := 0; y < b.H; y++ {
+		for x := 0; x < b.W; x++ {
+			if b.at(x, y) {
+				img.SetGray(x+margin, y+margin, color.Gray{})
+			}
+		}
+	}
+	return img
+}
+
+// String draws the bitmap with # and . (learning prompts, test failures).
+func (b Bitmap) String() string {
+	var sb strings.Builder
+	for y := 0; y < b.H; y++ {
+		for x := 0; x < b.W; x++ {
+			if b.at(x, y) {
+				sb.WriteByte('#')
+			} else {
+				sb.WriteByte('.')
+			}
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// crop returns the rectangle [x0,x1)×[y0,y1) of b.
+func (b Bitmap) crop(x0, y0, x1, y1 int) Bitmap {
+	out := Bitmap{W: x1 - x0, H: y1 - y0, Pix: make([]bool, (x1-x0)*(y1-y0))}
+	for y := y0; y < y1; y++ {
+		copy(out.Pix[(y-y0)*out.W:], b.Pix[y*b.W+x0:y*b.W+x1])
+	}
+	return out
+}
+
+// span is a run [start, end) of rows or columns containing ink.
+type span struct{ start, end int }
+
+// Lines splits b into text lines at empty rows. Bands much shorter than the tallest one
+// (i dots, accents, apostrophes on their own rows) join the nearest band.
+func (b Bitmap) Lines() []Bitmap {
+	var bands []span
+	for y := 0; y < b.H; y++ {
+		ink := false
+		for x := 0; x < b.W && !ink; x++ {
+			ink = b.at(x, y)
+		}
+		switch {
+		case ink && (len(bands) == 0 || bands[len(bands)-1].end != y):
+			bands = append(bands, span{y, y + 1})
+		case ink:
+			bands[len(bands)-1].end = y + 1
+		}
+	}
+	tallest := 0
+	for _, s := range bands {
+		tallest = max(tallest, s.end-s.start)
+	}
+	for i := 0; i < len(bands) && len(bands) > 1; {
+		if (bands[i].end-bands[i].start)*5 >= tallest*2 {
+			i++
+			continue
+		}
+		j := i + 1 // merge into the closer neighbour
+		if i == len(bands)-1 || (i > 0 && bands[i].start-bands[i-1].end <= bands[i+1].start-bands[i].end) {
+			j = i - 1
+		}
+		lo, hi := min(i, j), max(i, j)
+		bands[lo] = span{bands[lo].start, bands[hi].end}
+		bands = append(bands[:hi], bands[hi+1:]...)
+		i = 0
+	}
+	lines := make([]Bitmap, len(bands))
+	for i, s := range bands {
+		lines[i] = b.crop(0, s.start, b.W, s.end)
+	}
+	return lines
+}
+
+// glyphBox is one glyph of a line: its columns and ink rows, and whether a space precedes it.
+type glyphBox struct {
+	x0, x1, y0, y1 int
+	space          bool
+}
+
+// glyphs splits a line into glyphs at empty columns; gaps wider than a third of the
+// line height are word spaces.
+func (b Bitmap) glyphs() []glyphBox {
+	var boxes []glyphBox
+	gap := -1
+	for x := 0; x < b.W; x++ {
+		ink := false
+		for y := 0; y < b.H && !ink; y++ {
+			ink = b.at(x, y)
+		}
+		if !ink {
+			if gap >= 0 {
+				gap++
+			}
+			continue
+		}
+		if len(boxes) > 0 && gap == 0 {
+			boxes[len(boxes)-1].x1 = x + 1
+		} else {
+			boxes = append(boxes, glyphBox{x0: x, x1: x + 1, space: len(boxes) > 0 && gap*3 > b.H})
+		}
+		gap = 0
+	}
+	for i := range boxes {
+		g := &boxes[i]
+		g.y0, g.y1 = b.H, 0
+		for y := 0; y < b.H; y++ {
+			for x := g.x0; x < g.x1; x++ {
+				if b.at(x, y) {
+					g.y0, g.y1 = min(g.y0, y), max(g.y1, y+1)
+					break
+				}
+			}
+		}
+	}
+	return boxes
+}
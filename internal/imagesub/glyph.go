@@ -0,0 +1,185 @@
+package imagesub
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// glyphGrid is the side of the normalized glyph fingerprint.
+const glyphGrid = 12
+
+// Match thresholds on Glyph.distance: below matchDistance a dictionary glyph is used,
+// below confidentDistance learning mode does not ask to confirm it.
+const (
+	matchDistance     = 0.15
+	confidentDistance = 0.07
+)
+
+// Glyph is the fingerprint of one glyph: its ink scaled to a 12×12 grid (hex bits) plus
+// its shape and place in the line, so "l"/"I" or "'"/"," can differ. Text is what it
+// reads as; it is the field to correct by hand in the dictionary file.
+type Glyph struct {
+	Text   string  `json:"text"`
+	Bits   string  `json:"bits"`
+	Aspect float64 `json:"aspect"` // width / height
+	Top    float64 `json:"top"`    // ink top / line height
+	Height float64 `json:"height"` // ink height / line height
+}
+
+// fingerprint describes the glyph box g of line.
+func fingerprint(line Bitmap, g glyphBox) Glyph {
+	w, h := g.x1-g.x0, g.y1-g.y0
+	bits := make([]byte, glyphGrid*glyphGrid/8)
+	for gy := 0; gy < glyphGrid; gy++ {
+		for gx := 0; gx < glyphGrid; gx++ {
+			x0, x1 := g.x0+gx*w/glyphGrid, g.x0+max((gx+1)*w/glyphGrid, gx*w/glyphGrid+1)
+			y0, y1 := g.y0+gy*h/glyphGrid, g.y0+max((gy+1)*h/glyphGrid, gy*h/glyphGrid+1)
+			ink, total := 0, 0
+			for y := y0; y < min(y1, g.y1); y++ {
+				for x := x0; x < min(x1, g.x1); x++ {
+					total++
+					if line.at(x, y) {
+						ink++
+					}
+				}
+			}
+			if total > 0 && ink*10 >= total*3 {
+				i := gy*glyphGrid + gx
+				bits[i/8] |= 0x80 >> (i % 8)
+			}
+		}
+	}
+	return Glyph{
+		Bits:   hex.EncodeToString(bits),
+		Aspect: float64(w) / float64(h),
+		Top:    float64(g.y0) / float64(line.H),
+		Height: float64(h) / float64(line.H),
+	}
+}
+
+// distance is 0 for identical glyphs; around 0.1 is still the same character.
+func (g Glyph) distance(o Glyph) float64 {
+	a, errA := hex.DecodeString(g.Bits)
+	b, errB := hex.DecodeString(o.Bits)
+	if errA != nil || errB != nil || len(a) != len(b) {
+		return math.Inf(1)
+	}
+	diff := 0
+	for i := range a {
+		for x := a[i] ^ b[i]; x != 0; x &= x - 1 {
+			diff++
+		}
+	}
+	d := float64(diff) / float64(len(a)*8)
+	d += 0.5*math.Abs(g.Top-o.Top) + 0.5*math.Abs(g.Height-o.Height)
+	d += 0.25 * math.Min(1, math.Abs(math.Log((g.Aspect+0.01)/(o.Aspect+0.01))))
+	return d
+}
+
+// Dictionary holds the trained glyphs of the glyph engine.
+type Dictionary struct {
+	Version int     `json:"version"`
+	Glyphs  []Glyph `json:"glyphs"`
+}
+
+// LoadDictionary reads a dictionary file; a missing file is an empty dictionary.
+func LoadDictionary(path string) (*Dictionary, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &Dictionary{Version: 1}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read glyph dictionary: %w", err)
+	}
+	var d Dictionary
+	if err := json.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("parse glyph dictionary %s: %w", path, err)
+	}
+	return &d, nil
+}
+
+// Save writes d as indented JSON, creating the directory.
+func (d *Dictionary) Save(path string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal glyph dictionary: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("save glyph dictionary: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("save glyph dictionary: %w", err)
+	}
+	return nil
+}
+
+// Match returns the closest glyph text and its distance; ok is false above matchDistance.
+func (d *Dictionary) Match(g Glyph) (text string, dist float64, ok bool) {
+	dist = math.Inf(1)
+	for _, known := range d.Glyphs {
+		if kd := g.distance(known); kd < dist {
+			text, dist = known.Text, kd
+		}
+	}
+	return text, dist, dist < matchDistance
+}
+
+// Learn stores g as text, correcting an identical fingerprint already known.
+func (d *Dictionary) Learn(g Glyph, text string) {
+	g.Text = text
+	for i, known := range d.Glyphs {
+		if known.distance(g) < 0.01 {
+			d.Glyphs[i].Text = text
+			return
+		}
+	}
+	d.Glyphs = append(d.Glyphs, g)
+}
+
+// GlyphEngine is the bundled OCR engine: lines and glyphs are segmented from the
+// binarized image and matched against a trained Dictionary.
+type GlyphEngine struct {
+	Dict *Dictionary
+	// Ask, when set, is called for unknown or doubtful glyphs (guess may be ""). The
+	// answer is learned; ok false stops asking for the rest of the run.
+	Ask func(glyph Bitmap, guess string) (text string, ok bool)
+	// Unknown counts glyphs that were read as "?".
+	Unknown int
+}
+
+func (e *GlyphEngine) Recognize(img image.Image) (string, error) {
+	var lines []string
+	for _, line := range Binarize(img).Lines() {
+		var sb strings.Builder
+		for _, box := range line.glyphs() {
+			if box.space {
+				sb.WriteByte(' ')
+			}
+			g := fingerprint(line, box)
+			text, dist, ok := e.Dict.Match(g)
+			if e.Ask != nil && (!ok || dist >= confidentDistance) {
+				answer, more := e.Ask(line.crop(box.x0, 0, box.x1, line.H), text)
+				if more {
+					e.Dict.Learn(g, answer)
+					text, ok = answer, true
+				} else {
+					e.Ask = nil
+				}
+			}
+			if !ok {
+				text = "?"
+				e.Unknown++
+			}
+			sb.WriteString(text)
+		}
+		lines = append(lines, sb.String())
+	}
+	return strings.Join(lines, "\n"), nil
+}
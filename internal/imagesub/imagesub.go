@@ -0,0 +1,69 @@
+// Package imagesub decodes bitmap subtitles (Blu-ray PGS .sup, DVD VobSub .idx/.sub)
+// into timed images and turns them into SRT through a pluggable OCR Engine: the bundled
+// glyph-matching engine (trained into a user-editable dictionary) or a local tesseract.
+package imagesub
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+// Subtitle is one display set: the composed image at its position on the video frame.
+type Subtitle struct {
+	Start, End time.Duration
+	Forced     bool
+	X, Y       int
+	Image      *image.NRGBA
+}
+
+// IsImageSubtitle reports whether path is a .sup or .idx/.sub file.
+func IsImageSubtitle(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".sup", ".idx":
+		return true
+	}
+	return false
+}
+
+// Engine recognizes the text of one subtitle image (lines separated by "\n").
+type Engine interface {
+	Recognize(img image.Image) (string, error)
+}
+
+// ToSRT recognizes every subtitle with engine and renders the non-empty ones as SRT.
+func ToSRT(subs []Subtitle, engine Engine) ([]byte, error) {
+	var buf bytes.Buffer
+	n := 0
+	for _, s := range subs {
+		text, err := engine.Recognize(s.Image)
+		if err != nil {
+			return nil, fmt.Errorf("ocr at %s: %w", subtitle.FormatTimecode(s.Start), err)
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+		n++
+		fmt.Fprintf(&buf, "%d\n%s --> %s\n%s\n\n", n, subtitle.FormatTimecode(s.Start), subtitle.FormatTimecode(s.End), text)
+	}
+	return buf.Bytes(), nil
+}
+
+// closeEnds gives subtitles without an end time the start of the next one (or 3s).
+func closeEnds(subs []Subtitle) {
+	for i := range subs {
+		if subs[i].End > subs[i].Start {
+			continue
+		}
+		subs[i].End = subs[i].Start + 3*time.Second
+		if i+1 < len(subs) && subs[i+1].Start > subs[i].Start {
+			subs[i].End = subs[i+1].Start
+		}
+	}
+}
@@ -0,0 +1,442 @@
+package imagesub
+
+import (
+	"encoding/binary"
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// font is a tiny bitmap font for synthetic subtitles (rows of '#' ink).
+var font = map[rune][]string{
+	'H': {"#...#", "#...#", "#...#", "#####", "#...#", "#...#", "#...#"},
+	'i': {"#", ".", "#", "#", "#", "#", "#"},
+	'.': {".", ".", ".", ".", ".", ".", "#"},
+	'o': {"...", "...", "###", "#.#", "#.#", "#.#", "###"},
+}
+
+// render draws text (lines split by "\n") as palette indexes, 1 for ink and 2 for a
+// one pixel outline, scaled by 2.
+func render(text string) (w, h int, pix []byte) {
+	const scale = 2
+	lines := strings.Split(text, "\n")
+	w = 2
+	for _, line := range lines {
+		lw := 2
+		for _, r := range line {
+			if r == ' ' {
+				lw += 3
+				continue
+			}
+			lw += len(font[r][0]) + 1
+		}
+		w = max(w, lw)
+	}
+	h = len(lines)*10 + 2
+	grid := make([]byte, w*h)
+	for li, line := range lines {
+		x := 1
+		for _, r := range line {
+			if r == ' ' {
+				x += 3
+				continue
+			}
+			for y, row := range font[r] {
+				for dx, c := range row {
+					if c == '#' {
+						grid[(1+li*10+y)*w+x+dx] = 1
+					}
+				}
+			}
+			x += len(font[r][0]) + 1
+		}
+	}
+	W, H := w*scale, h*scale
+	pix = make([]byte, W*H)
+	for y := range H {
+		for x := range W {
+			pix[y*W+x] = grid[y/scale*w+x/scale]
+		}
+	}
+	out := append([]byte(nil), pix...)
+	for y := range H {
+		for x := range W {
+			if pix[y*W+x] != 0 {
+				continue
+			}
+			for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				nx, ny := x+d[0], y+d[1]
+				if nx >= 0 && ny >= 0 && nx < W && ny < H && pix[ny*W+nx] == 1 {
+					out[y*W+x] = 2
+				}
+			}
+		}
+	}
+	return W, H, out
+}
+
+func segment(pts time.Duration, kind byte, payload []byte) []byte {
+	seg := []byte{'P', 'G', 0, 0, 0, 0, 0, 0, 0, 0, kind, 0, 0}
+	binary.BigEndian.PutUint32(seg[2:], uint32(pts*90000/time.Second))
+	binary.BigEndian.PutUint16(seg[11:], uint16(len(payload)))
+	return append(seg, payload...)
+}
+
+func pgsRLE(w, h int, pix []byte) []byte {
+	var out []byte
+	for y := range h {
+		for x := 0; x < w; {
+			c, n := pix[y*w+x], 1
+			for x+n < w && pix[y*w+x+n] == c {
+				n++
+			}
+			x += n
+			switch {
+			case c != 0 && n == 1:
+				out = append(out, c)
+			case c == 0:
+				out = append(out, 0, 0x40|byte(n>>8), byte(n))
+			default:
+				out = append(out, 0, 0xC0|byte(n>>8), byte(n), c)
+			}
+		}
+		out = append(out, 0, 0)
+	}
+	return out
+}
+
+// testSup builds a .sup showing text at 1s (forced when asked) until 3s.
+func testSup(text string, forced bool) []byte {
+	w, h, pix := render(text)
+	var flags byte
+	if forced {
+		flags = 0x40
+	}
+	pcs := []byte{0x07, 0x80, 0x04, 0x38, 0x10, 0, 1, 0x80, 0, 0, 1,
+		0, 0, 0, flags, 0, 100, 0, 200}
+	pds := []byte{0, 0,
+		1, 235, 128, 128, 0xFF, // white fill
+		2, 16, 128, 128, 0xFF, // black outline
+	}
+	rle := pgsRLE(w, h, pix)
+	ods := []byte{0, 0, 0, 0xC0, 0, 0, 0, byte(w >> 8), byte(w), byte(h >> 8), byte(h)}
+	ods = append(ods, rle...)
+
+	var sup []byte
+	sup = append(sup, segment(time.Second, pgsPCS, pcs)...)
+	sup = append(sup, segment(time.Second, pgsWDS, []byte{1, 0, 0, 100, 0, 200, 0, byte(w), 0, byte(h)})...)
+	sup = append(sup, segment(time.Second, pgsPDS, pds)...)
+	sup = append(sup, segment(time.Second, pgsODS, ods)...)
+	sup = append(sup, segment(time.Second, pgsEND, nil)...)
+	clear := []byte{0x07, 0x80, 0x04, 0x38, 0x10, 0, 2, 0x00, 0, 0, 0}
+	sup = append(sup, segment(3*time.Second, pgsPCS, clear)...)
+	sup = append(sup, segment(3*time.Second, pgsEND, nil)...)
+	return sup
+}
+
+func TestDecodePGS(t *testing.T) {
+	t.Parallel()
+
+	subs, err := DecodePGS(testSup("Hi", true))
+	if err != nil {
+		t.Fatalf("DecodePGS: %v", err)
+	}
+	if len(subs) != 1 {
+		t.Fatalf("got %d subtitles, want 1", len(subs))
+	}
+	s := subs[0]
+	w, h, _ := render("Hi")
+	if s.Start != time.Second || s.End != 3*time.Second || !s.Forced || s.X != 100 || s.Y != 200 {
+		t.Fatalf("got %v-%v forced=%v at %d,%d", s.Start, s.End, s.Forced, s.X, s.Y)
+	}
+	if got := s.Image.Bounds(); got != image.Rect(0, 0, w, h) {
+		t.Fatalf("image bounds = %v, want %dx%d", got, w, h)
+	}
+	if lines := Binarize(s.Image).Lines(); len(lines) != 1 {
+		t.Fatalf("got %d lines, want 1:\n%s", len(lines), Binarize(s.Image))
+	}
+}
+
+func TestDecodePGS_badHeader(t *testing.T) {
+	t.Parallel()
+
+	if _, err := DecodePGS([]byte("not a sup file")); err == nil {
+		t.Fatalf("DecodePGS: expected error")
+	}
+}
+
+// spuRLE encodes rows of 2-bit values as DVD subpicture nibbles (one field).
+func spuRLE(w int, rows [][]byte) []byte {
+	var nibbles []byte
+	for _, row := range rows {
+		for x := 0; x < w; {
+			c, n := row[x], 1
+			for x+n < w && n < 255 && row[x+n] == c {
+				n++
+			}
+			x += n
+			v := n<<2 | int(c)
+			switch {
+			case v < 16:
+				nibbles = append(nibbles, byte(v))
+			case v < 64:
+				nibbles = append(nibbles, byte(v>>4), byte(v&0xF))
+			case v < 256:
+				nibbles = append(nibbles, 0, byte(v>>4), byte(v&0xF))
+			default:
+				nibbles = append(nibbles, 0, byte(v>>8), byte(v>>4&0xF), byte(v&0xF))
+			}
+		}
+		if len(nibbles)%2 == 1 {
+			nibbles = append(nibbles, 0)
+		}
+	}
+	out := make([]byte, len(nibbles)/2)
+	for i := range out {
+		out[i] = nibbles[2*i]<<4 | nibbles[2*i+1]
+	}
+	return out
+}
+
+// testSPU builds a subpicture of text shown from 0 to 2 (in 1024/90000 s units) at 10,20.
+func testSPU(text string) []byte {
+	w, h, pix := render(text)
+	var top, bottom [][]byte
+	for y := range h {
+		row := make([]byte, w)
+		for x := range w {
+			if pix[y*w+x] == 1 {
+				row[x] = 1
+			}
+		}
+		if y%2 == 0 {
+			top = append(top, row)
+		} else {
+			bottom = append(bottom, row)
+		}
+	}
+	topData, bottomData := spuRLE(w, top), spuRLE(w, bottom)
+	topOff := 4
+	bottomOff := topOff + len(topData)
+	ctrl := bottomOff + len(bottomData)
+	x1, x2, y1, y2 := 10, 10+w-1, 20, 20+h-1
+	second := ctrl + 4 + 1 + 3 + 3 + 7 + 5 + 1
+	spu := []byte{0, 0, byte(ctrl >> 8), byte(ctrl)}
+	spu = append(spu, topData...)
+	spu = append(spu, bottomData...)
+	spu = append(spu, 0, 0, byte(second>>8), byte(second),
+		0x01,
+		0x03, 0x00, 0x10,
+		0x04, 0x00, 0xF0,
+		0x05, byte(x1>>4), byte(x1&0xF)<<4|byte(x2>>8), byte(x2), byte(y1>>4), byte(y1&0xF)<<4|byte(y2>>8), byte(y2),
+		0x06, 0, byte(topOff), byte(bottomOff>>8), byte(bottomOff),
+		0xFF)
+	spu = append(spu, 0, 176, byte(second>>8), byte(second), 0x02, 0xFF)
+	binary.BigEndian.PutUint16(spu, uint16(len(spu)))
+	return spu
+}
+
+// psPacket wraps spu in an MPEG-2 pack header and a private stream 1 PES packet.
+func psPacket(spu []byte) []byte {
+	pkt := []byte{0, 0, 1, 0xBA, 0x44, 0, 4, 0, 4, 1, 1, 0x89, 0xC3, 0xF8}
+	pes := []byte{0, 0, 1, 0xBD, 0, 0, 0x81, 0x00, 0x00, 0x20}
+	binary.BigEndian.PutUint16(pes[4:], uint16(4+len(spu)))
+	pkt = append(pkt, pes...)
+	return append(pkt, spu...)
+}
+
+func TestDecodeVobSub(t *testing.T) {
+	t.Parallel()
+
+	first, second := psPacket(testSPU("Hi")), psPacket(testSPU("oH"))
+	sub := append(append([]byte(nil), first...), second...)
+	idx := strings.Join([]string{
+		"# VobSub index file, v7 (do not modify this line!)",
+		"size: 720x480",
+		"palette: 000000, ffffff, 808080, 000000",
+		"id: en, index: 0",
+		"timestamp: 00:00:05:000, filepos: 000000000",
+		"id: es, index: 1",
+		"timestamp: 00:01:00:500, filepos: " + strings.Repeat("0", 6) + hex3(len(first)),
+	}, "\n")
+
+	end := time.Duration(176) * 1024 * time.Second / 90000
+	tests := []struct {
+		index int
+		start time.Duration
+	}{
+		{index: -1, start: 5 * time.Second},
+		{index: 1, start: time.Minute + 500*time.Millisecond},
+	}
+	for _, tc := range tests {
+		subs, err := DecodeVobSub([]byte(idx), sub, tc.index)
+		if err != nil {
+			t.Fatalf("DecodeVobSub(%d): %v", tc.index, err)
+		}
+		if len(subs) != 1 {
+			t.Fatalf("DecodeVobSub(%d): got %d subtitles, want 1", tc.index, len(subs))
+		}
+		s := subs[0]
+		if s.Start != tc.start || s.End != tc.start+end || s.X != 10 || s.Y != 20 {
+			t.Fatalf("DecodeVobSub(%d): got %v-%v at %d,%d", tc.index, s.Start, s.End, s.X, s.Y)
+		}
+	}
+
+	// the decoded bitmap is the rendered ink
+	subs, _ := DecodeVobSub([]byte(idx), sub, 0)
+	w, h, pix := render("Hi")
+	bm := Binarize(subs[0].Image)
+	for y := range h {
+		for x := range w {
+			if bm.at(x, y) != (pix[y*w+x] == 1) {
+				t.Fatalf("pixel %d,%d differs:\n%s", x, y, bm)
+			}
+		}
+	}
+}
+
+func hex3(n int) string {
+	const digits = "0123456789abcdef"
+	return string([]byte{digits[n>>8&0xF], digits[n>>4&0xF], digits[n&0xF]})
+}
+
+func TestGlyphEngine(t *testing.T) {
+	t.Parallel()
+
+	dict := &Dictionary{Version: 1}
+	learner := &GlyphEngine{Dict: dict, Ask: func(glyph Bitmap, guess string) (string, bool) {
+		for r, rows := range font {
+			if glyph.W == len(rows[0])*2 && strings.Count(glyph.String(), "#") == strings.Count(strings.Join(rows, ""), "#")*4 {
+				return string(r), true
+			}
+		}
+		t.Fatalf("unexpected glyph:\n%s", glyph)
+		return "", false
+	}}
+	subs, err := DecodePGS(testSup("Hi. oH", false))
+	if err != nil {
+		t.Fatalf("DecodePGS: %v", err)
+	}
+	if got, _ := learner.Recognize(subs[0].Image); got != "Hi. oH" {
+		t.Fatalf("learning Recognize = %q", got)
+	}
+
+	path := filepath.Join(t.TempDir(), "glyphs", "glyphs.json")
+	if err := dict.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := LoadDictionary(path)
+	if err != nil {
+		t.Fatalf("LoadDictionary: %v", err)
+	}
+	if len(loaded.Glyphs) != 4 {
+		t.Fatalf("got %d glyphs, want 4", len(loaded.Glyphs))
+	}
+
+	engine := &GlyphEngine{Dict: loaded}
+	subs, _ = DecodePGS(testSup("oHi\nHo.", false))
+	if got, _ := engine.Recognize(subs[0].Image); got != "oHi\nHo." || engine.Unknown != 0 {
+		t.Fatalf("Recognize = %q (%d unknown)", got, engine.Unknown)
+	}
+
+	empty, err := LoadDictionary(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatalf("LoadDictionary(missing): %v", err)
+	}
+	blind := &GlyphEngine{Dict: empty}
+	if got, _ := blind.Recognize(subs[0].Image); got != "???\n???" || blind.Unknown != 6 {
+		t.Fatalf("Recognize with empty dictionary = %q (%d unknown)", got, blind.Unknown)
+	}
+}
+
+func TestDictionaryLearn_corrects(t *testing.T) {
+	t.Parallel()
+
+	d := &Dictionary{}
+	g := Glyph{Bits: strings.Repeat("f0", 18), Aspect: 0.5, Top: 0, Height: 1}
+	d.Learn(g, "l")
+	d.Learn(g, "I")
+	if len(d.Glyphs) != 1 || d.Glyphs[0].Text != "I" {
+		t.Fatalf("got %+v, want one corrected glyph", d.Glyphs)
+	}
+	if text, _, ok := d.Match(g); !ok || text != "I" {
+		t.Fatalf("Match = %q, %v", text, ok)
+	}
+}
+
+func TestToSRT(t *testing.T) {
+	t.Parallel()
+
+	subs := []Subtitle{
+		{Start: time.Second, End: 2 * time.Second},
+		{Start: 3 * time.Second, End: 4 * time.Second},
+		{Start: time.Hour + 5*time.Second, End: time.Hour + 6*time.Second + 250*time.Millisecond},
+	}
+	texts := []string{"Hello", " ", "Two\nlines\n"}
+	i := 0
+	engine := engineFunc(func(image.Image) (string, error) {
+		i++
+		return texts[i-1], nil
+	})
+	got, err := ToSRT(subs, engine)
+	if err != nil {
+		t.Fatalf("ToSRT: %v", err)
+	}
+	want := "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n2\n01:00:05,000 --> 01:00:06,250\nTwo\nlines\n\n"
+	if string(got) != want {
+		t.Fatalf("ToSRT =\n%q\nwant\n%q", got, want)
+	}
+}
+
+type engineFunc func(image.Image) (string, error)
+
+func (f engineFunc) Recognize(img image.Image) (string, error) { return f(img) }
+
+func TestDecodeVobSub_corrupt(t *testing.T) {
+	t.Parallel()
+
+	sub := psPacket(testSPU("Hi"))
+	idx := "palette: 000000, ffffff\nid: en, index: 0\ntimestamp: 00:00:05:000, filepos: -10\n"
+	if _, err := DecodeVobSub([]byte(idx), sub, -1); err == nil {
+		t.Error("negative filepos: expected error")
+	}
+	idx = "palette: 000000, ffffff\nid: en, index: 0\ntimestamp: 00:00:05:000, filepos: 0000ffff\n"
+	if _, err := DecodeVobSub([]byte(idx), sub, -1); err == nil {
+		t.Error("filepos past the end: expected error")
+	}
+
+	// Two control sequences pointing at each other: 4 -> 10 -> 4.
+	spu := []byte{0, 16, 0, 4, 0, 0, 0, 10, 0x01, 0xFF, 0, 0, 0, 4, 0x02, 0xFF}
+	if _, err := decodeSPU(spu, [16]color.NRGBA{}); err == nil {
+		t.Error("control sequence cycle: expected error")
+	}
+}
+
+func FuzzDecodePGS(f *testing.F) {
+	f.Add(testSup("Hi", true))
+	f.Add(testSup("oHi\nHo.", false))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		DecodePGS(data)
+	})
+}
+
+func FuzzDecodeVobSub(f *testing.F) {
+	first := psPacket(testSPU("Hi"))
+	idx := "palette: 000000, ffffff, 808080, 000000\nid: en, index: 0\n" +
+		"timestamp: 00:00:05:000, filepos: 000000000\ntimestamp: 00:00:06:000, filepos: 000000" + hex3(len(first)) + "\n"
+	f.Add([]byte(idx), append(first, psPacket(testSPU("oH"))...))
+	f.Fuzz(func(t *testing.T, idx, sub []byte) {
+		DecodeVobSub(idx, sub, -1)
+	})
+}
+
+func FuzzDecodeSPU(f *testing.F) {
+	f.Add(testSPU("Hi"))
+	f.Add(testSPU("oHi"))
+	f.Fuzz(func(t *testing.T, spu []byte) {
+		decodeSPU(spu, [16]color.NRGBA{})
+	})
+}
@@ -0,0 +1,227 @@
+package imagesub
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+// PGS segment types.
+const (
+	pgsPDS = 0x14 // palette definition
+	pgsODS = 0x15 // object (bitmap) definition
+	pgsPCS = 0x16 // presentation composition
+	pgsWDS = 0x17 // window definition
+	pgsEND = 0x80
+)
+
+type pgsObject struct {
+	width, height int
+	rle           []byte
+}
+
+type pgsPlacement struct {
+	id   int
+	x, y int
+}
+
+// DecodePGS decodes a .sup stream ("PG" segments). Each display set with composition
+// objects becomes a Subtitle ending when the next display set starts.
+func DecodePGS(data []byte) ([]Subtitle, error) {
+	palettes := map[int]color.Palette{}
+	objects := map[int]*pgsObject{}
+	var subs []Subtitle
+
+	var pts time.Duration
+	var placements []pgsPlacement
+	var paletteID int
+	var forced bool
+	open := -1 // index in subs of the subtitle shown until the next display set
+
+	for off := 0; off < len(data); {
+		if len(data)-off < 13 || data[off] != 'P' || data[off+1] != 'G' {
+			return nil, fmt.Errorf("pgs: bad segment header at %d", off)
+		}
+		segPTS := time.Duration(binary.BigEndian.Uint32(data[off+2:])) * time.Second / 90000
+		kind := data[off+10]
+		size := int(binary.BigEndian.Uint16(data[off+11:]))
+		off += 13
+		if off+size > len(data) {
+			return nil, fmt.Errorf("pgs: truncated segment at %d", off)
+		}
+		seg := data[off : off+size]
+		off += size
+
+		switch kind {
+		case pgsPCS:
+			if len(seg) < 11 {
+				return nil, errors.New("pgs: short composition segment")
+			}
+			pts = segPTS
+			if seg[7]&0x80 != 0 { // epoch start: forget earlier objects
+				objects = map[int]*pgsObject{}
+			}
+			paletteID = int(seg[9])
+			n := int(seg[10])
+			placements, forced = placements[:0], false
+			for p := 11; n > 0 && p+8 <= len(seg); n-- {
+				placements = append(placements, pgsPlacement{
+					id: int(binary.BigEndian.Uint16(seg[p:])),
+					x:  int(binary.BigEndian.Uint16(seg[p+4:])),
+					y:  int(binary.BigEndian.Uint16(seg[p+6:])),
+				})
+				forced = forced || seg[p+3]&0x40 != 0
+				if seg[p+3]&0x80 != 0 { // cropped: skip the crop rectangle
+					p += 8
+				}
+				p += 8
+			}
+		case pgsPDS:
+			if len(seg) < 2 {
+				return nil, errors.New("pgs: short palette segment")
+			}
+			pal := make(color.Palette, 256)
+			for i := range pal {
+				pal[i] = color.NRGBA{}
+			}
+			for p := 2; p+5 <= len(seg); p += 5 {
+				r, g, b := color.YCbCrToRGB(seg[p+1], seg[p+3], seg[p+2])
+				pal[seg[p]] = color.NRGBA{R: r, G: g, B: b, A: seg[p+4]}
+			}
+			palettes[int(seg[0])] = pal
+		case pgsODS:
+			if len(seg) < 4 {
+				return nil, errors.New("pgs: short object segment")
+			}
+			id := int(binary.BigEndian.Uint16(seg))
+			if seg[3]&0x80 != 0 { // first fragment: length, size, data
+				if len(seg) < 11 {
+					return nil, errors.New("pgs: short object segment")
+				}
+				objects[id] = &pgsObject{
+					width:  int(binary.BigEndian.Uint16(seg[7:])),
+					height: int(binary.BigEndian.Uint16(seg[9:])),
+					rle:    append([]byte(nil), seg[11:]...),
+				}
+			} else if obj := objects[id]; obj != nil {
+				obj.rle = append(obj.rle, seg[4:]...)
+			}
+		case pgsEND:
+			if open >= 0 {
+				subs[open].End = pts
+				open = -1
+			}
+			if len(placements) == 0 {
+				continue
+			}
+			sub, err := composePGS(placements, objects, palettes[paletteID])
+			if err != nil {
+				return nil, fmt.Errorf("pgs at %s: %w", subtitle.FormatTimecode(pts), err)
+			}
+			if sub.Image == nil {
+				continue
+			}
+			sub.Start, sub.Forced = pts, forced
+			subs = append(subs, sub)
+			open = len(subs) - 1
+		case pgsWDS:
+		}
+	}
+	closeEnds(subs)
+	return subs, nil
+}
+
+// maxImageSide bounds the composed subtitle image: PGS objects are at most 4096x4096 and
+// placed within the video frame, so anything larger is corrupt (and would take gigabytes).
+const maxImageSide = 4096
+
+// composePGS draws the placed objects into one image covering their bounding box.
+func composePGS(placements []pgsPlacement, objects map[int]*pgsObject, pal color.Palette) (Subtitle, error) {
+	if pal == nil {
+		return Subtitle{}, errors.New("no palette")
+	}
+	var bounds image.Rectangle
+	for _, p := range placements {
+		if obj := objects[p.id]; obj != nil {
+			bounds = bounds.Union(image.Rect(p.x, p.y, p.x+obj.width, p.y+obj.height))
+		}
+	}
+	if bounds.Empty() {
+		return Subtitle{}, nil
+	}
+	if bounds.Dx() > maxImageSide || bounds.Dy() > maxImageSide {
+		return Subtitle{}, fmt.Errorf("subtitle image %dx%d larger than %dx%d", bounds.Dx(), bounds.Dy(), maxImageSide, maxImageSide)
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	for _, p := range placements {
+		obj := objects[p.id]
+		if obj == nil {
+			continue
+		}
+		idx, err := decodePGSRLE(obj.rle, obj.width, obj.height)
+		if err != nil {
+			return Subtitle{}, err
+		}
+		ox, oy := p.x-bounds.Min.X, p.y-bounds.Min.Y
+		for y := 0; y < obj.height; y++ {
+			for x := 0; x < obj.width; x++ {
+				img.Set(ox+x, oy+y, pal[idx[y*obj.width+x]])
+			}
+		}
+	}
+	return Subtitle{X: bounds.Min.X, Y: bounds.Min.Y, Image: img}, nil
+}
+
+// decodePGSRLE expands the PGS run-length encoding into palette indexes.
+func decodePGSRLE(rle []byte, width, height int) ([]byte, error) {
+	out := make([]byte, width*height)
+	x, y := 0, 0
+	put := func(n int, c byte) {
+		for ; n > 0 && x < width; n-- {
+			if y < height {
+				out[y*width+x] = c
+			}
+			x++
+		}
+	}
+	for i := 0; i < len(rle) && y < height; {
+		b := rle[i]
+		i++
+		if b != 0 {
+			put(1, b)
+			continue
+		}
+		if i >= len(rle) {
+			break
+		}
+		flags := rle[i]
+		i++
+		if flags == 0 { // end of line
+			x, y = 0, y+1
+			continue
+		}
+		n := int(flags & 0x3F)
+		if flags&0x40 != 0 {
+			if i >= len(rle) {
+				return nil, errors.New("truncated rle")
+			}
+			n = n<<8 | int(rle[i])
+			i++
+		}
+		var c byte
+		if flags&0x80 != 0 {
+			if i >= len(rle) {
+				return nil, errors.New("truncated rle")
+			}
+			c = rle[i]
+			i++
+		}
+		put(n, c)
+	}
+	return out, nil
+}
@@ -0,0 +1,61 @@
+package imagesub
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Tesseract runs a local tesseract binary on each (binarized) subtitle image.
+type Tesseract struct {
+	Lang string // tesseract language, eg: "eng" (default)
+}
+
+var tesseractLookPath = exec.LookPath
+
+// TesseractAvailable reports whether tesseract is in PATH.
+func TesseractAvailable() bool {
+	_, err := tesseractLookPath("tesseract")
+	return err == nil
+}
+
+func (t Tesseract) Recognize(img image.Image) (string, error) {
+	tmp, err := os.MkdirTemp("", "subtitle-sanitizer-ocr-")
+	if err != nil {
+		return "", fmt.Errorf("create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+
+	in := filepath.Join(tmp, "sub.png")
+	f, err := os.Create(in)
+	if err != nil {
+		return "", err
+	}
+	err = png.Encode(f, Binarize(img).ToImage(10))
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("write ocr image: %w", err)
+	}
+
+	lang := t.Lang
+	if lang == "" {
+		lang = "eng"
+	}
+	out, err := exec.Command("tesseract", in, "stdout", "-l", lang, "--psm", "6").Output()
+	if err != nil {
+		return "", fmt.Errorf("tesseract failed: %w", err)
+	}
+	var lines []string
+	for line := range strings.SplitSeq(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
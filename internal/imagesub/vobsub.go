@@ -0,0 +1,312 @@
+package imagesub
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+// vobIdx is the part of a VobSub .idx the decoder needs.
+type vobIdx struct {
+	palette [16]color.NRGBA
+	entries []vobEntry // of the selected stream
+}
+
+type vobEntry struct {
+	time    time.Duration
+	filepos int64
+}
+
+// DecodeVobSub decodes the subtitle stream with the given index of a VobSub pair (the
+// first stream when index < 0): .idx text (palette, timestamps, file positions) and the
+// MPEG-PS .sub with the SPU packets.
+func DecodeVobSub(idx, sub []byte, index int) ([]Subtitle, error) {
+	parsed, err := parseIdx(idx, index)
+	if err != nil {
+		return nil, err
+	}
+	var subs []Subtitle
+	for _, e := range parsed.entries {
+		packet, err := readSPU(sub, e.filepos)
+		if err != nil {
+			return nil, fmt.Errorf("vobsub at %s: %w", subtitle.FormatTimecode(e.time), err)
+		}
+		s, err := decodeSPU(packet, parsed.palette)
+		if err != nil {
+			return nil, fmt.Errorf("vobsub at %s: %w", subtitle.FormatTimecode(e.time), err)
+		}
+		if s.Image == nil {
+			continue
+		}
+		s.Start += e.time
+		if s.End > 0 {
+			s.End += e.time
+		}
+		subs = append(subs, s)
+	}
+	closeEnds(subs)
+	return subs, nil
+}
+
+func parseIdx(data []byte, index int) (vobIdx, error) {
+	var out vobIdx
+	stream, selected := -1, false
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		key, value, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(key) {
+		case "palette":
+			for i, hex := range strings.Split(value, ",") {
+				if i >= 16 {
+					break
+				}
+				v, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 32)
+				if err != nil {
+					return out, fmt.Errorf("idx palette: %w", err)
+				}
+				out.palette[i] = color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xFF}
+			}
+		case "id":
+			stream++
+			selected = index < 0 && stream == 0 || stream == index
+		case "timestamp":
+			if !selected && stream >= 0 {
+				continue
+			}
+			// timestamp: 00:00:01:234, filepos: 000000000
+			ts, pos, ok := strings.Cut(value, ",")
+			if !ok {
+				continue
+			}
+			t, err := parseIdxTime(strings.TrimSpace(ts))
+			if err != nil {
+				return out, err
+			}
+			_, hex, _ := strings.Cut(pos, ":")
+			fp, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 62)
+			if err != nil {
+				return out, fmt.Errorf("idx filepos: %w", err)
+			}
+			out.entries = append(out.entries, vobEntry{time: t, filepos: int64(fp)})
+		}
+	}
+	if len(out.entries) == 0 {
+		return out, errors.New("idx: no timestamps")
+	}
+	return out, sc.Err()
+}
+
+// parseIdxTime parses HH:MM:SS:mmm.
+func parseIdxTime(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 4 {
+		return 0, fmt.Errorf("idx timestamp %q", s)
+	}
+	var v [4]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return 0, fmt.Errorf("idx timestamp %q", s)
+		}
+		v[i] = n
+	}
+	return time.Duration(v[0])*time.Hour + time.Duration(v[1])*time.Minute +
+		time.Duration(v[2])*time.Second + time.Duration(v[3])*time.Millisecond, nil
+}
+
+// readSPU gathers the private stream 1 payload of the MPEG-PS packs starting at pos
+// until the SPU packet (its size is in its first two bytes) is complete.
+func readSPU(sub []byte, pos int64) ([]byte, error) {
+	if pos < 0 || pos >= int64(len(sub)) {
+		return nil, fmt.Errorf("sub: file position %d out of range", pos)
+	}
+	var spu []byte
+	size := -1
+	p := int(pos)
+	for size < 0 || len(spu) < size {
+		if p+4 > len(sub) || sub[p] != 0 || sub[p+1] != 0 || sub[p+2] != 1 {
+			return nil, fmt.Errorf("sub: no start code at %d", p)
+		}
+		switch id := sub[p+3]; {
+		case id == 0xBA: // pack header (MPEG-2, or MPEG-1 without stuffing)
+			if p+14 > len(sub) {
+				return nil, errors.New("sub: truncated pack header")
+			}
+			if sub[p+4]>>6 == 1 {
+				p += 14 + int(sub[p+13]&7)
+			} else {
+				p += 12
+			}
+		case id == 0xBD: // private stream 1: PES header, substream id, SPU bytes
+			if p+9 > len(sub) {
+				return nil, errors.New("sub: truncated PES header")
+			}
+			end := p + 6 + int(binary.BigEndian.Uint16(sub[p+4:]))
+			start := p + 9 + int(sub[p+8]) + 1
+			if end > len(sub) || start > end {
+				return nil, errors.New("sub: truncated PES packet")
+			}
+			spu = append(spu, sub[start:end]...)
+			if size < 0 && len(spu) >= 2 {
+				size = int(binary.BigEndian.Uint16(spu))
+			}
+			p = end
+		case id >= 0xBB: // other PES (padding, ...)
+			if p+6 > len(sub) {
+				return nil, errors.New("sub: truncated PES header")
+			}
+			p += 6 + int(binary.BigEndian.Uint16(sub[p+4:]))
+		default:
+			return nil, fmt.Errorf("sub: unexpected start code %02X at %d", id, p)
+		}
+	}
+	return spu[:size], nil
+}
+
+// decodeSPU decodes one DVD subpicture: control sequences (display times, colors,
+// alpha, area, RLE offsets) and the interlaced 2-bit RLE bitmap. Times are relative.
+func decodeSPU(spu []byte, palette [16]color.NRGBA) (Subtitle, error) {
+	if len(spu) < 4 {
+		return Subtitle{}, errors.New("spu: too short")
+	}
+	var s Subtitle
+	var colors, alpha [4]byte
+	var x1, x2, y1, y2 int
+	var top, bottom int
+	seen := map[int]bool{}
+	for ctrl := int(binary.BigEndian.Uint16(spu[2:])); ; {
+		if ctrl+4 > len(spu) {
+			return Subtitle{}, errors.New("spu: control sequence out of range")
+		}
+		if seen[ctrl] {
+			return Subtitle{}, fmt.Errorf("spu: control sequences loop back to %d", ctrl)
+		}
+		seen[ctrl] = true
+		delay := time.Duration(binary.BigEndian.Uint16(spu[ctrl:])) * 1024 * time.Second / 90000
+		next := int(binary.BigEndian.Uint16(spu[ctrl+2:]))
+		p := ctrl + 4
+	commands:
+		for p < len(spu) {
+			cmd := spu[p]
+			p++
+			switch cmd {
+			case 0x00:
+				s.Forced = true
+				s.Start = delay
+			case 0x01:
+				s.Start = delay
+			case 0x02:
+				s.End = delay
+			case 0x03, 0x04:
+				if p+2 > len(spu) {
+					return Subtitle{}, errors.New("spu: truncated command")
+				}
+				v := &colors
+				if cmd == 0x04 {
+					v = &alpha
+				}
+				v[3], v[2], v[1], v[0] = spu[p]>>4, spu[p]&0xF, spu[p+1]>>4, spu[p+1]&0xF
+				p += 2
+			case 0x05:
+				if p+6 > len(spu) {
+					return Subtitle{}, errors.New("spu: truncated command")
+				}
+				b := spu[p:]
+				x1, x2 = int(b[0])<<4|int(b[1])>>4, int(b[1]&0xF)<<8|int(b[2])
+				y1, y2 = int(b[3])<<4|int(b[4])>>4, int(b[4]&0xF)<<8|int(b[5])
+				p += 6
+			case 0x06:
+				if p+4 > len(spu) {
+					return Subtitle{}, errors.New("spu: truncated command")
+				}
+				top, bottom = int(binary.BigEndian.Uint16(spu[p:])), int(binary.BigEndian.Uint16(spu[p+2:]))
+				p += 4
+			case 0xFF:
+				break commands
+			default:
+				return Subtitle{}, fmt.Errorf("spu: unknown command %02X", cmd)
+			}
+		}
+		if next == ctrl {
+			break
+		}
+		ctrl = next
+	}
+
+	w, h := x2-x1+1, y2-y1+1
+	if w <= 0 || h <= 0 || top == 0 {
+		return s, nil
+	}
+	var pal [4]color.NRGBA
+	for i := range pal {
+		c := palette[colors[i]]
+		c.A = alpha[i] * 17
+		pal[i] = c
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for field, off := range []int{top, bottom} {
+		if err := decodeSPUField(spu, off, w, h, field, img, pal); err != nil {
+			return Subtitle{}, err
+		}
+	}
+	s.X, s.Y, s.Image = x1, y1, img
+	return s, nil
+}
+
+// decodeSPUField draws the rows of one field (even rows for 0, odd for 1).
+func decodeSPUField(spu []byte, off, w, h, field int, img *image.NRGBA, pal [4]color.NRGBA) error {
+	nib := off * 2 // nibble position
+	next := func() (int, bool) {
+		if nib/2 >= len(spu) {
+			return 0, false
+		}
+		b := spu[nib/2]
+		nib++
+		if nib%2 == 1 {
+			return int(b >> 4), true
+		}
+		return int(b & 0xF), true
+	}
+	for y := field; y < h; y += 2 {
+		for x := 0; x < w; {
+			v, ok := next()
+			if !ok {
+				return errors.New("spu: truncated rle")
+			}
+			// 1 to 4 nibbles: the run length grows while the value is below 4, 16, 64.
+			for limit := 4; v < limit && limit <= 64; limit *= 4 {
+				n, ok := next()
+				if !ok {
+					return errors.New("spu: truncated rle")
+				}
+				v = v<<4 | n
+			}
+			run, c := v>>2, pal[v&3]
+			if run == 0 {
+				run = w - x
+			}
+			for ; run > 0 && x < w; run-- {
+				img.SetNRGBA(x, y, c)
+				x++
+			}
+		}
+		if nib%2 == 1 { // rows start on a byte boundary
+			nib++
+		}
+	}
+	return nil
+}
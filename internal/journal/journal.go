@@ -0,0 +1,372 @@
+// Package journal records what the sanitizer overwrites or deletes so that it can be put
+// back byte for byte: every write keeps the SHA-256 and a gzip copy of the input and of
+// the file the output replaced, in a central store.
+//
+// Layout of the journal directory: journal.ndjson, one Entry (or restore mark, an Entry
+// with Restores set) per line, and objects/SHA256.gz, the compressed contents, shared by
+// identical files. An entry is appended before the files are touched, so a write cut
+// short is still in the log.
+package journal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const logName = "journal.ndjson"
+
+// ErrChanged reports a file that no longer holds what the journaled write left in it.
+var ErrChanged = errors.New("changed since it was written")
+
+// Entry is one journaled write: Output got the sanitized bytes of Input.
+type Entry struct {
+	ID           string    `json:"id"` // time-ordered, unique
+	Time         time.Time `json:"time"`
+	Input        string    `json:"input"`
+	InputSHA256  string    `json:"inputSha256"`
+	InputRemoved bool      `json:"inputRemoved,omitempty"` // deleted after the write (ASS overwritten by SRT)
+	Output       string    `json:"output"`
+	OutputSHA256 string    `json:"outputSha256"`
+	// Previous is the SHA-256 of what Output held before the write ("" when it did not
+	// exist; InputSHA256 when the output replaced the input).
+	Previous string `json:"previous,omitempty"`
+	// Restores is the ID of the write a restore mark undoes; it is set on mark lines only.
+	Restores string `json:"restores,omitempty"`
+	// Restored is set by Entries once the write was undone (and on the mark lines of
+	// journals written before Restores, which repeated the ID of the write).
+	Restored *time.Time `json:"restored,omitempty"`
+}
+
+// Journal is a journal directory. Safe for concurrent use.
+type Journal struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// DefaultDir is the journal in the user config dir (eg: ~/.config/subtitle-sanitizer/journal).
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "subtitle-sanitizer", "journal"), nil
+}
+
+// Open opens the journal in dir, creating it when missing.
+func Open(dir string) (*Journal, error) {
+	if err := os.MkdirAll(filepath.Join(dir, "objects"), 0755); err != nil {
+		return nil, fmt.Errorf("journal: %w", err)
+	}
+	return &Journal{dir: dir}, nil
+}
+
+// Dir is the journal directory.
+func (j *Journal) Dir() string { return j.dir }
+
+// WriteFile writes data to output, made from input (whose bytes were original), and
+// deletes input afterwards when removeInput is set. Input and the file output replaces
+// are saved, and the entry logged, before anything is touched. A nil Journal writes without recording.
+func (j *Journal) WriteFile(input string, original []byte, output string, data []byte, removeInput bool) error {
+	if j == nil {
+		if err := os.WriteFile(output, data, 0644); err != nil {
+			return err
+		}
+		if removeInput && input != output {
+			return os.Remove(input)
+		}
+		return nil
+	}
+	input, output = absPath(input), absPath(output)
+	e := Entry{
+		Time:         time.Now(),
+		Input:        input,
+		InputRemoved: removeInput && input != output,
+		Output:       output,
+	}
+	e.ID = newID(e.Time)
+	var err error
+	if e.InputSHA256, err = j.store(original); err != nil {
+		return err
+	}
+	if e.OutputSHA256, err = j.store(data); err != nil {
+		return err
+	}
+	switch prev, err := os.ReadFile(output); {
+	case err == nil:
+		if e.Previous, err = j.store(prev); err != nil {
+			return err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return fmt.Errorf("journal: %w", err)
+	}
+	if err := j.append(e); err != nil {
+		return err
+	}
+
+	// A failed write left output as it was: mark the entry undone so restore skips it.
+	if err := writeAtomic(output, data); err != nil {
+		return errors.Join(err, j.append(restoreMark(e)))
+	}
+	if e.InputRemoved {
+		if err := os.Remove(input); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newID returns a time-ordered ID: the time in base 36 and a random suffix, so that
+// writes in the same nanosecond (or by several processes) do not share one.
+func newID(t time.Time) string {
+	var suffix [4]byte
+	rand.Read(suffix[:])
+	return strconv.FormatInt(t.UnixNano(), 36) + "-" + hex.EncodeToString(suffix[:])
+}
+
+// restoreMark is the log line recording that e was undone.
+func restoreMark(e Entry) Entry {
+	now := time.Now()
+	return Entry{ID: newID(now), Time: now, Input: e.Input, Output: e.Output, Restores: e.ID}
+}
+
+// Entries returns the journaled writes, oldest first, with Restored set on undone ones.
+func (j *Journal) Entries() ([]Entry, error) {
+	f, err := os.Open(filepath.Join(j.dir, logName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("journal: %w", err)
+	}
+	defer f.Close()
+
+	var entries []Entry
+	index := map[string]int{}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("journal: %s line %d: %w", logName, n, err)
+		}
+		switch {
+		case e.Restores != "":
+			if i, ok := index[e.Restores]; ok {
+				entries[i].Restored = &e.Time
+			}
+			continue
+		case e.Restored != nil: // mark of an older journal
+			if i, ok := index[e.ID]; ok {
+				entries[i].Restored = e.Restored
+			}
+			continue
+		}
+		index[e.ID] = len(entries)
+		entries = append(entries, e)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("journal: %w", err)
+	}
+	return entries, nil
+}
+
+// Restore undoes e: Output gets back what it held before (or is removed when it did
+// not exist) and Input its original bytes. Files changed since the write are left alone
+// with ErrChanged unless force is set. Undo later writes of the same files first.
+func (j *Journal) Restore(e Entry, force bool) error {
+	if e.Restored != nil {
+		return fmt.Errorf("%s: already restored", e.Output)
+	}
+	if !force {
+		if err := checkHash(e.Output, e.OutputSHA256); err != nil {
+			return err
+		}
+		if e.InputRemoved && exists(e.Input) {
+			return fmt.Errorf("%s: %w", e.Input, ErrChanged)
+		}
+	}
+
+	switch {
+	case e.Previous != "":
+		prev, err := j.load(e.Previous)
+		if err != nil {
+			return err
+		}
+		if err := writeAtomic(e.Output, prev); err != nil {
+			return err
+		}
+	case e.Output != e.Input:
+		if err := os.Remove(e.Output); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if e.InputRemoved {
+		original, err := j.load(e.InputSHA256)
+		if err != nil {
+			return err
+		}
+		if err := writeAtomic(e.Input, original); err != nil {
+			return err
+		}
+	}
+	return j.append(restoreMark(e))
+}
+
+func (j *Journal) append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	f, err := os.OpenFile(filepath.Join(j.dir, logName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("journal: %w", err)
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return fmt.Errorf("journal: %w", err)
+	}
+	return f.Close()
+}
+
+// store saves data compressed under its SHA-256 (once) and returns the hash.
+func (j *Journal) store(data []byte) (string, error) {
+	sum := Hash(data)
+	path := j.objectPath(sum)
+	if exists(path) {
+		return sum, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	if err := writeAtomic(path, buf.Bytes()); err != nil {
+		return "", fmt.Errorf("journal: %w", err)
+	}
+	return sum, nil
+}
+
+// load returns the contents stored under sum, checking the hash.
+func (j *Journal) load(sum string) ([]byte, error) {
+	f, err := os.Open(j.objectPath(sum))
+	if err != nil {
+		return nil, fmt.Errorf("journal: %w", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("journal: object %s: %w", sum, err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, fmt.Errorf("journal: object %s: %w", sum, err)
+	}
+	if Hash(data) != sum {
+		return nil, fmt.Errorf("journal: object %s is corrupt", sum)
+	}
+	return data, nil
+}
+
+func (j *Journal) objectPath(sum string) string {
+	return filepath.Join(j.dir, "objects", sum+".gz")
+}
+
+// Hash is the hex SHA-256 of data.
+func Hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Select returns the entries to undo for paths (outputs or inputs), newest first: every
+// write not yet restored, or with last only the newest one of each path. Entries before
+// since are left out; no paths selects every file.
+func Select(entries []Entry, paths []string, since time.Time, last bool) []Entry {
+	abs := make([]string, len(paths))
+	for i, p := range paths {
+		abs[i] = absPath(p)
+	}
+	var out []Entry
+	seen := map[string]bool{}
+	for _, e := range slices.Backward(entries) {
+		if e.Restored != nil || e.Time.Before(since) {
+			continue
+		}
+		if len(abs) > 0 && !slices.Contains(abs, e.Output) && !slices.Contains(abs, e.Input) {
+			continue
+		}
+		if last && (seen[e.Output] || seen[e.Input]) {
+			continue
+		}
+		seen[e.Output], seen[e.Input] = true, true
+		out = append(out, e)
+	}
+	return out
+}
+
+func checkHash(path, sum string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%s: %w (missing)", path, ErrChanged)
+		}
+		return err
+	}
+	if Hash(data) != sum {
+		return fmt.Errorf("%s: %w", path, ErrChanged)
+	}
+	return nil
+}
+
+// writeAtomic replaces path with data through a temporary file in the same directory,
+// keeping the permissions of the file it replaces.
+func writeAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
@@ -0,0 +1,172 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournal_restore(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	j, err := Open(filepath.Join(dir, "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srt := filepath.Join(dir, "a.srt")
+	ass := filepath.Join(dir, "b.ass")
+	assOut := filepath.Join(dir, "b.srt")
+	his := filepath.Join(dir, "c-his.srt")
+	writeFile(t, srt, "1\r\n(sighs) Hi\r\n")
+	writeFile(t, ass, "[Script Info]\n")
+	writeFile(t, assOut, "old b.srt")
+
+	// In place twice, ASS replaced by SRT (over an existing b.srt), and a new -his.srt.
+	for _, w := range []struct {
+		input, output, data string
+		remove              bool
+	}{
+		{srt, srt, "1\nHi\n", false},
+		{srt, srt, "1\nHi!\n", false},
+		{ass, assOut, "1\nB\n", true},
+		{filepath.Join(dir, "c.srt"), his, "1\nC\n", false},
+	} {
+		original, _ := os.ReadFile(w.input)
+		if err := j.WriteFile(w.input, original, w.output, []byte(w.data), w.remove); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(ass); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("input not removed: %v", err)
+	}
+
+	entries, err := j.Entries()
+	if err != nil || len(entries) != 4 {
+		t.Fatalf("entries: %+v, %v", entries, err)
+	}
+	if got := Select(entries, []string{srt}, time.Time{}, true); len(got) != 1 || got[0].OutputSHA256 != Hash([]byte("1\nHi!\n")) {
+		t.Fatalf("Select last: %+v", got)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(wd, srt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths := []string{rel}
+	if got := Select(entries, paths, time.Time{}, false); len(got) != 2 || paths[0] != rel {
+		t.Fatalf("Select relative: %d entries, paths %q", len(got), paths)
+	}
+
+	writeFile(t, his, "edited by hand")
+	for _, e := range Select(entries, nil, time.Time{}, false) {
+		err := j.Restore(e, false)
+		if e.Output == his {
+			if !errors.Is(err, ErrChanged) {
+				t.Fatalf("restore of a changed file: %v", err)
+			}
+			err = j.Restore(e, true)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, want := range map[string]string{srt: "1\r\n(sighs) Hi\r\n", ass: "[Script Info]\n", assOut: "old b.srt"} {
+		if got, _ := os.ReadFile(path); string(got) != want {
+			t.Fatalf("%s = %q, want %q", path, got, want)
+		}
+	}
+	if _, err := os.Stat(his); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("new output not removed: %v", err)
+	}
+
+	entries, _ = j.Entries()
+	if left := Select(entries, nil, time.Time{}, false); len(left) != 0 {
+		t.Fatalf("not marked restored: %+v", left)
+	}
+}
+
+func TestJournal_entries(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	j, err := Open(filepath.Join(dir, "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srt := filepath.Join(dir, "a.srt")
+	writeFile(t, srt, "0")
+	for i := range 20 {
+		if err := j.WriteFile(srt, []byte("0"), srt, []byte{byte('a' + i)}, false); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A write that fails is logged first, then marked undone.
+	missing := filepath.Join(dir, "missing", "b.srt")
+	if err := j.WriteFile(srt, []byte("0"), missing, []byte("b"), false); err == nil {
+		t.Fatal("write into a missing directory succeeded")
+	}
+
+	entries, err := j.Entries()
+	if err != nil || len(entries) != 21 {
+		t.Fatalf("entries: %d, %v", len(entries), err)
+	}
+	ids := map[string]bool{}
+	for _, e := range entries {
+		if ids[e.ID] {
+			t.Fatalf("duplicate ID %s", e.ID)
+		}
+		ids[e.ID] = true
+	}
+	if last := entries[20]; last.Output != missing || last.Restored == nil {
+		t.Fatalf("failed write: %+v", last)
+	}
+	if got := Select(entries, nil, time.Time{}, false); len(got) != 20 {
+		t.Fatalf("Select: %d entries", len(got))
+	}
+
+	// Older journals marked a restore by repeating the ID with Restored set; a repeated ID
+	// without it is another write.
+	old, err := Open(filepath.Join(dir, "old"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(old.Dir(), logName), `{"id":"a","time":"2024-01-02T00:00:00Z","output":"/x.srt"}
+{"id":"a","time":"2024-01-03T00:00:00Z","output":"/x.srt","restored":"2024-01-03T00:00:00Z"}
+{"id":"a","time":"2024-01-04T00:00:00Z","output":"/y.srt"}
+`)
+	entries, err = old.Entries()
+	if err != nil || len(entries) != 2 || entries[0].Restored == nil || entries[1].Restored != nil {
+		t.Fatalf("old journal: %+v, %v", entries, err)
+	}
+}
+
+func TestJournal_nil(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "a.ass"), filepath.Join(dir, "a.srt")
+	writeFile(t, in, "x")
+	var j *Journal
+	if err := j.WriteFile(in, []byte("x"), out, []byte("y"), true); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := os.ReadFile(out); string(got) != "y" {
+		t.Fatalf("output = %q", got)
+	}
+	if _, err := os.Stat(in); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("input not removed: %v", err)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
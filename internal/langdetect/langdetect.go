@@ -0,0 +1,111 @@
+// Package langdetect guesses the language of short subtitle lines from common function
+// words and a few distinctive letters. It only tells apart the languages it knows
+// (ISO 639-1: en, es, pt, fr, de, it, nl) and says so when a line is too short to tell.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopwords are frequent short words of each language; words shared by several
+// languages still count for each of them and only the margin decides.
+var stopwords = map[string][]string{
+	"en": {"the", "and", "you", "is", "are", "that", "it", "to", "of", "what", "this", "have", "was", "not", "for", "with", "i'm", "don't", "we", "he", "she", "my", "your", "me", "do", "be", "in", "on", "i", "it's", "just", "know", "can", "will", "there", "here", "no"},
+	"es": {"el", "la", "los", "las", "que", "de", "y", "es", "no", "en", "un", "una", "por", "qué", "para", "con", "está", "estoy", "yo", "tú", "pero", "muy", "sí", "eso", "esto", "como", "lo", "se", "me", "te", "del", "al", "usted", "aquí", "ahora", "bien", "hay"},
+	"pt": {"o", "a", "os", "as", "que", "de", "e", "é", "não", "em", "um", "uma", "por", "para", "com", "está", "eu", "você", "mas", "muito", "sim", "isso", "isto", "como", "se", "me", "te", "do", "da", "ele", "ela", "aqui", "agora", "bem", "tem", "vai"},
+	"fr": {"le", "la", "les", "que", "de", "et", "est", "ne", "pas", "en", "un", "une", "pour", "avec", "je", "tu", "vous", "mais", "très", "oui", "ça", "ce", "comme", "il", "elle", "nous", "c'est", "du", "des", "qui", "suis", "ici", "bien", "moi"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ich", "du", "sie", "wir", "ein", "eine", "zu", "mit", "was", "es", "ja", "nein", "aber", "sehr", "auf", "für", "den", "dem", "bin", "hier", "jetzt", "gut", "mir", "mich"},
+	"it": {"il", "lo", "la", "che", "di", "e", "è", "non", "un", "una", "per", "con", "sono", "io", "tu", "lei", "ma", "molto", "sì", "questo", "cosa", "come", "mi", "ti", "ci", "del", "della", "qui", "adesso", "bene", "ho"},
+	"nl": {"de", "het", "een", "en", "is", "niet", "ik", "je", "jij", "wij", "zijn", "dat", "wat", "met", "voor", "maar", "ja", "nee", "van", "ook", "hier", "nu", "goed", "mij", "heb"},
+}
+
+// letters are characters that (among the known languages) point to one of them.
+var letters = map[rune]string{'ñ': "es", '¿': "es", '¡': "es", 'ã': "pt", 'õ': "pt", 'ß': "de"}
+
+var index = func() map[string][]string {
+	idx := map[string][]string{}
+	for lang, words := range stopwords {
+		for _, w := range words {
+			idx[w] = append(idx[w], lang)
+		}
+	}
+	return idx
+}()
+
+// Detect returns the language of text and whether the guess is reliable: at least two
+// distinct hints (a word repeated, as in "No, no, no!", counts once) and twice the score
+// of the runner-up. Markup is not stripped; pass plain text.
+func Detect(text string) (string, bool) {
+	hits := map[string]int{}  // score: words count 1, letters 2
+	hints := map[string]int{} // distinct words and letters
+	seen := map[string]bool{}
+	for _, r := range strings.ToLower(text) {
+		if lang, ok := letters[r]; ok && !seen[string(r)] {
+			seen[string(r)] = true
+			hits[lang] += 2
+			hints[lang]++
+		}
+	}
+	for word := range strings.FieldsFuncSeq(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\'' && r != '’'
+	}) {
+		word = strings.ReplaceAll(strings.Trim(word, "'’"), "’", "'")
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		for _, lang := range index[word] {
+			hits[lang]++
+			hints[lang]++
+		}
+	}
+	best, first, second := "", 0, 0
+	for _, lang := range Languages {
+		switch n := hits[lang]; {
+		case n > first:
+			best, first, second = lang, n, first
+		case n > second:
+			second = n
+		}
+	}
+	return best, hints[best] >= 2 && first >= 2*second
+}
+
+// Languages lists the detectable languages, in tie-break order.
+var Languages = []string{"en", "es", "pt", "fr", "de", "it", "nl"}
+
+// iso6392 maps the ISO 639-2 codes of the known languages to ISO 639-1.
+var iso6392 = map[string]string{
+	"eng": "en", "spa": "es", "por": "pt", "fre": "fr", "fra": "fr",
+	"ger": "de", "deu": "de", "ita": "it", "dut": "nl", "nld": "nl",
+}
+
+// Normalize returns the ISO 639-1 code of lang ("eng", "EN", "en-US" -> "en").
+func Normalize(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if base, _, ok := strings.Cut(lang, "-"); ok {
+		lang = base
+	}
+	if two, ok := iso6392[lang]; ok {
+		return two
+	}
+	return lang
+}
+
+// Majority returns the language most lines are reliably detected as ("" when none is).
+func Majority(lines []string) string {
+	counts := map[string]int{}
+	for _, l := range lines {
+		if lang, ok := Detect(l); ok {
+			counts[lang]++
+		}
+	}
+	best, n := "", 0
+	for _, lang := range Languages {
+		if counts[lang] > n {
+			best, n = lang, counts[lang]
+		}
+	}
+	return best
+}
@@ -0,0 +1,59 @@
+package langdetect
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		text string
+		want string
+		ok   bool
+	}{
+		{"What are you doing here?", "en", true},
+		{"I don't know what to do with it.", "en", true},
+		{"¿Qué estás haciendo aquí?", "es", true},
+		{"No sé lo que quieres de mí.", "es", true},
+		{"Eu não sei o que você quer.", "pt", true},
+		{"Je ne sais pas ce que tu veux.", "fr", true},
+		{"Ich weiß nicht, was du willst.", "de", true},
+		{"Non so cosa vuoi da me, ma va bene.", "it", true},
+		{"Ik weet niet wat je wilt.", "nl", true},
+		{"No.", "", false},
+		{"Okay.", "", false},
+		// Short English lines with words other languages share are not foreign dialogue.
+		{"No, no, no!", "", false},
+		{"Me? No.", "", false},
+		{"Oh no, no way", "", false},
+		{"¡Ay!", "", false},
+	}
+	for _, tc := range tests {
+		got, ok := Detect(tc.text)
+		if ok != tc.ok || (ok && got != tc.want) {
+			t.Fatalf("Detect(%q) = %q, %v; want %q, %v", tc.text, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]string{"eng": "en", "EN": "en", "pt-BR": "pt", "ger": "de", "jpn": "jpn", "": ""}
+	for in, want := range tests {
+		if got := Normalize(in); got != want {
+			t.Fatalf("Normalize(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMajority(t *testing.T) {
+	t.Parallel()
+
+	lines := []string{"What are you doing here?", "¿Qué estás haciendo aquí?", "I don't know.", "It's not the time for this.", "Ok."}
+	if got := Majority(lines); got != "en" {
+		t.Fatalf("Majority = %q, want en", got)
+	}
+	if got := Majority([]string{"Ok.", "Hm."}); got != "" {
+		t.Fatalf("Majority = %q, want empty", got)
+	}
+}
@@ -0,0 +1,229 @@
+// Package lint checks parsed subtitle documents against quality-control thresholds
+// (reading speed, line length, timing) without changing them.
+package lint
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+// Severity of a lint issue. Errors fail CI runs; warnings only fail with --strict.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Check identifies one lint rule.
+type Check string
+
+const (
+	CheckReadingSpeed   Check = "reading-speed"
+	CheckLineLength     Check = "line-length"
+	CheckLineCount      Check = "line-count"
+	CheckMinDuration    Check = "min-duration"
+	CheckMaxDuration    Check = "max-duration"
+	CheckOverlap        Check = "overlap"
+	CheckGap            Check = "gap"
+	CheckEmptyCue       Check = "empty-cue"
+	CheckUnbalancedTags Check = "unbalanced-tags"
+	CheckHIMarker       Check = "hi-marker"
+	CheckDuplicate      Check = "duplicate"
+	CheckParse          Check = "parse"
+)
+
+// AllChecks lists every check in report order (JUnit emits one test case per entry).
+var AllChecks = []Check{
+	CheckReadingSpeed,
+	CheckLineLength,
+	CheckLineCount,
+	CheckMinDuration,
+	CheckMaxDuration,
+	CheckOverlap,
+	CheckGap,
+	CheckEmptyCue,
+	CheckUnbalancedTags,
+	CheckHIMarker,
+	CheckDuplicate,
+	CheckParse,
+}
+
+// Issue is one finding on a cue.
+type Issue struct {
+	CueIndex int      `json:"cueIndex"`
+	Start    string   `json:"start"`
+	Check    Check    `json:"check"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+var (
+	reSRTTag    = regexp.MustCompile(`<[^>]*>`)
+	reASSTag    = regexp.MustCompile(`{\\[^}]*}`)
+	reFormatTag = regexp.MustCompile(`</?([biu])>`)
+)
+
+// Document runs every check on doc and returns issues ordered by cue, then check.
+func Document(doc model.Document, th Thresholds) []Issue {
+	var issues []Issue
+	var prev *model.Cue
+	seen := make(map[string]int, len(doc.Cues))
+
+	for _, cue := range doc.Cues {
+		add := func(check Check, sev Severity, format string, a ...any) {
+			issues = append(issues, Issue{
+				CueIndex: cue.Index,
+				Start:    subtitle.FormatTimecode(cue.Start),
+				Check:    check,
+				Severity: sev,
+				Message:  fmt.Sprintf(format, a...),
+			})
+		}
+
+		text := plainText(cue.Lines)
+		lines := nonEmptyLines(text)
+		duration := cue.End - cue.Start
+
+		if len(lines) == 0 {
+			add(CheckEmptyCue, SeverityError, "cue has no text")
+		}
+
+		if th.MaxCPS > 0 && duration > 0 && len(lines) > 0 {
+			chars := utf8.RuneCountInString(strings.Join(lines, ""))
+			cps := float64(chars) / duration.Seconds()
+			if cps > th.MaxCPS {
+				add(CheckReadingSpeed, SeverityWarning, "reading speed %.1f cps exceeds %.1f", cps, th.MaxCPS)
+			}
+		}
+
+		if th.MaxCPL > 0 {
+			for n, line := range lines {
+				if cpl := utf8.RuneCountInString(line); cpl > th.MaxCPL {
+					add(CheckLineLength, SeverityWarning, "line %d has %d characters (max %d)", n+1, cpl, th.MaxCPL)
+				}
+			}
+		}
+
+		if th.MaxLines > 0 && len(lines) > th.MaxLines {
+			add(CheckLineCount, SeverityWarning, "cue has %d lines (max %d)", len(lines), th.MaxLines)
+		}
+
+		if duration <= 0 {
+			add(CheckMinDuration, SeverityError, "end %s is not after start %s", subtitle.FormatTimecode(cue.End), subtitle.FormatTimecode(cue.Start))
+		} else if th.MinDuration > 0 && duration < th.MinDuration {
+			add(CheckMinDuration, SeverityWarning, "duration %s is below %s", formatDuration(duration), formatDuration(th.MinDuration))
+		}
+		if th.MaxDuration > 0 && duration > th.MaxDuration {
+			add(CheckMaxDuration, SeverityWarning, "duration %s exceeds %s", formatDuration(duration), formatDuration(th.MaxDuration))
+		}
+
+		if prev != nil {
+			gap := cue.Start - prev.End
+			switch {
+			case gap < 0:
+				add(CheckOverlap, SeverityError, "overlaps cue %d by %s", prev.Index, formatDuration(-gap))
+			case th.MinGap > 0 && gap < th.MinGap:
+				add(CheckGap, SeverityWarning, "gap of %s after cue %d is below %s", formatDuration(gap), prev.Index, formatDuration(th.MinGap))
+			}
+		}
+
+		if msg := unbalancedTags(cue.Lines); msg != "" {
+			add(CheckUnbalancedTags, SeverityError, "%s", msg)
+		}
+
+		if marker := hi.Marker(text); marker != "" {
+			add(CheckHIMarker, SeverityWarning, "leftover hearing-impaired marker %q", marker)
+		}
+
+		if len(lines) > 0 {
+			key := fmt.Sprintf("%d|%d|%s", cue.Start, cue.End, strings.Join(lines, "\n"))
+			if first, ok := seen[key]; ok {
+				add(CheckDuplicate, SeverityError, "duplicate of cue %d", first)
+			} else {
+				seen[key] = cue.Index
+			}
+		}
+
+		prev = cue
+	}
+	return issues
+}
+
+// ParseIssues converts parser diagnostics into issues; info diagnostics are dropped.
+func ParseIssues(diags []subtitle.Diagnostic) []Issue {
+	var issues []Issue
+	for _, d := range diags {
+		sev := SeverityWarning
+		switch d.Severity {
+		case subtitle.SeverityInfo:
+			continue
+		case subtitle.SeverityError:
+			sev = SeverityError
+		}
+		issues = append(issues, Issue{
+			Check:    CheckParse,
+			Severity: sev,
+			Message:  fmt.Sprintf("line %d: %s", d.Line, d.Message),
+		})
+	}
+	return issues
+}
+
+// HasErrors reports whether issues should fail a run (warnings count when strict).
+func HasErrors(issues []Issue, strict bool) bool {
+	for _, is := range issues {
+		if is.Severity == SeverityError || strict {
+			return true
+		}
+	}
+	return false
+}
+
+// plainText strips SRT/ASS formatting tags and converts ASS line breaks.
+func plainText(s string) string {
+	s = strings.ReplaceAll(s, `\N`, "\n")
+	s = reASSTag.ReplaceAllString(s, "")
+	return reSRTTag.ReplaceAllString(s, "")
+}
+
+func nonEmptyLines(s string) []string {
+	var out []string
+	for line := range strings.SplitSeq(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// unbalancedTags reports the first mismatched <i>/<b>/<u> pair, or "" when balanced.
+func unbalancedTags(s string) string {
+	var stack []string
+	for _, m := range reFormatTag.FindAllStringSubmatch(s, -1) {
+		tag := m[1]
+		if !strings.HasPrefix(m[0], "</") {
+			stack = append(stack, tag)
+			continue
+		}
+		if len(stack) == 0 || stack[len(stack)-1] != tag {
+			return fmt.Sprintf("closing </%s> without matching <%s>", tag, tag)
+		}
+		stack = stack[:len(stack)-1]
+	}
+	if len(stack) > 0 {
+		return fmt.Sprintf("<%s> is never closed", stack[len(stack)-1])
+	}
+	return ""
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
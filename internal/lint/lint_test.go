@@ -0,0 +1,187 @@
+package lint
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+)
+
+func cue(index int, start, end time.Duration, lines string) *model.Cue {
+	return &model.Cue{Index: index, Start: start, End: end, Lines: lines}
+}
+
+func checksOf(issues []Issue) map[Check]int {
+	out := map[Check]int{}
+	for _, is := range issues {
+		out[is.Check]++
+	}
+	return out
+}
+
+func TestDocument_cleanDocumentHasNoIssues(t *testing.T) {
+	doc := model.Document{Cues: []*model.Cue{
+		cue(1, 1*time.Second, 3*time.Second, "Hello there."),
+		cue(2, 3500*time.Millisecond, 6*time.Second, "<i>How are you?</i>"),
+	}}
+	th, _ := Preset("netflix")
+	if issues := Document(doc, th); len(issues) != 0 {
+		t.Fatalf("want no issues, got %+v", issues)
+	}
+}
+
+func TestDocument_checks(t *testing.T) {
+	th, _ := Preset("netflix")
+	tests := []struct {
+		name string
+		cues []*model.Cue
+		want Check
+	}{
+		{"reading speed", []*model.Cue{cue(1, 0, time.Second, "This line is far too long to read in one second")}, CheckReadingSpeed},
+		{"line length", []*model.Cue{cue(1, 0, 7*time.Second, "This single line is definitely longer than forty-two characters")}, CheckLineLength},
+		{"line count", []*model.Cue{cue(1, 0, 5*time.Second, "one\ntwo\nthree")}, CheckLineCount},
+		{"min duration", []*model.Cue{cue(1, 0, 300*time.Millisecond, "Hi")}, CheckMinDuration},
+		{"inverted timing", []*model.Cue{cue(1, 2*time.Second, time.Second, "Hi")}, CheckMinDuration},
+		{"max duration", []*model.Cue{cue(1, 0, 9*time.Second, "Hi")}, CheckMaxDuration},
+		{"overlap", []*model.Cue{cue(1, 0, 2*time.Second, "Hi"), cue(2, 1500*time.Millisecond, 3*time.Second, "Bye")}, CheckOverlap},
+		{"gap", []*model.Cue{cue(1, 0, 2*time.Second, "Hi"), cue(2, 2010*time.Millisecond, 4*time.Second, "Bye")}, CheckGap},
+		{"empty cue", []*model.Cue{cue(1, 0, 2*time.Second, "  \n<i></i>")}, CheckEmptyCue},
+		{"unclosed italic", []*model.Cue{cue(1, 0, 2*time.Second, "<i>Hello")}, CheckUnbalancedTags},
+		{"stray close", []*model.Cue{cue(1, 0, 2*time.Second, "Hello</i>")}, CheckUnbalancedTags},
+		{"brackets", []*model.Cue{cue(1, 0, 2*time.Second, "[door slams] Hi")}, CheckHIMarker},
+		{"speaker", []*model.Cue{cue(1, 0, 2*time.Second, "JOHN: Hi")}, CheckHIMarker},
+		{"music", []*model.Cue{cue(1, 0, 2*time.Second, "♪ la la ♪")}, CheckHIMarker},
+		{"duplicate", []*model.Cue{cue(1, 0, 2*time.Second, "Hi"), cue(2, 0, 2*time.Second, "Hi")}, CheckDuplicate},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := checksOf(Document(model.Document{Cues: tt.cues}, th))
+			if got[tt.want] == 0 {
+				t.Fatalf("want %s issue, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDocument_disabledThresholds(t *testing.T) {
+	doc := model.Document{Cues: []*model.Cue{
+		cue(1, 0, 100*time.Millisecond, "A very long line that is read way too fast\ntwo\nthree"),
+	}}
+	got := checksOf(Document(doc, Thresholds{}))
+	for _, c := range []Check{CheckReadingSpeed, CheckLineLength, CheckLineCount, CheckMinDuration, CheckGap} {
+		if got[c] != 0 {
+			t.Fatalf("%s should be disabled with zero thresholds, got %v", c, got)
+		}
+	}
+}
+
+func TestParseIssues(t *testing.T) {
+	issues := ParseIssues([]subtitle.Diagnostic{
+		{Line: 3, Severity: subtitle.SeverityInfo, Message: "cue without index line"},
+		{Line: 7, Severity: subtitle.SeverityWarning, Message: "non-standard timestamp"},
+		{Line: 9, Severity: subtitle.SeverityError, Message: "invalid timing line"},
+	})
+	if len(issues) != 2 {
+		t.Fatalf("info diagnostics should be dropped: %+v", issues)
+	}
+	if issues[0].Severity != SeverityWarning || issues[1].Severity != SeverityError || issues[1].Message != "line 9: invalid timing line" {
+		t.Fatalf("issues: %+v", issues)
+	}
+}
+
+func TestHasErrors(t *testing.T) {
+	warn := []Issue{{Severity: SeverityWarning}}
+	if HasErrors(warn, false) {
+		t.Fatal("warnings alone should not fail")
+	}
+	if !HasErrors(warn, true) {
+		t.Fatal("warnings should fail when strict")
+	}
+	if !HasErrors([]Issue{{Severity: SeverityError}}, false) {
+		t.Fatal("errors should fail")
+	}
+}
+
+func TestThresholdsFromConfig(t *testing.T) {
+	th, err := ThresholdsFromConfig(nil, "")
+	if err != nil || th.MaxCPL != 42 {
+		t.Fatalf("default preset: %+v, %v", th, err)
+	}
+	conf := &rules.LintConfig{Preset: "bbc", MaxCPL: 30, MinGapMs: 120}
+	th, err = ThresholdsFromConfig(conf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.MaxCPL != 30 || th.MinGap != 120*time.Millisecond || th.MaxCPS != 17 {
+		t.Fatalf("overrides not applied on bbc preset: %+v", th)
+	}
+	th, err = ThresholdsFromConfig(conf, "NETFLIX")
+	if err != nil || th.MaxCPS != 20 || th.MaxCPL != 30 {
+		t.Fatalf("CLI preset should replace config preset but keep overrides: %+v, %v", th, err)
+	}
+	if _, err := ThresholdsFromConfig(nil, "nope"); err == nil {
+		t.Fatal("expected unknown preset error")
+	}
+}
+
+func sampleReports() []FileReport {
+	return []FileReport{
+		{Path: "a.srt", Cues: 2, Issues: []Issue{
+			{CueIndex: 1, Start: "00:00:01,000", Check: CheckOverlap, Severity: SeverityError, Message: "overlaps"},
+			{CueIndex: 2, Start: "00:00:02,000", Check: CheckGap, Severity: SeverityWarning, Message: "gap"},
+		}},
+		{Path: "b.srt", Error: "no cues found"},
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteText(&buf, sampleReports()); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	for _, sub := range []string{"a.srt:1 [00:00:01,000] error overlap: overlaps", "b.srt: error: no cues found", "2 files, 2 errors, 1 warnings"} {
+		if !strings.Contains(s, sub) {
+			t.Fatalf("text report missing %q in:\n%s", sub, s)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, sampleReports()); err != nil {
+		t.Fatal(err)
+	}
+	var got []FileReport
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Issues[0].Check != CheckOverlap || got[1].Error == "" {
+		t.Fatalf("round trip mismatch: %+v", got)
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, sampleReports(), false); err != nil {
+		t.Fatal(err)
+	}
+	s := buf.String()
+	wantTests := len(AllChecks) + 1
+	for _, sub := range []string{
+		`<testsuites tests="` + strconv.Itoa(wantTests) + `" failures="2">`,
+		`<testcase name="overlap" classname="a.srt">`,
+		`<failure message="no cues found" type="parse">`,
+		`<system-out>cue 2 [00:00:02,000] warning: gap`,
+	} {
+		if !strings.Contains(s, sub) {
+			t.Fatalf("junit report missing %q in:\n%s", sub, s)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package lint
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FileReport groups the issues found in one input file.
+type FileReport struct {
+	Path   string  `json:"path"`
+	Cues   int     `json:"cues"`
+	Error  string  `json:"error,omitempty"` // read/parse failure; Issues is empty when set
+	Issues []Issue `json:"issues"`
+}
+
+// Counts returns the number of error and warning issues (a read/parse failure counts as an error).
+func (r FileReport) Counts() (errors, warnings int) {
+	if r.Error != "" {
+		errors++
+	}
+	for _, is := range r.Issues {
+		if is.Severity == SeverityError {
+			errors++
+		} else {
+			warnings++
+		}
+	}
+	return errors, warnings
+}
+
+// WriteText renders a human-readable report, one line per issue.
+func WriteText(w io.Writer, reports []FileReport) error {
+	var totalErr, totalWarn int
+	for _, r := range reports {
+		errs, warns := r.Counts()
+		totalErr += errs
+		totalWarn += warns
+		if r.Error != "" {
+			if _, err := fmt.Fprintf(w, "%s: error: %s\n", r.Path, r.Error); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, is := range r.Issues {
+			if _, err := fmt.Fprintf(w, "%s:%d [%s] %s %s: %s\n",
+				r.Path, is.CueIndex, is.Start, is.Severity, is.Check, is.Message); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintf(w, "%s: %d cues, %d errors, %d warnings\n", r.Path, r.Cues, errs, warns); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d files, %d errors, %d warnings\n", len(reports), totalErr, totalWarn)
+	return err
+}
+
+// WriteJSON renders reports as an indented JSON array.
+func WriteJSON(w io.Writer, reports []FileReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(reports)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit renders one test suite per file and one test case per check.
+// Checks with error issues (or any issue when strict) fail; the rest are listed in system-out.
+func WriteJUnit(w io.Writer, reports []FileReport, strict bool) error {
+	doc := junitTestSuites{}
+	for _, r := range reports {
+		suite := junitTestSuite{Name: r.Path}
+		if r.Error != "" {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "parse",
+				ClassName: r.Path,
+				Failure:   &junitFailure{Message: r.Error, Type: "parse"},
+			})
+		} else {
+			byCheck := make(map[Check][]Issue, len(AllChecks))
+			for _, is := range r.Issues {
+				byCheck[is.Check] = append(byCheck[is.Check], is)
+			}
+			for _, check := range AllChecks {
+				tc := junitTestCase{Name: string(check), ClassName: r.Path}
+				issues := byCheck[check]
+				if len(issues) > 0 {
+					var body strings.Builder
+					for _, is := range issues {
+						fmt.Fprintf(&body, "cue %d [%s] %s: %s\n", is.CueIndex, is.Start, is.Severity, is.Message)
+					}
+					if HasErrors(issues, strict) {
+						tc.Failure = &junitFailure{
+							Message: fmt.Sprintf("%d %s issues", len(issues), check),
+							Type:    string(check),
+							Body:    body.String(),
+						}
+					} else {
+						tc.SystemOut = body.String()
+					}
+				}
+				suite.Cases = append(suite.Cases, tc)
+			}
+		}
+		for _, tc := range suite.Cases {
+			suite.Tests++
+			if tc.Failure != nil {
+				suite.Failures++
+			}
+		}
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Suites = append(doc.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,102 @@
+package lint
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+)
+
+// DefaultPreset is used when neither the CLI nor config names a preset.
+const DefaultPreset = "netflix"
+
+// Thresholds are the limits checked by Document. Zero disables a limit.
+type Thresholds struct {
+	MaxCPS      float64
+	MaxCPL      int
+	MaxLines    int
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	MinGap      time.Duration
+}
+
+// presets are style-guide inspired starting points (English, adult programs).
+var presets = map[string]Thresholds{
+	// Netflix Timed Text Style Guide: 20 cps, 42 cpl, 5/6 s minimum, 7 s maximum, 2 frames gap.
+	"netflix": {
+		MaxCPS:      20,
+		MaxCPL:      42,
+		MaxLines:    2,
+		MinDuration: 833 * time.Millisecond,
+		MaxDuration: 7 * time.Second,
+		MinGap:      83 * time.Millisecond,
+	},
+	// BBC Subtitle Guidelines: ~160-180 wpm, 37 characters per line, 1 s minimum.
+	"bbc": {
+		MaxCPS:      17,
+		MaxCPL:      37,
+		MaxLines:    2,
+		MinDuration: 1 * time.Second,
+		MaxDuration: 8 * time.Second,
+		MinGap:      40 * time.Millisecond,
+	},
+}
+
+// PresetNames returns the built-in preset names, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Preset returns the named thresholds (case-insensitive).
+func Preset(name string) (Thresholds, error) {
+	th, ok := presets[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Thresholds{}, fmt.Errorf("unknown lint preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	return th, nil
+}
+
+// ThresholdsFromConfig resolves presetOverride (CLI), then conf.Preset, then DefaultPreset,
+// and applies every non-zero value from conf on top.
+func ThresholdsFromConfig(conf *rules.LintConfig, presetOverride string) (Thresholds, error) {
+	name := DefaultPreset
+	if conf != nil && conf.Preset != "" {
+		name = conf.Preset
+	}
+	if presetOverride != "" {
+		name = presetOverride
+	}
+	th, err := Preset(name)
+	if err != nil {
+		return Thresholds{}, err
+	}
+	if conf == nil {
+		return th, nil
+	}
+	if conf.MaxCPS > 0 {
+		th.MaxCPS = conf.MaxCPS
+	}
+	if conf.MaxCPL > 0 {
+		th.MaxCPL = conf.MaxCPL
+	}
+	if conf.MaxLines > 0 {
+		th.MaxLines = conf.MaxLines
+	}
+	if conf.MinDurationMs > 0 {
+		th.MinDuration = time.Duration(conf.MinDurationMs) * time.Millisecond
+	}
+	if conf.MaxDurationMs > 0 {
+		th.MaxDuration = time.Duration(conf.MaxDurationMs) * time.Millisecond
+	}
+	if conf.MinGapMs > 0 {
+		th.MinGap = time.Duration(conf.MinGapMs) * time.Millisecond
+	}
+	return th, nil
+}
@@ -0,0 +1,264 @@
+// Package matroska is a minimal pure-Go Matroska/WebM demuxer for subtitle tracks: it
+// reads the EBML header, Info, Tracks and Tags, and walks Clusters for the blocks of the
+// requested tracks. It does not depend on os or os/exec, so the WASM builds can use it.
+package matroska
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Element IDs (with their length marker bits, as written in the file).
+const (
+	idEBML    = 0x1A45DFA3
+	idDocType = 0x4282
+
+	idSegment     = 0x18538067
+	idSeekHead    = 0x114D9B74
+	idSeek        = 0x4DBB
+	idSeekID      = 0x53AB
+	idSeekPos     = 0x53AC
+	idInfo        = 0x1549A966
+	idTimecodeSc  = 0x2AD7B1
+	idTracks      = 0x1654AE6B
+	idCluster     = 0x1F43B675
+	idCues        = 0x1C53BB6B
+	idTags        = 0x1254C367
+	idChapters    = 0x1043A770
+	idAttachments = 0x1941A469
+
+	idTrackEntry     = 0xAE
+	idTrackNumber    = 0xD7
+	idTrackUID       = 0x73C5
+	idTrackType      = 0x83
+	idCodecID        = 0x86
+	idCodecPrivate   = 0x63A2
+	idLanguage       = 0x22B59C
+	idLanguageBCP47  = 0x22B59D
+	idName           = 0x536E
+	idFlagDefault    = 0x88
+	idFlagForced     = 0x55AA
+	idFlagHearingImp = 0x55AB
+	idDefaultDur     = 0x23E383
+
+	idContentEncodings  = 0x6D80
+	idContentEncoding   = 0x6240
+	idContentScope      = 0x5032
+	idContentType       = 0x5033
+	idContentCompr      = 0x5034
+	idContentCompAlgo   = 0x4254
+	idContentCompSettng = 0x4255
+
+	idTimecode      = 0xE7
+	idSimpleBlock   = 0xA3
+	idBlockGroup    = 0xA0
+	idBlock         = 0xA1
+	idBlockDuration = 0x9B
+
+	idTag         = 0x7373
+	idTargets     = 0x63C0
+	idTagTrackUID = 0x63C5
+	idSimpleTag   = 0x67C8
+	idTagName     = 0x45A3
+	idTagString   = 0x4487
+)
+
+// unknownSize marks an element whose size field is all ones (live streams).
+const unknownSize = -1
+
+var errNotMatroska = errors.New("not a matroska/webm file")
+
+// element is one EBML element header: its ID, where its data starts and how long it is.
+type element struct {
+	id      uint32
+	off     int64 // header offset
+	dataOff int64
+	size    int64 // unknownSize when not set
+}
+
+func (e element) end() int64 { return e.dataOff + e.size }
+
+// reader wraps an io.ReaderAt with a small window cache: walking clusters reads many
+// tiny element headers close to each other.
+type reader struct {
+	r      io.ReaderAt
+	size   int64
+	buf    []byte
+	bufOff int64
+	bufLen int
+}
+
+const readerWindow = 64 << 10
+
+func newReader(r io.ReaderAt, size int64) *reader {
+	return &reader{r: r, size: size, buf: make([]byte, readerWindow), bufOff: -1}
+}
+
+// peek returns up to n bytes at off (fewer at the end of the file). The slice is only
+// valid until the next call.
+func (r *reader) peek(off int64, n int) ([]byte, error) {
+	if n < 0 || off < 0 {
+		return nil, fmt.Errorf("invalid read of %d bytes at %d", n, off)
+	}
+	if off >= r.size {
+		return nil, io.EOF
+	}
+	n = int(min(int64(n), r.size-off))
+	if n > len(r.buf) {
+		out := make([]byte, n)
+		if _, err := r.r.ReadAt(out, off); err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		return out, nil
+	}
+	if r.bufOff < 0 || off < r.bufOff || off+int64(n) > r.bufOff+int64(r.bufLen) {
+		got, err := r.r.ReadAt(r.buf, off)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		r.bufOff, r.bufLen = off, got
+		if got < n {
+			return nil, io.ErrUnexpectedEOF
+		}
+	}
+	start := int(off - r.bufOff)
+	return r.buf[start : start+n], nil
+}
+
+// read returns a copy of n bytes at off.
+func (r *reader) read(off int64, n int64) ([]byte, error) {
+	if n < 0 || off < 0 {
+		return nil, fmt.Errorf("invalid read of %d bytes at %d", n, off)
+	}
+	if off+n > r.size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	b, err := r.peek(off, int(n))
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), b...), nil
+}
+
+// element reads the element header at off.
+func (r *reader) element(off int64) (element, error) {
+	b, err := r.peek(off, 12)
+	if err != nil {
+		return element{}, err
+	}
+	id, idLen, err := vint(b, true)
+	if err != nil {
+		return element{}, fmt.Errorf("element id at %d: %w", off, err)
+	}
+	size, sizeLen, err := vint(b[idLen:], false)
+	if err != nil {
+		return element{}, fmt.Errorf("element size at %d: %w", off, err)
+	}
+	e := element{id: uint32(id), off: off, dataOff: off + int64(idLen+sizeLen), size: int64(size)}
+	if size == 1<<(7*sizeLen)-1 {
+		e.size = unknownSize
+	} else if size > math.MaxInt64/2 || e.end() > r.size {
+		// Truncated files keep what is there.
+		e.size = r.size - e.dataOff
+	}
+	return e, nil
+}
+
+// vint decodes an EBML variable-length integer. IDs keep their length marker.
+func vint(b []byte, keepMarker bool) (uint64, int, error) {
+	if len(b) == 0 {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	n := 1
+	for mask := byte(0x80); n <= 8 && b[0]&mask == 0; mask >>= 1 {
+		n++
+	}
+	if n > 8 || (keepMarker && n > 4) {
+		return 0, 0, errors.New("invalid variable-length integer")
+	}
+	if len(b) < n {
+		return 0, 0, io.ErrUnexpectedEOF
+	}
+	v := uint64(b[0])
+	if !keepMarker {
+		v &= 0xFF >> n
+	}
+	for _, c := range b[1:n] {
+		v = v<<8 | uint64(c)
+	}
+	return v, n, nil
+}
+
+// children calls fn for every child of parent, up to end for unknown-size parents. For
+// those, a level-1 ID ends the parent (the next Cluster, Cues, ...).
+func (r *reader) children(parent element, end int64, fn func(e element) error) (next int64, err error) {
+	if parent.size != unknownSize {
+		end = parent.end()
+	}
+	off := parent.dataOff
+	for off < end {
+		e, err := r.element(off)
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return end, nil
+		}
+		if err != nil {
+			return off, err
+		}
+		if parent.size == unknownSize && isTopLevel(e.id) {
+			return off, nil
+		}
+		if e.size == unknownSize && e.id != idSegment && e.id != idCluster {
+			// Only Segment and Cluster may have an unknown size; anything else is corrupt.
+			return off, fmt.Errorf("element %X at %d has unknown size", e.id, e.off)
+		}
+		if err := fn(e); err != nil {
+			return off, err
+		}
+		if e.size == unknownSize {
+			// fn read the Segment or Cluster; its end, and so the next sibling, is unknown.
+			return off, fmt.Errorf("element %X at %d has unknown size", e.id, e.off)
+		}
+		off = e.end()
+	}
+	return end, nil
+}
+
+func isTopLevel(id uint32) bool {
+	switch id {
+	case idCluster, idCues, idTags, idChapters, idAttachments, idSeekHead, idInfo, idTracks, idSegment, idEBML:
+		return true
+	}
+	return false
+}
+
+func (r *reader) uint(e element) (uint64, error) {
+	if e.size > 8 {
+		return 0, fmt.Errorf("unsigned integer element %X is %d bytes", e.id, e.size)
+	}
+	b, err := r.peek(e.dataOff, int(e.size))
+	if err != nil {
+		return 0, err
+	}
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v, nil
+}
+
+func (r *reader) string(e element) (string, error) {
+	b, err := r.read(e.dataOff, e.size)
+	if err != nil {
+		return "", err
+	}
+	// Strings may be zero-padded.
+	for len(b) > 0 && b[len(b)-1] == 0 {
+		b = b[:len(b)-1]
+	}
+	return string(b), nil
+}
+
+func int16At(b []byte) int16 { return int16(binary.BigEndian.Uint16(b)) }
@@ -0,0 +1,516 @@
+package matroska
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Track types.
+const (
+	TrackTypeVideo    = 0x01
+	TrackTypeAudio    = 0x02
+	TrackTypeSubtitle = 0x11
+)
+
+// Track is one TrackEntry. Index is its position among all tracks, which is the stream
+// index ffmpeg and ffprobe use for Matroska.
+type Track struct {
+	Index           int
+	Number          uint64
+	UID             uint64
+	Type            uint64
+	CodecID         string // S_TEXT/UTF8, S_TEXT/ASS, S_HDMV/PGS, ...
+	CodecPrivate    []byte
+	Language        string // ISO 639-2 ("eng" when unset)
+	LanguageBCP47   string
+	Name            string
+	Default         bool
+	Forced          bool
+	HearingImpaired bool
+	DefaultDuration time.Duration
+	Frames          int // NUMBER_OF_FRAMES statistics tag; -1 when absent
+
+	compression *compression
+}
+
+// compression is the ContentCompression of a track (zlib or header stripping).
+type compression struct {
+	algo     uint64
+	settings []byte
+	private  bool // CodecPrivate is compressed too
+}
+
+// Block is one frame of a track with its absolute timestamp.
+type Block struct {
+	Track    uint64
+	Time     time.Duration
+	Duration time.Duration // 0 when the block has none
+	Data     []byte
+}
+
+// File is an opened Matroska/WebM segment.
+type File struct {
+	r             *reader
+	DocType       string
+	TimecodeScale uint64 // nanoseconds per timestamp tick
+	Tracks        []Track
+
+	segment      element
+	segmentEnd   int64
+	firstCluster int64 // -1 when the segment has no clusters
+}
+
+// IsMatroska reports whether data starts with an EBML header (Matroska, WebM).
+func IsMatroska(data []byte) bool {
+	return len(data) >= 4 && binary.BigEndian.Uint32(data) == idEBML
+}
+
+// Open reads the headers of the Matroska/WebM file in r: EBML header, Info, Tracks and
+// Tags (following the SeekHead when they come after the clusters).
+func Open(r io.ReaderAt, size int64) (*File, error) {
+	f := &File{r: newReader(r, size), TimecodeScale: 1000000, firstCluster: -1}
+	head, err := f.r.element(0)
+	if err != nil || head.id != idEBML {
+		return nil, errNotMatroska
+	}
+	if _, err := f.r.children(head, size, func(e element) error {
+		if e.id == idDocType {
+			f.DocType, err = f.r.string(e)
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if f.DocType != "matroska" && f.DocType != "webm" {
+		return nil, fmt.Errorf("%w (doctype %q)", errNotMatroska, f.DocType)
+	}
+
+	f.segment, err = f.r.element(head.end())
+	if err != nil || f.segment.id != idSegment {
+		return nil, errors.New("matroska: missing segment")
+	}
+	f.segmentEnd = size
+	if f.segment.size != unknownSize {
+		f.segmentEnd = f.segment.end()
+	}
+
+	seeks := map[uint32]int64{}
+	seen := map[uint32]bool{}
+	var tagFrames map[uint64]int
+	visit := func(e element) error {
+		if seen[e.id] {
+			return nil
+		}
+		seen[e.id] = true
+		switch e.id {
+		case idSeekHead:
+			return f.readSeekHead(e, seeks)
+		case idInfo:
+			return f.readInfo(e)
+		case idTracks:
+			return f.readTracks(e)
+		case idTags:
+			tagFrames, err = f.readTags(e)
+			return err
+		}
+		return nil
+	}
+
+	// Level-1 elements up to the first cluster; Tracks and Tags found later via SeekHead.
+	for off := f.segment.dataOff; off < f.segmentEnd; {
+		e, err := f.r.element(off)
+		if err != nil {
+			break
+		}
+		if e.id == idCluster {
+			f.firstCluster = e.off
+			break
+		}
+		if err := visit(e); err != nil {
+			return nil, err
+		}
+		if e.size == unknownSize {
+			break
+		}
+		off = e.end()
+	}
+	for _, id := range []uint32{idInfo, idTracks, idTags} {
+		pos, ok := seeks[id]
+		if !ok || seen[id] {
+			continue
+		}
+		e, err := f.r.element(f.segment.dataOff + pos)
+		if err != nil || e.id != id {
+			continue // stale SeekHead entries are not fatal
+		}
+		if err := visit(e); err != nil {
+			return nil, err
+		}
+	}
+	if !seen[idTracks] {
+		return nil, errors.New("matroska: no Tracks element")
+	}
+	for i := range f.Tracks {
+		f.Tracks[i].Frames = -1
+		if n, ok := tagFrames[f.Tracks[i].UID]; ok {
+			f.Tracks[i].Frames = n
+		}
+	}
+	return f, nil
+}
+
+func (f *File) readSeekHead(head element, seeks map[uint32]int64) error {
+	_, err := f.r.children(head, f.segmentEnd, func(seek element) error {
+		if seek.id != idSeek {
+			return nil
+		}
+		var id uint32
+		var pos int64 = -1
+		_, err := f.r.children(seek, f.segmentEnd, func(e element) error {
+			switch e.id {
+			case idSeekID:
+				b, err := f.r.read(e.dataOff, e.size)
+				for _, c := range b {
+					id = id<<8 | uint32(c)
+				}
+				return err
+			case idSeekPos:
+				v, err := f.r.uint(e)
+				pos = int64(v)
+				return err
+			}
+			return nil
+		})
+		if err == nil && pos >= 0 {
+			if _, dup := seeks[id]; !dup {
+				seeks[id] = pos
+			}
+		}
+		return err
+	})
+	return err
+}
+
+func (f *File) readInfo(info element) error {
+	_, err := f.r.children(info, f.segmentEnd, func(e element) error {
+		if e.id == idTimecodeSc {
+			v, err := f.r.uint(e)
+			if err == nil && v > 0 {
+				f.TimecodeScale = v
+			}
+			return err
+		}
+		return nil
+	})
+	return err
+}
+
+func (f *File) readTracks(tracks element) error {
+	_, err := f.r.children(tracks, f.segmentEnd, func(entry element) error {
+		if entry.id != idTrackEntry {
+			return nil
+		}
+		t := Track{Index: len(f.Tracks), Language: "eng", Default: true}
+		_, err := f.r.children(entry, f.segmentEnd, func(e element) error {
+			var err error
+			var v uint64
+			switch e.id {
+			case idTrackNumber:
+				t.Number, err = f.r.uint(e)
+			case idTrackUID:
+				t.UID, err = f.r.uint(e)
+			case idTrackType:
+				t.Type, err = f.r.uint(e)
+			case idCodecID:
+				t.CodecID, err = f.r.string(e)
+			case idCodecPrivate:
+				t.CodecPrivate, err = f.r.read(e.dataOff, e.size)
+			case idLanguage:
+				t.Language, err = f.r.string(e)
+			case idLanguageBCP47:
+				t.LanguageBCP47, err = f.r.string(e)
+			case idName:
+				t.Name, err = f.r.string(e)
+			case idFlagDefault:
+				v, err = f.r.uint(e)
+				t.Default = v != 0
+			case idFlagForced:
+				v, err = f.r.uint(e)
+				t.Forced = v != 0
+			case idFlagHearingImp:
+				v, err = f.r.uint(e)
+				t.HearingImpaired = v != 0
+			case idDefaultDur:
+				v, err = f.r.uint(e)
+				t.DefaultDuration = time.Duration(v)
+			case idContentEncodings:
+				t.compression, err = f.readEncodings(e)
+			}
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("matroska: track %d: %w", t.Index, err)
+		}
+		if t.compression != nil && t.compression.private {
+			if t.CodecPrivate, err = t.compression.decode(t.CodecPrivate); err != nil {
+				return fmt.Errorf("matroska: track %d codec private: %w", t.Index, err)
+			}
+		}
+		f.Tracks = append(f.Tracks, t)
+		return nil
+	})
+	return err
+}
+
+func (f *File) readEncodings(encodings element) (*compression, error) {
+	var c *compression
+	_, err := f.r.children(encodings, f.segmentEnd, func(enc element) error {
+		if enc.id != idContentEncoding {
+			return nil
+		}
+		scope, kind := uint64(1), uint64(0)
+		comp := &compression{}
+		_, err := f.r.children(enc, f.segmentEnd, func(e element) error {
+			var err error
+			switch e.id {
+			case idContentScope:
+				scope, err = f.r.uint(e)
+			case idContentType:
+				kind, err = f.r.uint(e)
+			case idContentCompr:
+				_, err = f.r.children(e, f.segmentEnd, func(ce element) error {
+					var err error
+					switch ce.id {
+					case idContentCompAlgo:
+						comp.algo, err = f.r.uint(ce)
+					case idContentCompSettng:
+						comp.settings, err = f.r.read(ce.dataOff, ce.size)
+					}
+					return err
+				})
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if kind != 0 {
+			return errors.New("encrypted tracks are not supported")
+		}
+		if comp.algo != 0 && comp.algo != 3 {
+			return fmt.Errorf("unsupported compression algorithm %d", comp.algo)
+		}
+		comp.private = scope&2 != 0
+		c = comp
+		return nil
+	})
+	return c, err
+}
+
+func (c *compression) decode(data []byte) ([]byte, error) {
+	if c == nil {
+		return data, nil
+	}
+	if c.algo == 3 { // header stripping
+		return append(append([]byte(nil), c.settings...), data...), nil
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("zlib: %w", err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// readTags returns the NUMBER_OF_FRAMES statistics tag of each track UID.
+func (f *File) readTags(tags element) (map[uint64]int, error) {
+	frames := map[uint64]int{}
+	_, err := f.r.children(tags, f.segmentEnd, func(tag element) error {
+		if tag.id != idTag {
+			return nil
+		}
+		var uids []uint64
+		count := -1
+		_, err := f.r.children(tag, f.segmentEnd, func(e element) error {
+			switch e.id {
+			case idTargets:
+				_, err := f.r.children(e, f.segmentEnd, func(te element) error {
+					if te.id == idTagTrackUID {
+						uid, err := f.r.uint(te)
+						uids = append(uids, uid)
+						return err
+					}
+					return nil
+				})
+				return err
+			case idSimpleTag:
+				var name, value string
+				_, err := f.r.children(e, f.segmentEnd, func(se element) error {
+					var err error
+					switch se.id {
+					case idTagName:
+						name, err = f.r.string(se)
+					case idTagString:
+						value, err = f.r.string(se)
+					}
+					return err
+				})
+				if name == "NUMBER_OF_FRAMES" || strings.HasPrefix(name, "NUMBER_OF_FRAMES-") {
+					if n, convErr := strconv.Atoi(strings.TrimSpace(value)); convErr == nil {
+						count = n
+					}
+				}
+				return err
+			}
+			return nil
+		})
+		if count >= 0 {
+			for _, uid := range uids {
+				frames[uid] = count
+			}
+		}
+		return err
+	})
+	return frames, err
+}
+
+// SubtitleTracks returns the subtitle tracks, in file order.
+func (f *File) SubtitleTracks() []Track {
+	var out []Track
+	for _, t := range f.Tracks {
+		if t.Type == TrackTypeSubtitle {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Blocks walks every cluster and returns the (decompressed) blocks of the given track
+// numbers, sorted by time. Blocks without a BlockDuration get the track DefaultDuration.
+func (f *File) Blocks(numbers ...uint64) (map[uint64][]Block, error) {
+	tracks := map[uint64]*Track{}
+	for _, n := range numbers {
+		for i := range f.Tracks {
+			if f.Tracks[i].Number == n {
+				tracks[n] = &f.Tracks[i]
+			}
+		}
+		if tracks[n] == nil {
+			return nil, fmt.Errorf("matroska: no track number %d", n)
+		}
+	}
+	out := map[uint64][]Block{}
+	if f.firstCluster < 0 {
+		return out, nil
+	}
+
+	scale := time.Duration(f.TimecodeScale)
+	for off := f.firstCluster; off < f.segmentEnd; {
+		e, err := f.r.element(off)
+		if err != nil {
+			break // truncated tail
+		}
+		if e.id != idCluster {
+			if e.size == unknownSize {
+				break
+			}
+			off = e.end()
+			continue
+		}
+		var clusterTime int64
+		next, err := f.r.children(e, f.segmentEnd, func(ce element) error {
+			switch ce.id {
+			case idTimecode:
+				v, err := f.r.uint(ce)
+				clusterTime = int64(v)
+				return err
+			case idSimpleBlock:
+				b, ok, err := f.block(ce, tracks)
+				if ok {
+					b.Time = time.Duration(clusterTime+int64(b.Time)) * scale
+					out[b.Track] = append(out[b.Track], b)
+				}
+				return err
+			case idBlockGroup:
+				var b Block
+				var ok bool
+				var duration int64 = -1
+				_, err := f.r.children(ce, f.segmentEnd, func(ge element) error {
+					var err error
+					switch ge.id {
+					case idBlock:
+						b, ok, err = f.block(ge, tracks)
+					case idBlockDuration:
+						var v uint64
+						v, err = f.r.uint(ge)
+						duration = int64(v)
+					}
+					return err
+				})
+				if ok {
+					b.Time = time.Duration(clusterTime+int64(b.Time)) * scale
+					if duration >= 0 {
+						b.Duration = time.Duration(duration) * scale
+					}
+					out[b.Track] = append(out[b.Track], b)
+				}
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("matroska: cluster at %d: %w", e.off, err)
+		}
+		off = next
+	}
+
+	for n, blocks := range out {
+		sort.SliceStable(blocks, func(i, j int) bool { return blocks[i].Time < blocks[j].Time })
+		for i := range blocks {
+			if blocks[i].Duration == 0 {
+				blocks[i].Duration = tracks[n].DefaultDuration
+			}
+		}
+	}
+	return out, nil
+}
+
+// block decodes a (Simple)Block of one of the wanted tracks. Time holds the timestamp
+// relative to the cluster until the caller adds it.
+func (f *File) block(e element, tracks map[uint64]*Track) (Block, bool, error) {
+	head, err := f.r.peek(e.dataOff, int(min(e.size, 11)))
+	if err != nil {
+		return Block{}, false, err
+	}
+	number, n, err := vint(head, false)
+	if err != nil {
+		return Block{}, false, err
+	}
+	track, wanted := tracks[number]
+	if !wanted {
+		return Block{}, false, nil
+	}
+	if len(head) < n+3 {
+		return Block{}, false, io.ErrUnexpectedEOF
+	}
+	rel := int16At(head[n:])
+	if lacing := head[n+2] >> 1 & 3; lacing != 0 {
+		return Block{}, false, fmt.Errorf("laced block in track %d is not supported", number)
+	}
+	data, err := f.r.read(e.dataOff+int64(n+3), e.size-int64(n+3))
+	if err != nil {
+		return Block{}, false, err
+	}
+	if data, err = track.compression.decode(data); err != nil {
+		return Block{}, false, err
+	}
+	return Block{Track: number, Time: time.Duration(rel), Data: data}, true, nil
+}
@@ -0,0 +1,227 @@
+package matroska
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// el encodes an EBML element; payload parts are concatenated.
+func el(id uint32, parts ...[]byte) []byte {
+	data := bytes.Join(parts, nil)
+	var idb []byte
+	for v := id; v > 0; v >>= 8 {
+		idb = append([]byte{byte(v)}, idb...)
+	}
+	return append(append(idb, size(len(data))...), data...)
+}
+
+// size encodes a data size as an 8-byte EBML vint.
+func size(n int) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(n))
+	b[0] = 0x01
+	return b
+}
+
+func uintEl(id uint32, v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return el(id, b)
+}
+
+func strEl(id uint32, s string) []byte { return el(id, []byte(s)) }
+
+func blockData(track uint64, rel int16, payload string) []byte {
+	b := []byte{0x80 | byte(track), byte(uint16(rel) >> 8), byte(rel), 0x80}
+	return append(b, payload...)
+}
+
+func zlibBytes(s string) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write([]byte(s))
+	w.Close()
+	return buf.Bytes()
+}
+
+const assPrivate = "[Script Info]\nScriptType: v4.00+\n\n[V4+ Styles]\nFormat: Name, Fontname\nStyle: Default,Arial\n"
+
+func testFile(unknownCluster bool) []byte {
+	header := el(idEBML, strEl(idDocType, "matroska"))
+	tracks := el(idTracks,
+		el(idTrackEntry, uintEl(idTrackNumber, 1), uintEl(idTrackUID, 101), uintEl(idTrackType, TrackTypeVideo), strEl(idCodecID, "V_MPEG4/ISO/AVC")),
+		el(idTrackEntry, uintEl(idTrackNumber, 2), uintEl(idTrackUID, 102), uintEl(idTrackType, TrackTypeSubtitle),
+			strEl(idCodecID, "S_TEXT/UTF8"), strEl(idLanguage, "spa"), strEl(idName, "SDH"), uintEl(idFlagDefault, 0), uintEl(idFlagHearingImp, 1)),
+		el(idTrackEntry, uintEl(idTrackNumber, 3), uintEl(idTrackUID, 103), uintEl(idTrackType, TrackTypeSubtitle),
+			strEl(idCodecID, "S_TEXT/ASS"), el(idCodecPrivate, []byte(assPrivate)), strEl(idName, "Signs"), uintEl(idFlagForced, 1),
+			el(idContentEncodings, el(idContentEncoding, el(idContentCompr, uintEl(idContentCompAlgo, 0))))),
+	)
+	tags := el(idTags, el(idTag,
+		el(idTargets, uintEl(idTagTrackUID, 102)),
+		el(idSimpleTag, strEl(idTagName, "NUMBER_OF_FRAMES"), strEl(idTagString, "2")),
+	))
+
+	cluster1 := [][]byte{
+		uintEl(idTimecode, 1000),
+		el(idSimpleBlock, blockData(1, 0, "video")),
+		el(idBlockGroup, el(idBlock, blockData(2, 500, "Hola\r\n[música]")), uintEl(idBlockDuration, 1500)),
+		el(idBlockGroup, el(idBlock, append(blockData(3, 0, ""), zlibBytes("1,0,Default,,0,0,0,,{\\i1}Second{\\i0}")...)), uintEl(idBlockDuration, 1000)),
+		el(idBlockGroup, el(idBlock, append(blockData(3, 0, ""), zlibBytes("0,0,Default,,0,0,0,,First\nline")...)), uintEl(idBlockDuration, 2000)),
+	}
+	cluster2 := el(idCluster, uintEl(idTimecode, 5000), el(idSimpleBlock, blockData(2, 0, "Adiós")))
+
+	var c1 []byte
+	if unknownCluster {
+		c1 = append([]byte{0x1F, 0x43, 0xB6, 0x75, 0x01, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}, bytes.Join(cluster1, nil)...)
+	} else {
+		c1 = el(idCluster, cluster1...)
+	}
+
+	// Tags after the clusters, found through the SeekHead.
+	info := el(idInfo, uintEl(idTimecodeSc, 1000000))
+	seekLen := len(el(idSeekHead, el(idSeek, el(idSeekID, []byte{0x12, 0x54, 0xC3, 0x67}), uintEl(idSeekPos, 0))))
+	tagsPos := seekLen + len(info) + len(tracks) + len(c1) + len(cluster2)
+	seek := el(idSeekHead, el(idSeek, el(idSeekID, []byte{0x12, 0x54, 0xC3, 0x67}), uintEl(idSeekPos, uint64(tagsPos))))
+	segment := el(idSegment, seek, info, tracks, c1, cluster2, tags)
+	return append(header, segment...)
+}
+
+func open(t *testing.T, data []byte) *File {
+	t.Helper()
+	f, err := Open(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return f
+}
+
+func TestOpen_tracks(t *testing.T) {
+	t.Parallel()
+
+	f := open(t, testFile(false))
+	subs := f.SubtitleTracks()
+	if len(f.Tracks) != 3 || len(subs) != 2 {
+		t.Fatalf("tracks = %d, subtitle tracks = %d; want 3, 2", len(f.Tracks), len(subs))
+	}
+	srt, ass := subs[0], subs[1]
+	if srt.Index != 1 || srt.Language != "spa" || srt.Name != "SDH" || srt.Default || !srt.HearingImpaired || srt.Frames != 2 {
+		t.Fatalf("srt track = %+v", srt)
+	}
+	if ass.Index != 2 || ass.Language != "eng" || !ass.Forced || !ass.Default || ass.Frames != -1 || ass.CodecName() != "ass" {
+		t.Fatalf("ass track = %+v", ass)
+	}
+}
+
+func TestExtractSubtitle(t *testing.T) {
+	t.Parallel()
+
+	for _, unknown := range []bool{false, true} {
+		f := open(t, testFile(unknown))
+		subs := f.SubtitleTracks()
+
+		srt, err := f.ExtractSubtitle(subs[0])
+		if err != nil {
+			t.Fatalf("srt: %v", err)
+		}
+		wantSRT := "1\n00:00:01,500 --> 00:00:03,000\nHola\n[música]\n\n2\n00:00:05,000 --> 00:00:07,000\nAdiós\n\n"
+		if string(srt) != wantSRT {
+			t.Fatalf("unknown=%t srt:\n%q\nwant\n%q", unknown, srt, wantSRT)
+		}
+
+		ass, err := f.ExtractSubtitle(subs[1])
+		if err != nil {
+			t.Fatalf("ass: %v", err)
+		}
+		wantASS := strings.TrimSuffix(assPrivate, "\n") + "\n\n[Events]\n" + assEventsFormat + "\n" +
+			"Dialogue: 0,0:00:01.00,0:00:03.00,Default,,0,0,0,,First\\Nline\n" +
+			"Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\\i1}Second{\\i0}\n"
+		if string(ass) != wantASS {
+			t.Fatalf("unknown=%t ass:\n%s\nwant\n%s", unknown, ass, wantASS)
+		}
+	}
+}
+
+func TestOpen_notMatroska(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("1\n00:00:01,000 --> 00:00:02,000\nHi\n")
+	if _, err := Open(bytes.NewReader(data), int64(len(data))); err == nil {
+		t.Fatal("Open(srt) expected error")
+	}
+}
+
+func TestRender_pgsAndVTT(t *testing.T) {
+	t.Parallel()
+
+	pgs := Track{CodecID: "S_HDMV/PGS"}
+	seg := []byte{0x16, 0x00, 0x02, 0xAA, 0xBB, 0x80, 0x00, 0x00}
+	got, err := Render(pgs, []Block{{Time: time.Second, Data: seg}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{'P', 'G', 0x00, 0x01, 0x5F, 0x90, 0, 0, 0, 0, 0x16, 0x00, 0x02, 0xAA, 0xBB,
+		'P', 'G', 0x00, 0x01, 0x5F, 0x90, 0, 0, 0, 0, 0x80, 0x00, 0x00}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("pgs = % x\nwant % x", got, want)
+	}
+
+	vtt := Track{CodecID: "S_TEXT/WEBVTT"}
+	got, err = Render(vtt, []Block{{Time: 0, Data: []byte("<v Bob>a &amp; <i>b</i></v>")}, {Time: 1500 * time.Millisecond, Duration: time.Second, Data: []byte("<c.yellow>c</c> <00:00:02.000>d")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantVTT := "1\n00:00:00,000 --> 00:00:01,500\na & <i>b</i>\n\n2\n00:00:01,500 --> 00:00:02,500\nc d\n\n"
+	if string(got) != wantVTT {
+		t.Fatalf("vtt = %q, want %q", got, wantVTT)
+	}
+}
+
+func TestBlocks_unknownSizeBlock(t *testing.T) {
+	t.Parallel()
+
+	// A SimpleBlock whose size field is all ones inside a sized cluster.
+	tracks := el(idTracks, el(idTrackEntry, uintEl(idTrackNumber, 2), uintEl(idTrackType, TrackTypeSubtitle), strEl(idCodecID, "S_TEXT/UTF8")))
+	block := []byte{0xA3, 0xFF, 0x82, 0x00, 0x00, 0x80}
+	data := append(el(idEBML, strEl(idDocType, "matroska")), el(idSegment, tracks, el(idCluster, uintEl(idTimecode, 0), block))...)
+	f := open(t, data)
+	if _, err := f.Blocks(2); err == nil {
+		t.Fatal("Blocks with an unknown-size SimpleBlock should fail")
+	}
+}
+
+// fuzzSeeds are the files the fuzz targets start from.
+func fuzzSeeds(f *testing.F) {
+	sample, err := os.ReadFile(filepath.Join("testdata", "sample.mkv"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(sample)
+	f.Add(testFile(false))
+	f.Add(testFile(true))
+}
+
+func FuzzOpen(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		Open(bytes.NewReader(data), int64(len(data)))
+	})
+}
+
+func FuzzBlocks(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		mf, err := Open(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return
+		}
+		for _, track := range mf.SubtitleTracks() {
+			mf.ExtractSubtitle(track)
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package matroska
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assEventsFormat is the Format line of the [Events] section Matroska ASS blocks follow
+// (after ReadOrder); SSA has Marked instead of Layer.
+const (
+	assEventsFormat = "Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text"
+	ssaEventsFormat = "Format: Marked, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text"
+)
+
+// Extension returns the file extension the track is rebuilt as, or "" when the codec
+// cannot be extracted natively (VobSub and other image formats). WebVTT tracks are
+// rebuilt as SRT.
+func (t Track) Extension() string {
+	switch t.CodecID {
+	case "S_TEXT/UTF8", "S_TEXT/ASCII", "S_TEXT/WEBVTT", "D_WEBVTT/SUBTITLES":
+		return ".srt"
+	case "S_TEXT/ASS", "S_TEXT/SSA", "S_ASS", "S_SSA":
+		return ".ass"
+	case "S_HDMV/PGS":
+		return ".sup"
+	}
+	return ""
+}
+
+// CodecName maps the Matroska CodecID to the ffprobe codec name (subrip, ass, ...).
+func (t Track) CodecName() string {
+	switch t.CodecID {
+	case "S_TEXT/UTF8", "S_TEXT/ASCII":
+		return "subrip"
+	case "S_TEXT/ASS", "S_ASS":
+		return "ass"
+	case "S_TEXT/SSA", "S_SSA":
+		return "ssa"
+	case "S_TEXT/WEBVTT", "D_WEBVTT/SUBTITLES":
+		return "webvtt"
+	case "S_HDMV/PGS":
+		return "hdmv_pgs_subtitle"
+	case "S_VOBSUB":
+		return "dvd_subtitle"
+	case "S_DVBSUB":
+		return "dvb_subtitle"
+	}
+	return strings.ToLower(t.CodecID)
+}
+
+// ExtractSubtitle demuxes track and rebuilds it as a standalone file (see Extension).
+func (f *File) ExtractSubtitle(track Track) ([]byte, error) {
+	if track.Extension() == "" {
+		return nil, fmt.Errorf("matroska: codec %s cannot be extracted natively", track.CodecID)
+	}
+	blocks, err := f.Blocks(track.Number)
+	if err != nil {
+		return nil, err
+	}
+	return Render(track, blocks[track.Number])
+}
+
+// Render rebuilds the file of a subtitle track from its blocks (sorted by time): SRT
+// (also for WebVTT, without the WebVTT-only markup), ASS/SSA (CodecPrivate header plus
+// Dialogue lines in ReadOrder) or PGS .sup. A block without duration ends when the next
+// one starts.
+func Render(track Track, blocks []Block) ([]byte, error) {
+	ends := make([]time.Duration, len(blocks))
+	for i, b := range blocks {
+		switch {
+		case b.Duration > 0:
+			ends[i] = b.Time + b.Duration
+		case i+1 < len(blocks):
+			ends[i] = blocks[i+1].Time
+		default:
+			ends[i] = b.Time + 2*time.Second
+		}
+	}
+
+	var buf bytes.Buffer
+	switch track.Extension() {
+	case ".srt":
+		webvtt := track.CodecName() == "webvtt"
+		for i, b := range blocks {
+			text := strings.TrimRight(strings.ReplaceAll(string(b.Data), "\r\n", "\n"), "\n")
+			if webvtt {
+				text = vttText(text)
+			}
+			fmt.Fprintf(&buf, "%d\n%s --> %s\n%s\n\n", i+1, srtTime(b.Time), srtTime(ends[i]), text)
+		}
+	case ".ass":
+		return renderASS(track, blocks, ends)
+	case ".sup":
+		for _, b := range blocks {
+			if err := writePGS(&buf, b); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("matroska: codec %s cannot be extracted natively", track.CodecID)
+	}
+	return buf.Bytes(), nil
+}
+
+func renderASS(track Track, blocks []Block, ends []time.Duration) ([]byte, error) {
+	type event struct {
+		order int
+		line  string
+	}
+	events := make([]event, 0, len(blocks))
+	for i, b := range blocks {
+		// ReadOrder, Layer, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+		fields := strings.SplitN(string(b.Data), ",", 9)
+		if len(fields) < 9 {
+			return nil, fmt.Errorf("matroska: malformed ASS block at %s", srtTime(b.Time))
+		}
+		order, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			order = i
+		}
+		text := strings.NewReplacer("\r\n", `\N`, "\n", `\N`).Replace(strings.TrimRight(fields[8], "\r\n"))
+		line := fmt.Sprintf("Dialogue: %s,%s,%s,%s,%s", fields[1], assTime(b.Time), assTime(ends[i]),
+			strings.Join(fields[2:8], ","), text)
+		events = append(events, event{order, line})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].order < events[j].order })
+
+	var buf bytes.Buffer
+	header := strings.TrimRight(strings.ReplaceAll(string(track.CodecPrivate), "\r\n", "\n"), "\n")
+	if header == "" {
+		header = "[Script Info]\nScriptType: v4.00+"
+	}
+	buf.WriteString(header + "\n")
+	if !strings.Contains(header, "[Events]") {
+		format := assEventsFormat
+		if track.CodecName() == "ssa" {
+			format = ssaEventsFormat
+		}
+		buf.WriteString("\n[Events]\n" + format + "\n")
+	}
+	for _, e := range events {
+		buf.WriteString(e.line + "\n")
+	}
+	return buf.Bytes(), nil
+}
+
+// writePGS writes the segments of one PGS block with the .sup "PG" headers (PTS in
+// 90 kHz ticks, DTS zero).
+func writePGS(buf *bytes.Buffer, b Block) error {
+	pts := uint32(b.Time * 90000 / time.Second)
+	for data := b.Data; len(data) > 0; {
+		if len(data) < 3 {
+			return fmt.Errorf("matroska: truncated PGS segment at %s", srtTime(b.Time))
+		}
+		n := 3 + int(binary.BigEndian.Uint16(data[1:3]))
+		if n > len(data) {
+			return fmt.Errorf("matroska: truncated PGS segment at %s", srtTime(b.Time))
+		}
+		var head [10]byte
+		head[0], head[1] = 'P', 'G'
+		binary.BigEndian.PutUint32(head[2:], pts)
+		buf.Write(head[:])
+		buf.Write(data[:n])
+		data = data[n:]
+	}
+	return nil
+}
+
+func clock(d time.Duration) (h, m, s int, rest time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h = int(d / time.Hour)
+	m = int(d / time.Minute % 60)
+	s = int(d / time.Second % 60)
+	return h, m, s, d % time.Second
+}
+
+func srtTime(d time.Duration) string {
+	h, m, s, rest := clock(d)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, rest/time.Millisecond)
+}
+
+// vttTag matches the WebVTT cue markup SRT has no counterpart for: class, voice,
+// language and ruby spans and inline timestamps. <i>, <b> and <u> are kept.
+var vttTag = regexp.MustCompile(`</?(?:c|v|lang|ruby|rt)(?:[.\s][^>]*)?>|<\d[\d:.]*>`)
+
+// vttText converts the text of a WebVTT cue to SRT: markup without an SRT equivalent is
+// dropped and the &amp;, &lt;, &gt; and &nbsp; escapes are decoded.
+func vttText(text string) string {
+	text = vttTag.ReplaceAllString(text, "")
+	return strings.NewReplacer("&lt;", "<", "&gt;", ">", "&nbsp;", "\u00a0", "&lrm;", "\u200e", "&rlm;", "\u200f", "&amp;", "&").Replace(text)
+}
+
+func assTime(d time.Duration) string {
+	h, m, s, rest := clock(d)
+	return fmt.Sprintf("%d:%02d:%02d.%02d", h, m, s, rest/(10*time.Millisecond))
+}
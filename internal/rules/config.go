@@ -1,9 +1,14 @@
 package rules
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
 	"strings"
 )
 
@@ -14,15 +19,29 @@ import (
 // RemoveLineIfContains: remove line if it contains the specified text. Used when some subtitles don't follow common rules or patterns. eg: "tense music * (should be [tense music])"
 // RemoveLineIfAllCapsAction: remove line if it describes an action and is all uppercase. eg: "PHONE RINGS", "ALL SIGHS"
 // RemoveOnlySymbolsLine: remove line if it contains only symbols. eg: "***", "♪", "♫"
+// Version: config format (ConfigVersion; files without it are version 1 and are migrated when read).
+// Extends: the preset the config starts from (see PresetNames); keys set in the config override it.
+// Allow: text no rule may remove, and cues of specific files no rule may change (see AllowConfig).
+// Hook: presets by subtitle language and backups of the hook command (see HookConfig).
 type Config struct {
-	LoadedFromFile                   bool        `json:"loadedFromFile"`
-	RemoveTextBeforeColonIfUppercase bool        `json:"removeTextBeforeColonIfUppercase"`
-	RemoveTextBeforeColon            bool        `json:"removeTextBeforeColon"`
-	RemoveSingleLineColon            bool        `json:"removeSingleLineColon"`
-	RemoveLineIfAllCapsAction        bool        `json:"removeLineIfAllCapsAction"`
-	RemoveBetweenDelimiters          []Delimiter `json:"removeBetweenDelimiters"`
-	RemoveLineIfContains             string      `json:"removeLineIfContains"`
-	RemoveOnlySymbolsLine            bool        `json:"removeOnlySymbolsLine"`
+	Version                          int            `json:"version,omitempty"`
+	Extends                          string         `json:"extends,omitempty"`
+	LoadedFromFile                   bool           `json:"-"`
+	RemoveTextBeforeColonIfUppercase bool           `json:"removeTextBeforeColonIfUppercase"`
+	RemoveTextBeforeColon            bool           `json:"removeTextBeforeColon"`
+	RemoveSingleLineColon            bool           `json:"removeSingleLineColon"`
+	RemoveLineIfAllCapsAction        bool           `json:"removeLineIfAllCapsAction"`
+	RemoveBetweenDelimiters          []Delimiter    `json:"removeBetweenDelimiters"`
+	RemoveLineIfContains             string         `json:"removeLineIfContains"`
+	RemoveOnlySymbolsLine            bool           `json:"removeOnlySymbolsLine"`
+	Allow                            *AllowConfig   `json:"allow,omitempty"`
+	Lint                             *LintConfig    `json:"lint,omitempty"`
+	Extract                          *ExtractConfig `json:"extract,omitempty"`
+	Forced                           *ForcedConfig  `json:"forced,omitempty"`
+	Hook                             *HookConfig    `json:"hook,omitempty"`
+
+	Sources map[string][]string `json:"-"` // Merge: top-level key -> names of the layers that set it
+	Layers  []Layer             `json:"-"` // Merge: layers applied over the defaults, in order
 }
 
 type Delimiter struct {
@@ -30,9 +49,151 @@ type Delimiter struct {
 	Right string `json:"right"`
 }
 
+// Pattern is the regex matching Left, the text in between and Right.
+func (d Delimiter) Pattern() string {
+	controlEscape := ""
+	minContentLen := 0
+	if d.Left == "<" {
+		// SRT format uses angle brackets for formatting (italic, bold, etc.), <i>Text</i>
+		minContentLen = 3
+		controlEscape = "/="
+	}
+	left := regexp.QuoteMeta(d.Left)
+	right := regexp.QuoteMeta(d.Right)
+	return fmt.Sprintf(`%s[^%s%s]{%d,}%s`, left, controlEscape, right, minContentLen, right)
+}
+
+// AllowConfig protects dialogue the rules would take for hearing-impaired markup. A rule
+// that would remove a protected phrase or a match of a protected pattern is not applied
+// to that cue (eg: "NOTE:", "GET OUT NOW", `(?i)\bhe said:`); cues containing one of
+// CuesContaining (false positives rejected during review, eg: "[Dr. House]", "MR. T:")
+// and the cues listed for a file are never changed.
+type AllowConfig struct {
+	Phrases        []string    `json:"phrases"`
+	Patterns       []string    `json:"patterns"`
+	CuesContaining []string    `json:"cuesContaining"`
+	Cues           []AllowCues `json:"cues"`
+}
+
+// AllowCues lists cue indexes of the files whose base name matches File (a name or a
+// glob, eg: "Movie.2019.srt", "*.S01E0?.srt").
+type AllowCues struct {
+	File string `json:"file"`
+	Cues []int  `json:"cues"`
+}
+
+// Matches reports whether File selects the file at path ("" matches nothing).
+func (a AllowCues) Matches(path string) bool {
+	if path == "" {
+		return false
+	}
+	ok, _ := filepath.Match(a.File, filepath.Base(path))
+	return ok
+}
+
+// LintConfig selects quality-control thresholds for the lint command.
+// Preset names a built-in threshold set (eg: "netflix", "bbc"); non-zero fields override it.
+type LintConfig struct {
+	Preset        string  `json:"preset,omitempty"`
+	MaxCPS        float64 `json:"maxCps,omitempty"`
+	MaxCPL        int     `json:"maxCpl,omitempty"`
+	MaxLines      int     `json:"maxLines,omitempty"`
+	MinDurationMs int     `json:"minDurationMs,omitempty"`
+	MaxDurationMs int     `json:"maxDurationMs,omitempty"`
+	MinGapMs      int     `json:"minGapMs,omitempty"`
+}
+
+// ExtractConfig controls the files written by video extraction (-m).
+// NameTemplate: eg: "{base}.{lang2}{.forced}{.sdh}.{ext}" (the default when empty).
+// Sidecar: also write SUBTITLE.json with the source stream index, codec, title and flags.
+// SkipSDH: leave out SDH tracks (flag, title or content) when the language has a plain track.
+type ExtractConfig struct {
+	NameTemplate string `json:"nameTemplate,omitempty"`
+	Sidecar      bool   `json:"sidecar,omitempty"`
+	SkipSDH      bool   `json:"skipSdh,omitempty"`
+}
+
+// ForcedConfig selects the cues kept when building a forced track from a full one
+// (--forced). A cue is kept when it matches one of Patterns (the match is removed from
+// the text), when Italics is set and every line is in italics, or when DetectLanguage is
+// set and a line is reliably detected in another language than Language (ISO 639-1/2;
+// empty: the language most of the document is in).
+type ForcedConfig struct {
+	Patterns       []string `json:"patterns"`
+	Italics        bool     `json:"italics"`
+	DetectLanguage bool     `json:"detectLanguage"`
+	Language       string   `json:"language,omitempty"`
+}
+
+// DefaultForcedConfig is the "forced" section of DefaultConfig, also used when a
+// config has none.
+func DefaultForcedConfig() ForcedConfig {
+	return ForcedConfig{
+		Patterns:       []string{`(?i)[\[(]\s*(in|speaking|speaks)\s+[\p{L} -]+[\])]`},
+		Italics:        true,
+		DetectLanguage: true,
+	}
+}
+
+// HookConfig tunes the hook command (Bazarr, Sonarr, Radarr and Jellyfin post-processing).
+// Presets: the preset a subtitle is sanitized with, by its language; languages not listed
+// use the config as it is (nil: DefaultHookConfig's, empty: none).
+// Backup: suffix of the copy kept of a subtitle before it is rewritten in place (empty:
+// ".bak"; "-": no copy).
+type HookConfig struct {
+	Presets []LanguagePreset `json:"presets"`
+	Backup  string           `json:"backup,omitempty"`
+}
+
+// LanguagePreset applies Preset to the subtitles in one of Languages (ISO 639-1, eg: "ja").
+type LanguagePreset struct {
+	Languages []string `json:"languages"`
+	Preset    string   `json:"preset"`
+}
+
+// DefaultHookConfig is used when a config has no "hook" section. Scripts without letter
+// case get "light": the uppercase speaker and action rules have nothing to go by there,
+// and parentheses often hold readings and translator notes rather than sounds.
+func DefaultHookConfig() HookConfig {
+	return HookConfig{
+		Presets: []LanguagePreset{{Languages: []string{"ja", "zh", "ko", "th"}, Preset: "light"}},
+		Backup:  ".bak",
+	}
+}
+
+// PresetFor returns the preset of the subtitles in lang (ISO 639-1, compared
+// case-insensitively; "" when none applies).
+func (h HookConfig) PresetFor(lang string) string {
+	presets := h.Presets
+	if presets == nil {
+		presets = DefaultHookConfig().Presets
+	}
+	for _, p := range presets {
+		for _, l := range p.Languages {
+			if lang != "" && strings.EqualFold(l, lang) {
+				return p.Preset
+			}
+		}
+	}
+	return ""
+}
+
+// BackupSuffix returns the suffix of the backups ("" when none are kept).
+func (h HookConfig) BackupSuffix() string {
+	switch h.Backup {
+	case "":
+		return DefaultHookConfig().Backup
+	case "-":
+		return ""
+	}
+	return h.Backup
+}
+
 // DefaultConfig returns built-in rule defaults when no config file is used.
 func DefaultConfig() Config {
+	forced := DefaultForcedConfig()
 	return Config{
+		Version:                          ConfigVersion,
 		LoadedFromFile:                   false,
 		RemoveTextBeforeColonIfUppercase: true,
 		RemoveTextBeforeColon:            false,
@@ -45,39 +206,49 @@ func DefaultConfig() Config {
 		},
 		RemoveLineIfContains:  " music *",
 		RemoveOnlySymbolsLine: true,
+		Forced:                &forced,
 	}
 }
 
-// ParseConfig unmarshals JSON rule config. On success, LoadedFromFile is set true
-// (caller supplied explicit JSON; any loadedFromFile field in the payload is ignored).
+// ParseConfig validates (see Validate) and unmarshals JSON rule config, migrating older
+// versions. On success, LoadedFromFile is set true (caller supplied explicit JSON).
 func ParseConfig(data []byte) (Config, error) {
-	var c Config
-	if err := json.Unmarshal(data, &c); err != nil {
+	if err := Validate(data); err != nil {
 		return Config{}, err
 	}
-	c.LoadedFromFile = true
-	return c, nil
-}
-
-// LoadDefaultOrEmpty loads config.json from the current working directory, or returns
-// DefaultConfig after logging read/unmarshal errors to stderr.
-func LoadDefaultOrEmpty() Config {
-	data, err := os.ReadFile("config.json")
+	m, err := decodeObject(data)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error reading config:", err)
-		return DefaultConfig()
+		return Config{}, err
+	}
+	upgrade(m)
+	delete(m, "$schema")
+	if name, ok := m["extends"].(string); ok {
+		base, err := presetObject(name)
+		if err != nil {
+			return Config{}, err
+		}
+		m = mergeValue(base, m).(map[string]any)
 	}
-	conf, err := ParseConfig(data)
+	data, err = json.Marshal(m)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error unmarshalling config:", err)
-		return DefaultConfig()
+		return Config{}, err
 	}
-	return conf
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var c Config
+	if err := dec.Decode(&c); err != nil {
+		return Config{}, err
+	}
+	c.LoadedFromFile = true
+	return c, nil
 }
 
 // DescribeEffective returns a readable summary of the active rules (for CLI preview).
 func (c Config) DescribeEffective() string {
 	var b strings.Builder
+	if c.Extends != "" {
+		fmt.Fprintf(&b, "preset: %s\n", c.Extends)
+	}
 	fmt.Fprintf(&b, "removeTextBeforeColonIfUppercase: %t\n", c.RemoveTextBeforeColonIfUppercase)
 	fmt.Fprintf(&b, "removeTextBeforeColon: %t\n", c.RemoveTextBeforeColon)
 	fmt.Fprintf(&b, "removeSingleLineColon: %t\n", c.RemoveSingleLineColon)
@@ -95,7 +266,20 @@ func (c Config) DescribeEffective() string {
 	} else {
 		b.WriteString("removeLineIfContains: (empty; disabled)\n")
 	}
-	if c.LoadedFromFile {
+	for _, section := range []struct {
+		key   string
+		value any
+	}{{"allow", c.Allow}, {"lint", c.Lint}, {"extract", c.Extract}, {"forced", c.Forced}, {"hook", c.Hook}} {
+		if reflect.ValueOf(section.value).IsNil() {
+			continue
+		}
+		data, _ := json.Marshal(section.value)
+		fmt.Fprintf(&b, "%s: %s\n", section.key, data)
+	}
+	if c.Sources != nil {
+		c.annotateSources(&b)
+		b.WriteString("\nsources: " + describeLayers(c.Extends, c.Layers) + "\n")
+	} else if c.LoadedFromFile {
 		b.WriteString("\nsource: config.json\n")
 	} else {
 		b.WriteString("\nsource: built-in defaults\n")
@@ -103,10 +287,50 @@ func (c Config) DescribeEffective() string {
 	return strings.TrimRight(b.String(), "\n")
 }
 
-func (c *Config) SaveToBackupFile(jsonData []byte) error {
-	err := os.WriteFile("config.json", jsonData, 0644)
+// annotateSources appends the layers that set each top-level key to its line in b
+// (lines of keys no layer set are from the preset).
+func (c Config) annotateSources(b *strings.Builder) {
+	lines := strings.Split(strings.TrimRight(b.String(), "\n"), "\n")
+	for i, line := range lines {
+		key, _, ok := strings.Cut(line, ":")
+		if !ok || strings.HasPrefix(line, " ") {
+			continue
+		}
+		src := "preset " + c.Extends
+		if key == "preset" {
+			key, src = "extends", "default"
+		}
+		if names := c.Sources[key]; len(names) > 0 {
+			src = strings.Join(names, ", ")
+		}
+		lines[i] = line + "  [" + src + "]"
+	}
+	b.Reset()
+	b.WriteString(strings.Join(lines, "\n") + "\n")
+}
+
+// AllowCuesContaining appends phrase to Allow.CuesContaining unless it is blank or
+// already present. It reports whether the config changed.
+func (c *Config) AllowCuesContaining(phrase string) bool {
+	phrase = strings.TrimSpace(phrase)
+	if phrase == "" || c.Allow != nil && slices.Contains(c.Allow.CuesContaining, phrase) {
+		return false
+	}
+	if c.Allow == nil {
+		c.Allow = &AllowConfig{}
+	}
+	c.Allow.CuesContaining = append(c.Allow.CuesContaining, phrase)
+	return true
+}
+
+// Save writes c as indented JSON to path.
+func (c Config) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
 	if err != nil {
-		return fmt.Errorf("save config (backup): %w", err)
+		return fmt.Errorf("marshal config: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("save config: %w", err)
 	}
 	return nil
 }
@@ -122,4 +346,8 @@ const (
 	RuleRemoveBetweenDelimiters          AbbreviatedRuleDescription = "\\ Delims /"
 	RuleRemoveLineIfContains             AbbreviatedRuleDescription = "%Contains%"
 	RuleRemoveOnlySymbolsLine            AbbreviatedRuleDescription = "♪ ♪"
+	RuleForcedPattern                    AbbreviatedRuleDescription = "Forced [in]"
+	RuleForcedItalics                    AbbreviatedRuleDescription = "Forced <i>"
+	RuleForcedLanguage                   AbbreviatedRuleDescription = "Forced lang"
+	RuleCleanup                          AbbreviatedRuleDescription = "Cleanup" // spaces and emptied lines left by the rules
 )
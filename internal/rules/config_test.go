@@ -104,3 +104,28 @@ func TestParseConfig_roundTripDefault(t *testing.T) {
 		t.Fatalf("round trip mismatch:\n%+v\nvs\n%+v", c, d)
 	}
 }
+
+func TestHookConfig(t *testing.T) {
+	c, err := ParseConfig([]byte(`{"hook": {"backup": ".orig"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only backup set: the default presets still apply.
+	if got := c.Hook.PresetFor("JA"); got != "light" {
+		t.Fatalf("PresetFor(ja) = %q, want light", got)
+	}
+	if got := c.Hook.PresetFor("en"); got != "" {
+		t.Fatalf("PresetFor(en) = %q, want none", got)
+	}
+	if got := c.Hook.BackupSuffix(); got != ".orig" {
+		t.Fatalf("BackupSuffix = %q", got)
+	}
+
+	h := HookConfig{Presets: []LanguagePreset{{Languages: []string{"en", "fr"}, Preset: "aggressive"}}, Backup: "-"}
+	if h.PresetFor("fr") != "aggressive" || h.PresetFor("ja") != "" || h.BackupSuffix() != "" {
+		t.Fatalf("custom hook config: %q %q %q", h.PresetFor("fr"), h.PresetFor("ja"), h.BackupSuffix())
+	}
+	if got := (HookConfig{}).BackupSuffix(); got != ".bak" {
+		t.Fatalf("default BackupSuffix = %q", got)
+	}
+}
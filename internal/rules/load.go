@@ -0,0 +1,404 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// DirConfigName is the per-directory config applied to the files of its directory and
+// subdirectories (the nearest one wins).
+const DirConfigName = ".subtitle-sanitizer.json"
+
+// Layer names, lowest precedence first. The preset (DefaultPreset unless a layer sets
+// "extends") is always the bottom layer.
+const (
+	LayerUser      = "user"
+	LayerDirectory = "directory"
+	LayerFlags     = "flags"
+)
+
+// Layer is one source of config values; only the keys it sets override the layers
+// below it, and sections (allow, lint, extract, forced, hook) merge key by key.
+type Layer struct {
+	Name   string
+	Path   string // file the layer was read from ("" for flags)
+	values map[string]any
+}
+
+// ReadLayer reads the JSON object in path as a layer.
+func ReadLayer(name, path string) (Layer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Layer{}, fmt.Errorf("read config: %w", err)
+	}
+	l, err := ParseLayer(name, data)
+	if err != nil {
+		return Layer{}, fmt.Errorf("invalid config %s:\n%w", path, err)
+	}
+	l.Path = path
+	return l, nil
+}
+
+// ParseLayer validates data (see Validate) and decodes it as a layer, migrated to the
+// current version.
+func ParseLayer(name string, data []byte) (Layer, error) {
+	if err := Validate(data); err != nil {
+		return Layer{}, err
+	}
+	values, err := decodeObject(data)
+	if err != nil {
+		return Layer{}, err
+	}
+	upgrade(values)
+	delete(values, "version")
+	delete(values, "$schema")
+	return Layer{Name: name, values: values}, nil
+}
+
+// Set assigns value to key; dotted keys reach into sections (eg: "lint.maxCps").
+func (l *Layer) Set(key string, value any) {
+	if l.values == nil {
+		l.values = map[string]any{}
+	}
+	m := l.values
+	parts := strings.Split(key, ".")
+	for _, p := range parts[:len(parts)-1] {
+		sub, ok := m[p].(map[string]any)
+		if !ok {
+			sub = map[string]any{}
+			m[p] = sub
+		}
+		m = sub
+	}
+	m[parts[len(parts)-1]] = value
+}
+
+// SetString applies a KEY=VALUE assignment (--set). VALUE is read as JSON when it
+// parses (true, 20, ["x"]) and as a plain string otherwise.
+func (l *Layer) SetString(assignment string) error {
+	key, raw, ok := strings.Cut(assignment, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.Contains(key, "..") || strings.HasPrefix(key, ".") || strings.HasSuffix(key, ".") {
+		return fmt.Errorf("--set %q: expected KEY=VALUE", assignment)
+	}
+	var value any = raw
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err == nil && !dec.More() {
+		value = v
+	}
+	l.Set(key, value)
+	return nil
+}
+
+// IsZero reports whether the layer sets nothing.
+func (l Layer) IsZero() bool { return len(l.values) == 0 }
+
+func (l Layer) String() string {
+	if l.Path == "" {
+		return l.Name
+	}
+	return l.Name + " (" + l.Path + ")"
+}
+
+// Merge applies layers in order over their preset: the "extends" of the last layer
+// setting one, else DefaultPreset. The result records the preset (Extends), per
+// top-level key the layers that set it (Sources) and the layers used (Layers).
+func Merge(layers ...Layer) (Config, error) {
+	preset := DefaultPreset
+	for _, l := range layers {
+		if name, ok := l.values["extends"].(string); ok {
+			preset = name
+		}
+	}
+	merged, err := presetObject(preset)
+	if err != nil {
+		return Config{}, err
+	}
+
+	sources := map[string][]string{}
+	fromFile := false
+	var used []Layer
+	for _, l := range layers {
+		if l.IsZero() && l.Path == "" {
+			continue
+		}
+		used = append(used, l)
+		fromFile = fromFile || l.Path != ""
+		for k, v := range l.values {
+			merged[k] = mergeValue(merged[k], v)
+			if !slices.Contains(sources[k], l.Name) {
+				sources[k] = append(sources[k], l.Name)
+			}
+		}
+	}
+	merged["extends"] = strings.ToLower(strings.TrimSpace(preset))
+	data, err := json.Marshal(merged)
+	if err != nil {
+		return Config{}, err
+	}
+	c, err := ParseConfig(data)
+	if err != nil {
+		return Config{}, fmt.Errorf("merge config (%s): %w", describeLayers(preset, used), err)
+	}
+	c.LoadedFromFile = fromFile
+	c.Sources = sources
+	c.Layers = used
+	return c, nil
+}
+
+// presetObject is the named preset as a decoded JSON object.
+func presetObject(name string) (map[string]any, error) {
+	c, err := Preset(name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return decodeObject(data)
+}
+
+// mergeValue merges JSON objects key by key; anything else replaces base.
+func mergeValue(base, over any) any {
+	b, ok1 := base.(map[string]any)
+	o, ok2 := over.(map[string]any)
+	if !ok1 || !ok2 {
+		return over
+	}
+	out := make(map[string]any, len(b)+len(o))
+	for k, v := range b {
+		out[k] = v
+	}
+	for k, v := range o {
+		out[k] = mergeValue(out[k], v)
+	}
+	return out
+}
+
+func decodeObject(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("config must be a JSON object")
+	}
+	return m, nil
+}
+
+func describeLayers(preset string, layers []Layer) string {
+	names := []string{"preset " + preset}
+	for _, l := range layers {
+		names = append(names, l.String())
+	}
+	return strings.Join(names, " < ")
+}
+
+// UserConfigPath is config.json in the user config dir ($XDG_CONFIG_HOME or
+// ~/.config/subtitle-sanitizer on Linux, %AppData% on Windows).
+func UserConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "subtitle-sanitizer", "config.json")
+}
+
+// legacyConfigPath is config.json next to the executable, where earlier versions kept it.
+func legacyConfigPath() string {
+	exe, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(filepath.Dir(exe), "config.json")
+}
+
+// FindDirConfig returns the nearest DirConfigName in dir or its parents ("" when none).
+func FindDirConfig(dir string) string {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	for {
+		p := filepath.Join(dir, DirConfigName)
+		if st, err := os.Stat(p); err == nil && !st.IsDir() {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Loader resolves the effective config per input directory:
+// defaults < user file (or --config) < nearest .subtitle-sanitizer.json < flags.
+// It is safe for concurrent use.
+type Loader struct {
+	userPath string // --config, the user config file, or where one would be created
+	flags    Layer
+
+	mu    sync.Mutex
+	user  Layer
+	dirs  map[string]string       // directory -> its dir config ("" none)
+	confs map[string]loadedConfig // dir config ("" none) -> merged config
+}
+
+type loadedConfig struct {
+	conf Config
+	err  error
+}
+
+// NewLoader reads the user layer: explicit (--config, must exist) or else the user
+// config file, falling back to a config.json next to the executable. A missing user
+// file is not an error; nothing is written.
+func NewLoader(explicit string, flags Layer) (*Loader, error) {
+	l := &Loader{flags: flags}
+	l.flags.Name = LayerFlags
+	path := explicit
+	if path == "" {
+		path = UserConfigPath()
+		if legacy := legacyConfigPath(); !exists(path) && exists(legacy) {
+			path = legacy
+		}
+	}
+	l.userPath = path
+	if err := l.reload(explicit != ""); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// reload re-reads the user layer and forgets merged configs.
+func (l *Loader) reload(required bool) error {
+	l.user = Layer{}
+	if l.userPath != "" && (required || exists(l.userPath)) {
+		user, err := ReadLayer(LayerUser, l.userPath)
+		if err != nil {
+			return err
+		}
+		l.user = user
+	}
+	l.dirs = map[string]string{}
+	l.confs = map[string]loadedConfig{}
+	return nil
+}
+
+// UserPath is the file the user layer is read from and review rejections are saved to.
+func (l *Loader) UserPath() string { return l.userPath }
+
+// Base is the config without any directory layer.
+func (l *Loader) Base() (Config, error) {
+	return l.load("")
+}
+
+// ForDir is the config for the files of dir, with the nearest .subtitle-sanitizer.json.
+// ForDir returns the dir config path too ("" when none), a key for caching derived rules.
+func (l *Loader) ForDir(dir string) (Config, string, error) {
+	l.mu.Lock()
+	p, ok := l.dirs[dir]
+	if !ok {
+		p = FindDirConfig(dir)
+		l.dirs[dir] = p
+	}
+	l.mu.Unlock()
+	conf, err := l.load(p)
+	return conf, p, err
+}
+
+func (l *Loader) load(dirConfig string) (Config, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if c, ok := l.confs[dirConfig]; ok {
+		return c.conf, c.err
+	}
+	layers := []Layer{l.user}
+	var c loadedConfig
+	if dirConfig != "" {
+		dir, err := ReadLayer(LayerDirectory, dirConfig)
+		if err != nil {
+			c.err = err
+		}
+		layers = append(layers, dir)
+	}
+	if c.err == nil {
+		c.conf, c.err = Merge(append(layers, l.flags)...)
+	}
+	l.confs[dirConfig] = c
+	return c.conf, c.err
+}
+
+// AllowCuesContaining appends phrases to "allow.cuesContaining" of the user config file
+// (created when missing, migrated to the current version) and reloads it. It reports
+// whether the file changed.
+func (l *Loader) AllowCuesContaining(phrases []string) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.userPath == "" {
+		return false, errors.New("no user config path")
+	}
+	values := map[string]any{}
+	if data, err := os.ReadFile(l.userPath); err == nil {
+		if values, err = decodeObject(data); err != nil {
+			return false, fmt.Errorf("%s: %w", l.userPath, err)
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return false, fmt.Errorf("read config: %w", err)
+	}
+	upgrade(values)
+	allow, _ := values["allow"].(map[string]any)
+	if allow == nil {
+		allow = map[string]any{}
+	}
+	c := Config{Allow: &AllowConfig{}}
+	if list, ok := allow["cuesContaining"].([]any); ok {
+		for _, e := range list {
+			if s, ok := e.(string); ok {
+				c.Allow.CuesContaining = append(c.Allow.CuesContaining, s)
+			}
+		}
+	}
+	changed := false
+	for _, p := range phrases {
+		changed = c.AllowCuesContaining(p) || changed
+	}
+	if !changed {
+		return false, nil
+	}
+	allow["cuesContaining"] = c.Allow.CuesContaining
+	values["allow"] = allow
+	data, err := json.MarshalIndent(values, "", "  ")
+	if err != nil {
+		return false, fmt.Errorf("marshal config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.userPath), 0755); err != nil {
+		return false, fmt.Errorf("save config: %w", err)
+	}
+	if err := os.WriteFile(l.userPath, data, 0644); err != nil {
+		return false, fmt.Errorf("save config: %w", err)
+	}
+	return true, l.reload(true)
+}
+
+func exists(path string) bool {
+	if path == "" {
+		return false
+	}
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,150 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestMerge_layers(t *testing.T) {
+	t.Parallel()
+
+	user, err := ParseLayer(LayerUser, []byte(`{"removeTextBeforeColon": true, "lint": {"preset": "bbc", "maxCps": 15}, "exceptions": ["MR. T:"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ParseLayer(LayerDirectory, []byte(`{"removeTextBeforeColon": false, "lint": {"maxCps": 20}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var flags Layer
+	flags.Name = LayerFlags
+	for _, s := range []string{"removeLineIfContains=xyz", "lint.maxLines=3", "removeOnlySymbolsLine=false"} {
+		if err := flags.SetString(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	c, err := Merge(user, dir, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.RemoveTextBeforeColon || c.RemoveLineIfContains != "xyz" || c.RemoveOnlySymbolsLine || !c.RemoveSingleLineColon {
+		t.Fatalf("unexpected merge: %+v", c)
+	}
+	if c.Lint == nil || c.Lint.Preset != "bbc" || c.Lint.MaxCPS != 20 || c.Lint.MaxLines != 3 {
+		t.Fatalf("lint section: %+v", c.Lint)
+	}
+	if c.Allow == nil || !slices.Equal(c.Allow.CuesContaining, []string{"MR. T:"}) || len(c.RemoveBetweenDelimiters) != 3 {
+		t.Fatalf("allow %+v, delimiters %v", c.Allow, c.RemoveBetweenDelimiters)
+	}
+	if got := c.Sources["lint"]; !slices.Equal(got, []string{LayerUser, LayerDirectory, LayerFlags}) {
+		t.Fatalf("lint sources = %v", got)
+	}
+
+	s := c.DescribeEffective()
+	for _, sub := range []string{
+		"removeTextBeforeColon: false  [user, directory]",
+		"removeSingleLineColon: true  [preset standard]",
+		"preset: standard  [default]",
+		`removeLineIfContains: "xyz"  [flags]`,
+		"sources: preset standard < user < directory < flags",
+	} {
+		if !strings.Contains(s, sub) {
+			t.Fatalf("DescribeEffective missing %q in:\n%s", sub, s)
+		}
+	}
+}
+
+func TestMerge_invalidValue(t *testing.T) {
+	t.Parallel()
+
+	var flags Layer
+	if err := flags.SetString("removeTextBeforeColon=maybe"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Merge(flags); err == nil {
+		t.Fatal("expected error for a string in a boolean key")
+	}
+}
+
+func TestLayer_SetString_errors(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", "novalue", "=x", "lint.=3", ".x=1"} {
+		var l Layer
+		if err := l.SetString(s); err == nil {
+			t.Fatalf("SetString(%q) expected error", s)
+		}
+	}
+}
+
+func TestLoader(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	userPath := filepath.Join(root, "user.json")
+	show := filepath.Join(root, "Shows", "Show")
+	season := filepath.Join(show, "Season 1")
+	if err := os.MkdirAll(season, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, userPath, `{"version": 2, "removeTextBeforeColon": true, "exceptions": ["MR. T:"]}`)
+	writeFile(t, filepath.Join(show, DirConfigName), `{"removeLineIfAllCapsAction": true}`)
+
+	l, err := NewLoader(userPath, Layer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, err := l.Base()
+	if err != nil || !base.RemoveTextBeforeColon || base.RemoveLineIfAllCapsAction {
+		t.Fatalf("Base() = %+v, %v", base, err)
+	}
+	c, dirConfig, err := l.ForDir(season)
+	if err != nil || !c.RemoveTextBeforeColon || !c.RemoveLineIfAllCapsAction {
+		t.Fatalf("ForDir(season) = %+v, %v", c, err)
+	}
+	if dirConfig != filepath.Join(show, DirConfigName) {
+		t.Fatalf("dir config = %q", dirConfig)
+	}
+	if c, _, _ := l.ForDir(root); c.RemoveLineIfAllCapsAction {
+		t.Fatal("dir config applied outside its directory")
+	}
+
+	changed, err := l.AllowCuesContaining([]string{"[Dr. House]", " "})
+	if err != nil || !changed {
+		t.Fatalf("AllowCuesContaining = %t, %v", changed, err)
+	}
+	if changed, _ := l.AllowCuesContaining([]string{"[Dr. House]", "MR. T:"}); changed {
+		t.Fatal("AllowCuesContaining changed the file for known phrases")
+	}
+	c, _, _ = l.ForDir(season)
+	if c.Allow == nil || !slices.Equal(c.Allow.CuesContaining, []string{"MR. T:", "[Dr. House]"}) || !c.RemoveTextBeforeColon {
+		t.Fatalf("after AllowCuesContaining: %+v", c)
+	}
+	// The version 2 file was migrated: "exceptions" moved into "allow".
+	data, err := os.ReadFile(userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Validate(data); err != nil || strings.Contains(string(data), "exceptions") || !strings.Contains(string(data), `"version": 3`) {
+		t.Fatalf("saved config (%v):\n%s", err, data)
+	}
+
+	if _, err := NewLoader(filepath.Join(root, "missing.json"), Layer{}); err == nil {
+		t.Fatal("expected error for a missing --config file")
+	}
+	writeFile(t, filepath.Join(root, "bad.json"), `[1]`)
+	if _, err := NewLoader(filepath.Join(root, "bad.json"), Layer{}); err == nil {
+		t.Fatal("expected error for a non-object config")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ConfigVersion is the config format written by this build. Files without "version"
+// are version 1.
+const ConfigVersion = 3
+
+// migrations[i] upgrades a decoded version i+1 config to version i+2.
+var migrations = []func(m map[string]any){
+	// 1 -> 2: "loadedFromFile" was runtime state saved by mistake.
+	func(m map[string]any) { delete(m, "loadedFromFile") },
+	// 2 -> 3: "exceptions" moved to "allow.cuesContaining", next to the other allowlists.
+	func(m map[string]any) {
+		list, _ := m["exceptions"].([]any)
+		delete(m, "exceptions")
+		if len(list) == 0 {
+			return
+		}
+		allow, _ := m["allow"].(map[string]any)
+		if allow == nil {
+			allow = map[string]any{}
+			m["allow"] = allow
+		}
+		have, _ := allow["cuesContaining"].([]any)
+		allow["cuesContaining"] = append(have, list...)
+	},
+}
+
+// configVersion reads "version" from a decoded config (1 when absent).
+func configVersion(m map[string]any) int {
+	n, ok := m["version"].(json.Number)
+	if !ok {
+		return 1
+	}
+	v, err := n.Int64()
+	if err != nil {
+		return 1
+	}
+	return int(v)
+}
+
+// upgrade migrates a decoded, valid config to ConfigVersion in place and returns the
+// version it had.
+func upgrade(m map[string]any) int {
+	from := configVersion(m)
+	for v := from; v < ConfigVersion; v++ {
+		migrations[v-1](m)
+	}
+	m["version"] = ConfigVersion
+	return from
+}
+
+// Migrate validates data and rewrites it in the current format. It returns the
+// indented JSON and the version data had.
+func Migrate(data []byte) ([]byte, int, error) {
+	if err := Validate(data); err != nil {
+		return nil, 0, err
+	}
+	m, err := decodeObject(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	from := upgrade(m)
+	out, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, 0, fmt.Errorf("marshal config: %w", err)
+	}
+	return append(out, '\n'), from, nil
+}
@@ -0,0 +1,73 @@
+package rules
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// DefaultPreset is the base of every config that does not name one ("extends", --preset).
+const DefaultPreset = "standard"
+
+// presets are the built-in rule sets a config can extend.
+var presets = map[string]func() Config{
+	// Bracketed sound descriptions only, eg: [door slams]. Speaker labels, music and
+	// all-caps lines are kept.
+	"light": func() Config {
+		c := DefaultConfig()
+		c.RemoveTextBeforeColonIfUppercase = false
+		c.RemoveSingleLineColon = false
+		c.RemoveBetweenDelimiters = []Delimiter{{Left: "[", Right: "]"}}
+		c.RemoveLineIfContains = ""
+		c.RemoveOnlySymbolsLine = false
+		return c
+	},
+	// The built-in defaults.
+	"standard": DefaultConfig,
+	// Everything that looks like HI: any text before a colon, all-caps action lines and
+	// song lyrics between ♪ or ♫.
+	"aggressive": func() Config {
+		c := DefaultConfig()
+		c.RemoveTextBeforeColonIfUppercase = false // it takes precedence over the any-case rule
+		c.RemoveTextBeforeColon = true
+		c.RemoveLineIfAllCapsAction = true
+		c.RemoveBetweenDelimiters = append(c.RemoveBetweenDelimiters,
+			Delimiter{Left: "♪", Right: "♪"},
+			Delimiter{Left: "♫", Right: "♫"},
+			Delimiter{Left: "#", Right: "#"},
+		)
+		return c
+	},
+	// SDH to plain subtitles as in the Netflix Timed Text Style Guide: no speaker IDs,
+	// sound effects or caption-only lines, but lyrics stay (they are subtitled in plain
+	// tracks when plot pertinent).
+	"netflix-plain": func() Config {
+		c := DefaultConfig()
+		c.RemoveLineIfAllCapsAction = true
+		c.RemoveBetweenDelimiters = []Delimiter{{Left: "(", Right: ")"}, {Left: "[", Right: "]"}}
+		c.RemoveLineIfContains = ""
+		return c
+	},
+}
+
+// PresetNames returns the built-in preset names, sorted.
+func PresetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Preset returns the named rule set (case-insensitive) with Extends set to its name.
+func Preset(name string) (Config, error) {
+	key := strings.ToLower(strings.TrimSpace(name))
+	preset, ok := presets[key]
+	if !ok {
+		return Config{}, fmt.Errorf("unknown preset %q (available: %s)", name, strings.Join(PresetNames(), ", "))
+	}
+	c := preset()
+	c.Extends = key
+	return c, nil
+}
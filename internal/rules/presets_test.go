@@ -0,0 +1,112 @@
+package rules
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestPresets(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range PresetNames() {
+		c, err := Preset(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := Validate(data); err != nil {
+			t.Fatalf("preset %s does not validate: %v", name, err)
+		}
+	}
+	std, _ := Preset("Standard")
+	if std.Extends != "standard" || !std.RemoveTextBeforeColonIfUppercase || len(std.RemoveBetweenDelimiters) != 3 {
+		t.Fatalf("standard preset: %+v", std)
+	}
+	light, _ := Preset("light")
+	if light.RemoveTextBeforeColonIfUppercase || light.RemoveSingleLineColon || len(light.RemoveBetweenDelimiters) != 1 {
+		t.Fatalf("light preset: %+v", light)
+	}
+	aggressive, _ := Preset("aggressive")
+	if !aggressive.RemoveTextBeforeColon || aggressive.RemoveTextBeforeColonIfUppercase || !aggressive.RemoveLineIfAllCapsAction ||
+		!slices.Contains(aggressive.RemoveBetweenDelimiters, Delimiter{Left: "♪", Right: "♪"}) {
+		t.Fatalf("aggressive preset: %+v", aggressive)
+	}
+	if _, err := Preset("heavy"); err == nil || !strings.Contains(err.Error(), "netflix-plain") {
+		t.Fatalf("Preset(heavy) error = %v", err)
+	}
+}
+
+func TestParseConfig_extends(t *testing.T) {
+	t.Parallel()
+
+	c, err := ParseConfig([]byte(`{"extends": "light", "removeSingleLineColon": true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Extends != "light" || !c.RemoveSingleLineColon || len(c.RemoveBetweenDelimiters) != 1 || c.RemoveOnlySymbolsLine {
+		t.Fatalf("extends light: %+v", c)
+	}
+	err = Validate([]byte(`{"extends": "heavy"}`))
+	if err == nil || !strings.Contains(err.Error(), `line 1, col 13: extends: unknown preset "heavy"`) {
+		t.Fatalf("Validate(unknown preset) = %v", err)
+	}
+}
+
+func TestMerge_extends(t *testing.T) {
+	t.Parallel()
+
+	user, _ := ParseLayer(LayerUser, []byte(`{"extends": "aggressive", "removeTextBeforeColon": false}`))
+	c, err := Merge(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Extends != "aggressive" || c.RemoveTextBeforeColon || !c.RemoveLineIfAllCapsAction {
+		t.Fatalf("user extends aggressive: %+v", c)
+	}
+	var flags Layer
+	flags.Name = LayerFlags
+	flags.Set("extends", "light")
+	if c, _ = Merge(user, flags); c.Extends != "light" || c.RemoveLineIfAllCapsAction || c.RemoveTextBeforeColon {
+		t.Fatalf("--preset light over user: %+v", c)
+	}
+	s := c.DescribeEffective()
+	for _, sub := range []string{"preset: light  [user, flags]", "removeLineIfAllCapsAction: false  [preset light]", "sources: preset light < user < flags"} {
+		if !strings.Contains(s, sub) {
+			t.Fatalf("DescribeEffective missing %q in:\n%s", sub, s)
+		}
+	}
+	flags.Set("extends", "heavy")
+	if _, err := Merge(user, flags); err == nil {
+		t.Fatal("expected error for an unknown preset")
+	}
+}
+
+func TestConfigSchemaFile_presets(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "wasm", "schema", "config.v3.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Properties struct {
+			Extends struct {
+				Enum []string `json:"enum"`
+			} `json:"extends"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	got := slices.Sorted(slices.Values(schema.Properties.Extends.Enum))
+	if !slices.Equal(got, PresetNames()) {
+		t.Fatalf("schema extends enum = %v, want %v", got, PresetNames())
+	}
+}
@@ -0,0 +1,470 @@
+package rules
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ValidationError is one problem found in a config document. Line and Col are 1-based
+// (Col counts runes) and point at the offending key or value.
+type ValidationError struct {
+	Path string `json:"path,omitempty"` // eg: removeBetweenDelimiters[1].left
+	Line int    `json:"line"`
+	Col  int    `json:"col"`
+	Msg  string `json:"message"`
+}
+
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("line %d, col %d: %s", e.Line, e.Col, e.Msg)
+	}
+	return fmt.Sprintf("line %d, col %d: %s: %s", e.Line, e.Col, e.Path, e.Msg)
+}
+
+// ValidationErrors lists every problem of a config document, in document order.
+type ValidationErrors []ValidationError
+
+func (errs ValidationErrors) Error() string {
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Validate checks data against the config schema (the shape of Config, see
+// wasm/schema/config.v3.schema.json): JSON syntax, unknown keys, value types, the
+// version and the regexes built from delimiters and forced patterns. It returns nil or
+// ValidationErrors.
+func Validate(data []byte) error {
+	v := &validator{data: data, dec: json.NewDecoder(bytes.NewReader(data)), pos: map[string]int{}, legacy: map[string]int{}}
+	v.dec.UseNumber()
+	v.walk(configSchema(), "", true)
+	if !v.failed {
+		if v.dec.More() {
+			v.errorAt(v.next(), "", "unexpected data after the config object")
+		}
+	}
+	if !v.failed {
+		v.checkValues()
+	}
+	if len(v.errs) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(v.errs, func(a, b ValidationError) int {
+		if a.Line != b.Line {
+			return a.Line - b.Line
+		}
+		return a.Col - b.Col
+	})
+	return v.errs
+}
+
+// schemaNode is the expected shape of a JSON value, derived from the Config types.
+type schemaNode struct {
+	kind     reflect.Kind // Bool, String, Int, Float64, Slice or Struct
+	nullable bool
+	elem     *schemaNode            // Slice
+	fields   map[string]*schemaNode // Struct, by JSON name
+	names    []string               // Struct, JSON names in declaration order
+}
+
+var configSchema = sync.OnceValue(func() *schemaNode {
+	return schemaOf(reflect.TypeFor[Config]())
+})
+
+func schemaOf(t reflect.Type) *schemaNode {
+	n := &schemaNode{}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+		n.nullable = true
+	}
+	switch t.Kind() {
+	case reflect.Slice:
+		n.kind, n.nullable, n.elem = reflect.Slice, true, schemaOf(t.Elem())
+	case reflect.Struct:
+		n.kind, n.fields = reflect.Struct, map[string]*schemaNode{}
+		for i := range t.NumField() {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" || !f.IsExported() {
+				continue
+			}
+			n.fields[name] = schemaOf(f.Type)
+			n.names = append(n.names, name)
+		}
+	case reflect.Int:
+		n.kind = reflect.Int
+	case reflect.Float64:
+		n.kind = reflect.Float64
+	default:
+		n.kind = t.Kind()
+	}
+	return n
+}
+
+func (n *schemaNode) typeName() string {
+	switch n.kind {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Int:
+		return "an integer"
+	case reflect.Float64:
+		return "a number"
+	case reflect.Slice:
+		return "an array"
+	default:
+		return "an object"
+	}
+}
+
+// removedKeys are the top-level keys of older versions, still read (and migrated) in
+// files of those versions.
+var removedKeys = map[string]struct {
+	version int // the first version without the key
+	node    *schemaNode
+	msg     string
+}{
+	"loadedFromFile": {2, &schemaNode{kind: reflect.Bool}, "removed in version 2"},
+	"exceptions": {3, &schemaNode{kind: reflect.Slice, nullable: true, elem: &schemaNode{kind: reflect.String}},
+		`moved to "allow.cuesContaining" in version 3`},
+}
+
+type validator struct {
+	data   []byte
+	dec    *json.Decoder
+	errs   ValidationErrors
+	failed bool           // syntax error: stop walking
+	pos    map[string]int // offset of each value by path
+	legacy map[string]int // offset of each removedKeys key
+}
+
+// next is the offset of the next token (InputOffset may sit before separators).
+func (v *validator) next() int {
+	off := int(v.dec.InputOffset())
+	for off < len(v.data) && strings.IndexByte(" \t\r\n,:", v.data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+func (v *validator) errorAt(off int, path, msg string) {
+	line, col := lineCol(v.data, off)
+	v.errs = append(v.errs, ValidationError{Path: path, Line: line, Col: col, Msg: msg})
+}
+
+// token reads the next token, recording a syntax error (and stopping) on failure.
+func (v *validator) token() (json.Token, bool) {
+	if v.failed {
+		return nil, false
+	}
+	tok, err := v.dec.Token()
+	if err != nil {
+		off := v.next()
+		var syn *json.SyntaxError
+		if errors.As(err, &syn) {
+			off = int(syn.Offset) - 1 // the offending byte
+		}
+		msg := err.Error()
+		if err == io.EOF {
+			msg = "unexpected end of JSON"
+		}
+		v.errorAt(off, "", msg)
+		v.failed = true
+		return nil, false
+	}
+	return tok, true
+}
+
+// walk validates the next value against n.
+func (v *validator) walk(n *schemaNode, path string, top bool) {
+	off := v.next()
+	v.pos[path] = off
+	tok, ok := v.token()
+	if !ok {
+		return
+	}
+	got := ""
+	switch t := tok.(type) {
+	case json.Delim:
+		switch {
+		case t == '{' && n.kind == reflect.Struct:
+			v.object(n, path, top)
+			return
+		case t == '[' && n.kind == reflect.Slice:
+			for i := 0; v.dec.More() && !v.failed; i++ {
+				v.walk(n.elem, fmt.Sprintf("%s[%d]", path, i), false)
+			}
+			v.token() // ]
+			return
+		case t == '{':
+			got = "an object"
+		default:
+			got = "an array"
+		}
+		v.skipRest(t)
+	case nil:
+		if n.nullable {
+			return
+		}
+		got = "null"
+	case bool:
+		if n.kind == reflect.Bool {
+			return
+		}
+		got = "a boolean"
+	case string:
+		if n.kind == reflect.String {
+			return
+		}
+		got = "a string"
+	case json.Number:
+		if n.kind == reflect.Float64 || n.kind == reflect.Int && !strings.ContainsAny(t.String(), ".eE") {
+			return
+		}
+		got = "a number"
+		if n.kind == reflect.Int {
+			got = "a fractional number"
+		}
+	}
+	if top {
+		v.errorAt(off, path, "the config must be a JSON object")
+		return
+	}
+	v.errorAt(off, path, fmt.Sprintf("expected %s, got %s", n.typeName(), got))
+}
+
+// object validates the keys of an object whose '{' was just read.
+func (v *validator) object(n *schemaNode, path string, top bool) {
+	for v.dec.More() && !v.failed {
+		off := v.next()
+		tok, ok := v.token()
+		if !ok {
+			return
+		}
+		key, _ := tok.(string)
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+		field, known := n.fields[key]
+		switch {
+		case known:
+			v.walk(field, keyPath, false)
+		case top && key == "$schema":
+			v.walk(&schemaNode{kind: reflect.String}, keyPath, false)
+		case top && removedKeys[key].node != nil:
+			v.legacy[key] = off
+			v.walk(removedKeys[key].node, keyPath, false)
+		default:
+			msg := "unknown key"
+			if s := suggest(key, n.names); s != "" {
+				msg += fmt.Sprintf(" (did you mean %q?)", s)
+			}
+			v.errorAt(off, keyPath, msg)
+			if tok, ok := v.token(); ok {
+				v.skipRest(tok)
+			}
+		}
+	}
+	v.token() // }
+}
+
+// skipRest consumes the rest of a value whose first token was tok.
+func (v *validator) skipRest(tok json.Token) {
+	depth := 0
+	if d, ok := tok.(json.Delim); ok && (d == '{' || d == '[') {
+		depth = 1
+	}
+	for depth > 0 && !v.failed {
+		tok, ok := v.token()
+		if !ok {
+			return
+		}
+		if d, ok := tok.(json.Delim); ok {
+			if d == '{' || d == '[' {
+				depth++
+			} else {
+				depth--
+			}
+		}
+	}
+}
+
+// checkValues validates the decoded values of a structurally valid document.
+func (v *validator) checkValues() {
+	var doc struct {
+		Config
+		Version    *int     `json:"version"`
+		Exceptions []string `json:"exceptions"` // version 2
+	}
+	if err := json.Unmarshal(v.data, &doc); err != nil {
+		// Type mismatches were reported by walk; check what did decode.
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			if len(v.errs) == 0 {
+				v.errorAt(0, "", err.Error())
+			}
+			return
+		}
+	}
+	at := func(path string) int { return v.pos[path] }
+
+	version := 1
+	if doc.Version != nil {
+		version = *doc.Version
+		if version < 1 || version > ConfigVersion {
+			v.errorAt(at("version"), "version", fmt.Sprintf("unsupported version %d (this build reads 1 to %d)", version, ConfigVersion))
+		}
+	}
+	for key, off := range v.legacy {
+		if removed := removedKeys[key]; version >= removed.version {
+			v.errorAt(off, key, removed.msg)
+		}
+	}
+	if doc.Extends != "" {
+		if _, err := Preset(doc.Extends); err != nil {
+			v.errorAt(at("extends"), "extends", err.Error())
+		}
+	}
+	for i, d := range doc.RemoveBetweenDelimiters {
+		p := fmt.Sprintf("removeBetweenDelimiters[%d]", i)
+		switch {
+		case d.Left == "" || d.Right == "":
+			v.errorAt(at(p), p, `"left" and "right" are required and not empty`)
+		default:
+			if _, err := regexp.Compile(d.Pattern()); err != nil {
+				v.errorAt(at(p), p, fmt.Sprintf("invalid delimiters: %v", err))
+			}
+		}
+	}
+	for i, e := range doc.Exceptions {
+		if strings.TrimSpace(e) == "" {
+			p := fmt.Sprintf("exceptions[%d]", i)
+			v.errorAt(at(p), p, "blank exception")
+		}
+	}
+	if a := doc.Allow; a != nil {
+		for i, phrase := range a.Phrases {
+			if strings.TrimSpace(phrase) == "" {
+				p := fmt.Sprintf("allow.phrases[%d]", i)
+				v.errorAt(at(p), p, "blank phrase")
+			}
+		}
+		for i, phrase := range a.CuesContaining {
+			if strings.TrimSpace(phrase) == "" {
+				p := fmt.Sprintf("allow.cuesContaining[%d]", i)
+				v.errorAt(at(p), p, "blank phrase")
+			}
+		}
+		for i, pattern := range a.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				p := fmt.Sprintf("allow.patterns[%d]", i)
+				v.errorAt(at(p), p, fmt.Sprintf("invalid regex: %v", err))
+			}
+		}
+		for i, c := range a.Cues {
+			p := fmt.Sprintf("allow.cues[%d]", i)
+			if _, err := filepath.Match(c.File, ""); c.File == "" || err != nil {
+				v.errorAt(at(p), p, fmt.Sprintf(`"file" must be a file name or glob, got %q`, c.File))
+			}
+			for j, index := range c.Cues {
+				if index < 1 {
+					q := fmt.Sprintf("%s.cues[%d]", p, j)
+					v.errorAt(at(q), q, "cue indexes start at 1")
+				}
+			}
+		}
+	}
+	if doc.Forced != nil {
+		for i, pattern := range doc.Forced.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				p := fmt.Sprintf("forced.patterns[%d]", i)
+				v.errorAt(at(p), p, fmt.Sprintf("invalid regex: %v", err))
+			}
+		}
+	}
+	if h := doc.Hook; h != nil {
+		for i, lp := range h.Presets {
+			p := fmt.Sprintf("hook.presets[%d]", i)
+			if _, err := Preset(lp.Preset); err != nil {
+				v.errorAt(at(p+".preset"), p+".preset", err.Error())
+			}
+			for j, lang := range lp.Languages {
+				if strings.TrimSpace(lang) == "" {
+					q := fmt.Sprintf("%s.languages[%d]", p, j)
+					v.errorAt(at(q), q, "blank language")
+				}
+			}
+		}
+		if strings.ContainsAny(h.Backup, `/\`) {
+			v.errorAt(at("hook.backup"), "hook.backup", "must be a file name suffix, eg: .bak")
+		}
+	}
+	if l := doc.Lint; l != nil {
+		for _, f := range []struct {
+			key   string
+			value float64
+		}{
+			{"maxCps", l.MaxCPS}, {"maxCpl", float64(l.MaxCPL)}, {"maxLines", float64(l.MaxLines)},
+			{"minDurationMs", float64(l.MinDurationMs)}, {"maxDurationMs", float64(l.MaxDurationMs)}, {"minGapMs", float64(l.MinGapMs)},
+		} {
+			if f.value < 0 {
+				v.errorAt(at("lint."+f.key), "lint."+f.key, "must not be negative")
+			}
+		}
+	}
+}
+
+// lineCol converts a byte offset of data to a 1-based line and rune column.
+func lineCol(data []byte, off int) (line, col int) {
+	off = min(max(off, 0), len(data))
+	before := data[:off]
+	line = bytes.Count(before, []byte("\n")) + 1
+	return line, utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:]) + 1
+}
+
+// suggest returns the candidate closest to key (case-insensitive edit distance of at
+// most a third of its length), or "".
+func suggest(key string, candidates []string) string {
+	best, bestDist := "", len(key)/3+1
+	for _, c := range candidates {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(c)); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,222 @@
+package rules
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		data string
+		want []string // Error() of each ValidationError
+	}{
+		{name: "defaults", data: mustMarshal(t, DefaultConfig())},
+		{name: "version 1 with loadedFromFile", data: `{"loadedFromFile": true, "removeTextBeforeColon": true}`},
+		{name: "schema reference", data: `{"$schema": "config.v3.schema.json", "version": 3}`},
+		{
+			name: "typo",
+			data: "{\n  \"removeBetweenDelimeters\": [{\"left\": \"(\", \"right\": \")\"}]\n}",
+			want: []string{`line 2, col 3: removeBetweenDelimeters: unknown key (did you mean "removeBetweenDelimiters"?)`},
+		},
+		{
+			name: "types",
+			data: "{\"removeTextBeforeColon\": \"yes\",\n \"lint\": {\"maxCpl\": 42.5, \"preset\": 1},\n \"exceptions\": null}",
+			want: []string{
+				"line 1, col 27: removeTextBeforeColon: expected a boolean, got a string",
+				"line 2, col 21: lint.maxCpl: expected an integer, got a fractional number",
+				"line 2, col 37: lint.preset: expected a string, got a number",
+			},
+		},
+		{
+			name: "nested unknown key",
+			data: `{"extract": {"sidecar": true, "skipSDH": true}}`,
+			want: []string{`line 1, col 31: extract.skipSDH: unknown key (did you mean "skipSdh"?)`},
+		},
+		{
+			name: "values",
+			data: "{\"version\": 2, \"loadedFromFile\": false,\n \"removeBetweenDelimiters\": [{\"left\": \"(\"}],\n \"forced\": {\"patterns\": [\"(in\"]}, \"lint\": {\"minGapMs\": -1}}",
+			want: []string{
+				"line 1, col 16: loadedFromFile: removed in version 2",
+				`line 2, col 30: removeBetweenDelimiters[0]: "left" and "right" are required and not empty`,
+				"line 3, col 26: forced.patterns[0]: invalid regex: error parsing regexp: missing closing ): `(in`",
+				"line 3, col 56: lint.minGapMs: must not be negative",
+			},
+		},
+		{
+			name: "allow",
+			data: `{"allow": {"phrases": ["NOTE:", " "], "patterns": ["(?i)get out[", "ok"], "cues": [{"file": "a[.srt", "cues": [3, 0]}]}}`,
+			want: []string{
+				"line 1, col 33: allow.phrases[1]: blank phrase",
+				"line 1, col 52: allow.patterns[0]: invalid regex: error parsing regexp: missing closing ]: `[`",
+				`line 1, col 84: allow.cues[0]: "file" must be a file name or glob, got "a[.srt"`,
+				"line 1, col 115: allow.cues[0].cues[1]: cue indexes start at 1",
+			},
+		},
+		{
+			name: "hook",
+			data: `{"hook": {"presets": [{"languages": ["ja", ""], "preset": "gentle"}], "backup": "../orig"}}`,
+			want: []string{
+				"line 1, col 44: hook.presets[0].languages[1]: blank language",
+				`line 1, col 59: hook.presets[0].preset: unknown preset "gentle" (available: aggressive, light, netflix-plain, standard)`,
+				"line 1, col 81: hook.backup: must be a file name suffix, eg: .bak",
+			},
+		},
+		{
+			name: "version 2 with exceptions",
+			data: `{"version": 2, "exceptions": ["MR. T:", " "]}`,
+			want: []string{"line 1, col 41: exceptions[1]: blank exception"},
+		},
+		{
+			name: "exceptions in version 3",
+			data: `{"version": 3, "exceptions": ["MR. T:"], "allow": {"cuesContaining": [""]}}`,
+			want: []string{
+				`line 1, col 16: exceptions: moved to "allow.cuesContaining" in version 3`,
+				"line 1, col 71: allow.cuesContaining[0]: blank phrase",
+			},
+		},
+		{name: "future version", data: `{"version": 4}`, want: []string{"line 1, col 13: version: unsupported version 4 (this build reads 1 to 3)"}},
+		{name: "not an object", data: `["x"]`, want: []string{"line 1, col 1: the config must be a JSON object"}},
+		{name: "syntax", data: "{\n\"removeTextBeforeColon\": tru}", want: []string{"line 2, col 29: invalid character '}' in literal true (expecting 'e')"}},
+		{name: "trailing data", data: `{} {}`, want: []string{"line 1, col 4: unexpected data after the config object"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			err := Validate([]byte(tc.data))
+			var got []string
+			var verrs ValidationErrors
+			if errors.As(err, &verrs) {
+				for _, e := range verrs {
+					got = append(got, e.Error())
+				}
+			} else if err != nil {
+				t.Fatalf("Validate error is %T, want ValidationErrors", err)
+			}
+			if !slices.Equal(got, tc.want) {
+				t.Fatalf("Validate errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tc.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestParseConfig_rejectsUnknownKeys(t *testing.T) {
+	t.Parallel()
+
+	if _, err := ParseConfig([]byte(`{"removeBetweenDelimeters": []}`)); err == nil {
+		t.Fatal("expected error for a misspelled key")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	out, from, err := Migrate([]byte(`{"loadedFromFile": true, "removeLineIfContains": "x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from != 1 {
+		t.Fatalf("from = %d, want 1", from)
+	}
+	var m map[string]any
+	if err := json.Unmarshal(out, &m); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m["loadedFromFile"]; ok || m["version"] != float64(ConfigVersion) || m["removeLineIfContains"] != "x" {
+		t.Fatalf("migrated config: %s", out)
+	}
+	if err := Validate(out); err != nil {
+		t.Fatalf("migrated config does not validate: %v", err)
+	}
+	if _, from, _ := Migrate(out); from != ConfigVersion {
+		t.Fatalf("migrating a current config: from = %d", from)
+	}
+
+	out, from, err = Migrate([]byte(`{"version": 2, "exceptions": ["MR. T:"], "allow": {"phrases": ["NOTE:"], "cuesContaining": ["[Dr. House]"]}}`))
+	if err != nil || from != 2 {
+		t.Fatalf("Migrate version 2: from %d, %v", from, err)
+	}
+	c, err := ParseConfig(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(c.Allow.CuesContaining, []string{"[Dr. House]", "MR. T:"}) || !slices.Equal(c.Allow.Phrases, []string{"NOTE:"}) {
+		t.Fatalf("migrated allow: %+v\n%s", c.Allow, out)
+	}
+}
+
+// The published schema must describe exactly the keys and types Validate accepts.
+func TestConfigSchemaFile(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile(filepath.Join("..", "..", "wasm", "schema", "config.v3.schema.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+	defs, _ := schema["$defs"].(map[string]any)
+	compareSchema(t, "", schema, configSchema(), defs)
+}
+
+func compareSchema(t *testing.T, path string, js map[string]any, n *schemaNode, defs map[string]any) {
+	t.Helper()
+	if ref, ok := js["$ref"].(string); ok {
+		js, _ = defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]any)
+	}
+	want := map[string]string{
+		"bool": "boolean", "string": "string", "int": "integer", "float64": "number", "slice": "array", "struct": "object",
+	}[n.kind.String()]
+	types := []string{}
+	switch tv := js["type"].(type) {
+	case string:
+		types = append(types, tv)
+	case []any:
+		for _, s := range tv {
+			types = append(types, s.(string))
+		}
+	}
+	if !slices.Contains(types, want) {
+		t.Fatalf("%s: schema type %v, want %s", path, js["type"], want)
+	}
+	switch n.kind.String() {
+	case "slice":
+		items, _ := js["items"].(map[string]any)
+		compareSchema(t, path+"[]", items, n.elem, defs)
+	case "struct":
+		props, _ := js["properties"].(map[string]any)
+		for _, name := range n.names {
+			p, ok := props[name].(map[string]any)
+			if !ok {
+				t.Fatalf("%s: schema has no property %q", path, name)
+			}
+			compareSchema(t, path+"."+name, p, n.fields[name], defs)
+		}
+		for name := range props {
+			if _, ok := n.fields[name]; !ok && name != "$schema" {
+				t.Fatalf("%s: schema property %q is not in Config", path, name)
+			}
+		}
+		if js["additionalProperties"] != false {
+			t.Fatalf("%s: schema must set additionalProperties false", path)
+		}
+	}
+}
+
+func mustMarshal(t *testing.T, v any) string {
+	t.Helper()
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
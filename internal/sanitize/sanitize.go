@@ -1,6 +1,8 @@
 package sanitize
 
 import (
+	"io"
+
 	"github.com/luismascotto/subtitle-sanitizer/internal/model"
 	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
 	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
@@ -48,3 +50,24 @@ func ParseAndApplyRules(raw []byte, format model.SubtitleFormat, r transform.Rul
 	}
 	return ApplyRules(*doc, r), nil
 }
+
+// Stream reads cues from src, applies prepared Rules over a bounded window of cues and writes
+// SRT to dst, so memory stays flat regardless of file size. onChange, when non-nil, receives
+// every change in cue order. Returns the number of cues written.
+func Stream(src io.Reader, dst io.Writer, format model.SubtitleFormat, r transform.Rules, window int, onChange func(transform.CueChange)) (int, error) {
+	sw := subtitle.NewSRTWriter(dst)
+	for o, err := range transform.ApplyWindowed(subtitle.ReadCues(src, format), format, r, window) {
+		if err != nil {
+			return sw.Count(), err
+		}
+		if o.Change != nil && onChange != nil {
+			onChange(*o.Change)
+		}
+		if o.Cue != nil {
+			if err := sw.WriteCue(o.Cue); err != nil {
+				return sw.Count(), err
+			}
+		}
+	}
+	return sw.Count(), sw.Flush()
+}
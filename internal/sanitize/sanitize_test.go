@@ -1,10 +1,14 @@
 package sanitize
 
 import (
+	"bytes"
+	"strings"
 	"testing"
 
 	"github.com/luismascotto/subtitle-sanitizer/internal/model"
 	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
 )
 
 func TestParseAndApply_srt(t *testing.T) {
@@ -40,3 +44,93 @@ func TestApply_roundTripCue(t *testing.T) {
 		t.Fatalf("cues: %d", len(res.Document.Cues))
 	}
 }
+
+func TestStream_matchesApplyRules(t *testing.T) {
+	raw := `1
+00:00:01,000 --> 00:00:02,000
+Hello (x) world
+
+2
+00:00:03,000 --> 00:00:04,000
+[noise]
+
+3
+00:00:05,000 --> 00:00:06,000
+Plain
+`
+	r := transform.NewRules(rules.DefaultConfig())
+	want, err := ParseAndApplyRules([]byte(raw), model.SubtitleFormatSRT, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	var changes []transform.CueChange
+	n, err := Stream(strings.NewReader(raw), &out, model.SubtitleFormatSRT, r, 2, func(c transform.CueChange) {
+		changes = append(changes, c)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || out.String() != string(want.SRT) {
+		t.Fatalf("n=%d streamed:\n%s\nwant:\n%s", n, out.String(), want.SRT)
+	}
+	if len(changes) != len(want.Changes) {
+		t.Fatalf("changes %d, want %d", len(changes), len(want.Changes))
+	}
+}
+
+func TestSelect_rejectAndEdit(t *testing.T) {
+	doc := model.Document{
+		Format: model.SubtitleFormatSRT,
+		Cues: []*model.Cue{
+			{Index: 1, Start: 0, End: 1e9, Lines: "[DOOR SLAMS]"},
+			{Index: 2, Start: 2e9, End: 3e9, Lines: "JOHN: Hello"},
+			{Index: 3, Start: 4e9, End: 5e9, Lines: "(Dr. House) is in"},
+			{Index: 4, Start: 6e9, End: 7e9, Lines: "untouched"},
+		},
+	}
+	res := Apply(doc, rules.DefaultConfig())
+	if len(res.Changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", res.Changes)
+	}
+
+	// All accepted equals the rules output.
+	all := Select(doc, res.Changes, AcceptAll(res.Changes))
+	if got, want := string(subtitle.FormatSRT(all)), string(res.SRT); got != want {
+		t.Fatalf("accept all:\n%s\nwant:\n%s", got, want)
+	}
+
+	decisions := AcceptAll(res.Changes)
+	decisions[1].Text = "Hello!" // edited
+	decisions[2].Accept = false  // false positive
+	out := Select(doc, res.Changes, decisions)
+	var lines []string
+	for _, c := range out.Cues {
+		lines = append(lines, c.Lines)
+	}
+	if got := strings.Join(lines, "|"); got != "Hello!|(Dr. House) is in|untouched" {
+		t.Fatalf("selected cues: %s", got)
+	}
+}
+
+func TestApply_cuesContaining(t *testing.T) {
+	conf := rules.DefaultConfig()
+	conf.AllowCuesContaining("(Dr. House)")
+	doc := model.Document{
+		Format: model.SubtitleFormatSRT,
+		Cues: []*model.Cue{
+			{Index: 1, Start: 0, End: 1e9, Lines: "(Dr. House) is in"},
+			{Index: 2, Start: 2e9, End: 3e9, Lines: "(sighs) fine"},
+		},
+	}
+	res := Apply(doc, conf)
+	if len(res.Changes) != 2 || len(res.Changes[0].Rules) != 0 || res.Changes[1].CueIndex != 2 {
+		t.Fatalf("changes: %+v", res.Changes)
+	}
+	if s := res.Changes[0].Suppressed; len(s) != 1 || s[0].Reason != `allow cues containing "(Dr. House)"` {
+		t.Fatalf("suppressed: %+v", s)
+	}
+	if res.Document.Cues[0].Lines != "(Dr. House) is in" {
+		t.Fatalf("allowed cue changed: %q", res.Document.Cues[0].Lines)
+	}
+}
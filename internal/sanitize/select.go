@@ -0,0 +1,57 @@
+package sanitize
+
+import (
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+// Decision is the reviewer's verdict on one CueChange.
+type Decision struct {
+	Accept bool
+	Text   string // text written when accepted: Transformed, or the reviewer's edit
+}
+
+// AcceptAll returns one accepting Decision per change, keeping the transformed text.
+func AcceptAll(changes []transform.CueChange) []Decision {
+	decisions := make([]Decision, len(changes))
+	for i, ch := range changes {
+		decisions[i] = Decision{Accept: true, Text: ch.Transformed}
+	}
+	return decisions
+}
+
+// Select rebuilds the output from the original doc applying only accepted changes
+// (decisions[i] belongs to changes[i]). Rejected and unchanged cues keep their original
+// text, converted to SRT tags as the rules would; accepted changes with blank text drop
+// the cue.
+func Select(doc model.Document, changes []transform.CueChange, decisions []Decision) model.Document {
+	byCue := make(map[int]Decision, len(changes))
+	for i, ch := range changes {
+		if i < len(decisions) {
+			byCue[ch.CueIndex] = decisions[i]
+		}
+	}
+
+	out := model.Document{
+		Format: doc.Format,
+		Header: doc.Header,
+		Cues:   make([]*model.Cue, 0, len(doc.Cues)),
+	}
+	for _, cue := range doc.Cues {
+		text := transform.SRTText(cue.Lines, doc.Format)
+		if d, ok := byCue[cue.Index]; ok && d.Accept {
+			text = d.Text
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		if text == cue.Lines {
+			out.Cues = append(out.Cues, cue)
+			continue
+		}
+		out.Cues = append(out.Cues, &model.Cue{Index: cue.Index, Start: cue.Start, End: cue.End, Lines: text})
+	}
+	return out
+}
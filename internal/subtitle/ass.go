@@ -1,6 +1,7 @@
 package subtitle
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"strconv"
@@ -20,31 +21,16 @@ const (
 
 // ParseASS minimal, only Dialogue Event lines are parsed
 func ParseASS(data []byte) (*model.Document, error) {
-
-	blocks := splitASSBlocks(data)
 	cues := []*model.Cue{}
-	var err error
-	for _, blk := range blocks {
-		if len(blk) == 0 {
-			continue
-		}
-		// For now, only parse [Events] block
-		if !strings.Contains(blk[0], "[Events]") {
-			continue
-		}
-		cues, err = parseASSEventsBlock(blk)
+	for cue, err := range ReadASS(bytes.NewReader(data)) {
 		if err != nil {
 			return nil, err
 		}
-		break
+		cues = append(cues, cue)
 	}
 	if len(cues) == 0 {
 		return nil, errors.New("no cues found")
 	}
-	// renumber indices from 1..N
-	for i := range cues {
-		cues[i].Index = i + 1
-	}
 	return &model.Document{
 		Format: model.SubtitleFormatASS,
 		//Header: header,
@@ -52,73 +38,29 @@ func ParseASS(data []byte) (*model.Document, error) {
 	}, nil
 }
 
-func splitASSBlocks(data []byte) [][]string {
-	// Normalize newlines
-	s := strings.ReplaceAll(string(data), "\r\n", "\n")
-	s = strings.ReplaceAll(s, "\x00", "\n")
-	out := make([][]string, 0)
-	for p := range strings.SplitSeq(s, "\n\n") {
-		trimmed := make([]string, 0)
-		for l := range strings.SplitSeq(p, "\n") {
-			trimmed = append(trimmed, strings.TrimRight(l, " \t"))
-		}
-		// Drop leading/trailing empty lines in each block
-		for len(trimmed) > 0 && strings.TrimSpace(trimmed[0]) == "" {
-			trimmed = trimmed[1:]
-		}
-		for len(trimmed) > 0 && strings.TrimSpace(trimmed[len(trimmed)-1]) == "" {
-			trimmed = trimmed[:len(trimmed)-1]
-		}
-		if len(trimmed) > 0 {
-			out = append(out, trimmed)
-		}
-	}
-	return out
-}
-
-func parseASSEventsBlock(blocks []string) ([]*model.Cue, error) {
-	if len(blocks) < 3 {
-		return nil, errors.New("events block too short")
-	}
-	// First line is [Events]
-	_, _ = strconv.Atoi(strings.TrimSpace(blocks[0])) // ignore parsing errors; some files omit or duplicate
-	dialoguesStartIndex := 1
-	for dialoguesStartIndex < len(blocks) && !strings.Contains(blocks[dialoguesStartIndex], "Dialogue:") {
-		dialoguesStartIndex++
-	}
-	if dialoguesStartIndex >= len(blocks) {
-		return nil, errors.New("no dialogue lines found")
+// parseASSDialogue parses one "Dialogue:" event line.
+// Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+// Dialogue: 0,0:00:04.87,0:00:06.00,Default,,0,0,0,,[Tommy]\NThe president of every bank,
+func parseASSDialogue(dialogue string) (*model.Cue, error) {
+	// Split into 10 parts. May exist commas in text, so use SplitN. to dont need to join the text later.
+	parts := strings.SplitN(dialogue, ",", 10)
+	if len(parts) < 10 {
+		return nil, fmt.Errorf("invalid dialogue line: %s", dialogue)
+	}
+	// Parse timing
+	start, err := parseASSTime(parts[assDialogueLineStartIndex])
+	if err != nil {
+		return nil, fmt.Errorf("parse start timing: %w", err)
 	}
-	cues := make([]*model.Cue, 0, len(blocks)-dialoguesStartIndex)
-	// Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
-	// Dialogue: 0,0:00:04.87,0:00:06.00,Default,,0,0,0,,[Tommy]\NThe president of every bank,
-	for index, dialogue := range blocks[dialoguesStartIndex:] {
-		if strings.TrimSpace(dialogue) == "" {
-			continue
-		}
-		// Split into 10 parts. May exist commas in text, so use SplitN. to dont need to join the text later.
-		parts := strings.SplitN(dialogue, ",", 10)
-		if len(parts) < 10 {
-			return nil, fmt.Errorf("invalid dialogue line: %s", dialogue)
-		}
-		// Parse timing
-		start, err := parseASSTime(parts[assDialogueLineStartIndex])
-		if err != nil {
-			return nil, fmt.Errorf("parse start timing: %w", err)
-		}
-		end, err := parseASSTime(parts[assDialogueLineEndIndex])
-		if err != nil {
-			return nil, fmt.Errorf("parse timing: %w", err)
-		}
-
-		cues = append(cues, &model.Cue{
-			Index: index + 1,
-			Start: start,
-			End:   end,
-			Lines: strings.ReplaceAll(strings.TrimSpace(parts[textIndex]), "\\N", "\n"),
-		})
+	end, err := parseASSTime(parts[assDialogueLineEndIndex])
+	if err != nil {
+		return nil, fmt.Errorf("parse timing: %w", err)
 	}
-	return cues, nil
+	return &model.Cue{
+		Start: start,
+		End:   end,
+		Lines: strings.ReplaceAll(strings.TrimSpace(parts[textIndex]), "\\N", "\n"),
+	}, nil
 }
 
 func parseASSTime(s string) (time.Duration, error) {
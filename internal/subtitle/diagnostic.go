@@ -0,0 +1,32 @@
+package subtitle
+
+import "fmt"
+
+// Severity grades a parse diagnostic. Only SeverityError fails a strict parse.
+type Severity string
+
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// Diagnostic describes something the parser tolerated, repaired or dropped.
+type Diagnostic struct {
+	Line     int      `json:"line"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("line %d: %s: %s", d.Line, d.Severity, d.Message)
+}
+
+func firstError(diags []Diagnostic) (Diagnostic, bool) {
+	for _, d := range diags {
+		if d.Severity == SeverityError {
+			return d, true
+		}
+	}
+	return Diagnostic{}, false
+}
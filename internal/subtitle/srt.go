@@ -2,31 +2,37 @@ package subtitle
 
 import (
 	"bytes"
-	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/luismascotto/subtitle-sanitizer/internal/model"
 )
 
-// ParseSRT parses minimal, common SRT. Best-effort if ignoreMinorErrors is true.
+// ParseSRT parses common SRT, including minor format variants. Best-effort if ignoreMinorErrors
+// is true; otherwise the first error diagnostic is returned as an error.
 func ParseSRT(data []byte, ignoreMinorErrors bool) (*model.Document, error) {
-	blocks := splitSRTBlocks(data)
-	cues := make([]*model.Cue, 0, len(blocks))
-	for _, blk := range blocks {
-		if len(blk) == 0 {
-			continue
-		}
-		cue, err := parseSRTBlock(blk)
-		if err != nil {
-			if ignoreMinorErrors {
-				continue
-			}
-			return nil, err
+	doc, _, err := ParseSRTDiagnostics(data, ignoreMinorErrors)
+	return doc, err
+}
+
+// ParseSRTDiagnostics is ParseSRT that also returns every diagnostic (tolerated variants,
+// dropped text) with its 1-based line number.
+func ParseSRTDiagnostics(data []byte, ignoreMinorErrors bool) (*model.Document, []Diagnostic, error) {
+	cues := make([]*model.Cue, 0, bytes.Count(data, []byte("-->")))
+	p := newSRTParser(func(c *model.Cue) { cues = append(cues, c) })
+
+	s := strings.ReplaceAll(string(data), "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\x00", "\n")
+	for line := range strings.SplitSeq(s, "\n") {
+		p.feed(line)
+	}
+	p.finish()
+
+	if !ignoreMinorErrors {
+		if d, ok := firstError(p.diags); ok {
+			return nil, p.diags, fmt.Errorf("srt line %d: %s", d.Line, d.Message)
 		}
-		cues = append(cues, cue)
 	}
 	// renumber indices from 1..N
 	for i := range cues {
@@ -36,104 +42,12 @@ func ParseSRT(data []byte, ignoreMinorErrors bool) (*model.Document, error) {
 		Format: model.SubtitleFormatSRT,
 		//Header: header,
 		Cues: cues,
-	}, nil
-}
-
-func splitSRTBlocks(data []byte) [][]string {
-	// Normalize newlines
-	s := strings.ReplaceAll(string(data), "\r\n", "\n")
-	s = strings.ReplaceAll(s, "\x00", "\n")
-	out := make([][]string, 0)
-	for p := range strings.SplitSeq(s, "\n\n") {
-		trimmed := make([]string, 0)
-		for l := range strings.SplitSeq(p, "\n") {
-			trimmed = append(trimmed, strings.TrimRight(l, " \t"))
-		}
-		// Drop leading/trailing empty lines in each block
-		for len(trimmed) > 0 && strings.TrimSpace(trimmed[0]) == "" {
-			trimmed = trimmed[1:]
-		}
-		for len(trimmed) > 0 && strings.TrimSpace(trimmed[len(trimmed)-1]) == "" {
-			trimmed = trimmed[:len(trimmed)-1]
-		}
-		if len(trimmed) > 0 {
-			out = append(out, trimmed)
-		}
-	}
-	return out
+	}, p.diags, nil
 }
 
-func parseSRTBlock(lines []string) (*model.Cue, error) {
-	if len(lines) < 2 {
-		return nil, errors.New("srt block too short")
-	}
-	// First line is (usually) index
-	index, _ := strconv.Atoi(strings.TrimSpace(lines[0])) // ignore parsing errors; some files omit or duplicate
-	// Second line is timing
-	start, end, err := parseSRTTimingLine(lines[1])
-	if err != nil {
-		return nil, fmt.Errorf("srt parse timing: %w", err)
-	}
-	//textLines := append([]string{}, lines[2:]...)
-	textLines := strings.Join(lines[2:], "\n")
-	return &model.Cue{
-		Index: index,
-		Start: start,
-		End:   end,
-		Lines: textLines,
-	}, nil
-}
-
-func parseSRTTimingLine(line string) (time.Duration, time.Duration, error) {
-	// Example: 00:00:01,234 --> 00:00:04,567
-	parts := strings.Split(line, "-->")
-	if len(parts) != 2 {
-		return 0, 0, errors.New("invalid timing separator")
-	}
-	startStr := strings.TrimSpace(parts[0])
-	endStr := strings.TrimSpace(parts[1])
-	start, err := parseSRTTime(startStr)
-	if err != nil {
-		return 0, 0, fmt.Errorf("start time: %w", err)
-	}
-	end, err := parseSRTTime(endStr)
-	if err != nil {
-		return 0, 0, fmt.Errorf("end time: %w", err)
-	}
-	return start, end, nil
-}
-
-func parseSRTTime(s string) (time.Duration, error) {
-	// HH:MM:SS,mmm
-	hmsMillis := strings.Split(s, ",")
-	if len(hmsMillis) != 2 {
-		return 0, errors.New("missing millis")
-	}
-	hms := strings.Split(hmsMillis[0], ":")
-	if len(hms) != 3 {
-		return 0, errors.New("invalid h:m:s")
-	}
-	h, err := strconv.Atoi(hms[0])
-	if err != nil {
-		return 0, err
-	}
-	m, err := strconv.Atoi(hms[1])
-	if err != nil {
-		return 0, err
-	}
-	si, err := strconv.Atoi(hms[2])
-	if err != nil {
-		return 0, err
-	}
-	ms, err := strconv.Atoi(hmsMillis[1])
-	if err != nil {
-		return 0, err
-	}
-	total := time.Duration(h)*time.Hour +
-		time.Duration(m)*time.Minute +
-		time.Duration(si)*time.Second +
-		time.Duration(ms)*time.Millisecond
-	return total, nil
+// FormatTimecode renders d as an SRT timecode (HH:MM:SS,mmm); negative values clamp to zero.
+func FormatTimecode(d time.Duration) string {
+	return formatSRTTime(d)
 }
 
 func formatSRTTime(d time.Duration) string {
@@ -153,24 +67,10 @@ func formatSRTTime(d time.Duration) string {
 // FormatSRT renders a document to SRT and renumbers cues from 1..N.
 func FormatSRT(doc model.Document) []byte {
 	var buf bytes.Buffer
-	index := 1
+	sw := NewSRTWriter(&buf)
 	for _, cue := range doc.Cues {
-		hasText := strings.TrimSpace(cue.Lines) != ""
-		if !hasText {
-			continue
-		}
-		if index > 1 {
-			buf.WriteString("\n")
-		}
-		buf.WriteString(strconv.Itoa(index))
-		buf.WriteString("\n")
-		buf.WriteString(formatSRTTime(cue.Start))
-		buf.WriteString(" --> ")
-		buf.WriteString(formatSRTTime(cue.End))
-		buf.WriteString("\n")
-		buf.WriteString(cue.Lines)
-		buf.WriteString("\n")
-		index++
+		_ = sw.WriteCue(cue) // bytes.Buffer writes do not fail
 	}
+	_ = sw.Flush()
 	return buf.Bytes()
 }
@@ -0,0 +1,246 @@
+package subtitle
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+)
+
+var (
+	// reSRTTiming matches a timing line anywhere in the input: "start --> end [rest]".
+	// Timestamps are validated by parseSRTTime so variants (dots, missing zeros) still match here.
+	reSRTTiming = regexp.MustCompile(`^\s*([0-9][0-9:.,]*)\s*-{1,2}>\s*([0-9][0-9:.,]*)(.*)$`)
+	// reSRTCoordinates matches the legacy position suffix "X1:40 X2:600 Y1:20 Y2:50".
+	reSRTCoordinates = regexp.MustCompile(`^(?i)(\s*[XY][12]\s*:\s*\d+)+\s*$`)
+	// reSRTCanonicalTime is the strict HH:MM:SS,mmm form; anything else parses with a warning.
+	reSRTCanonicalTime = regexp.MustCompile(`^\d{2}:\d{2}:\d{2},\d{3}$`)
+	reSRTIndex         = regexp.MustCompile(`^\d+$`)
+)
+
+// srtParser is a line-based state machine. Timing lines start a cue wherever they appear;
+// a numeric line is held back until the next line tells whether it is an index or cue text.
+type srtParser struct {
+	emit  func(*model.Cue)
+	diags []Diagnostic
+
+	line     int        // 1-based number of the line being fed
+	cur      *model.Cue // cue being filled; nil before the first timing line or after a bad one
+	curLine  int        // line of cur's timing line
+	text     []string
+	blanks   int  // blank lines since the last content line
+	skipping bool // dropping text after an invalid timing line
+
+	held       string // numeric line waiting for its successor
+	heldLine   int
+	heldBlanks int // blank lines before the held line (blanks then counts those after it)
+}
+
+func newSRTParser(emit func(*model.Cue)) *srtParser {
+	return &srtParser{emit: emit}
+}
+
+func (p *srtParser) diag(line int, sev Severity, format string, a ...any) {
+	p.diags = append(p.diags, Diagnostic{Line: line, Severity: sev, Message: fmt.Sprintf(format, a...)})
+}
+
+// feed consumes one input line (without its newline).
+func (p *srtParser) feed(raw string) {
+	p.line++
+	if p.line == 1 {
+		raw = strings.TrimPrefix(raw, "\ufeff")
+	}
+	line := strings.TrimRight(raw, " \t\r")
+
+	if m := reSRTTiming.FindStringSubmatch(line); m != nil {
+		p.timing(m)
+		return
+	}
+	if strings.Contains(line, "-->") {
+		p.flush()
+		p.held = ""
+		p.diag(p.line, SeverityError, "invalid timing line %q; cue dropped", line)
+		p.skipping = true
+		return
+	}
+	if strings.TrimSpace(line) == "" {
+		p.blanks++
+		return
+	}
+	if trimmed := strings.TrimSpace(line); reSRTIndex.MatchString(trimmed) {
+		p.releaseHeld()
+		p.held, p.heldLine, p.heldBlanks = trimmed, p.line, p.blanks
+		p.blanks = 0
+		return
+	}
+	p.releaseHeld()
+	p.appendText(line, p.line)
+}
+
+// releaseHeld adds the held numeric line to the cue text: no timing line followed it.
+func (p *srtParser) releaseHeld() {
+	if p.held == "" {
+		return
+	}
+	after := p.blanks
+	p.blanks = p.heldBlanks
+	p.appendText(p.held, p.heldLine)
+	p.blanks = after
+	p.held = ""
+}
+
+// finish flushes the last cue; call once after the final feed.
+func (p *srtParser) finish() {
+	if p.held != "" && p.heldBlanks > 0 {
+		// A number on its own after a blank line at the end: the index of a cut-off cue.
+		p.diag(p.heldLine, SeverityWarning, "index line %q without timing dropped", p.held)
+		p.held = ""
+	}
+	p.releaseHeld()
+	p.flush()
+}
+
+func (p *srtParser) timing(m []string) {
+	start, err := p.timestamp(m[1])
+	if err == nil {
+		var end time.Duration
+		if end, err = p.timestamp(m[2]); err == nil {
+			p.startCue(start, end, strings.TrimSpace(m[3]))
+			return
+		}
+	}
+	p.flush()
+	p.held = ""
+	p.diag(p.line, SeverityError, "invalid timing line: %v; cue dropped", err)
+	p.skipping = true
+}
+
+func (p *srtParser) startCue(start, end time.Duration, rest string) {
+	hadIndex := p.held != ""
+	if p.cur != nil && !hadIndex && p.blanks == 0 && len(p.text) > 0 {
+		p.diag(p.line, SeverityWarning, "missing blank line before timing line")
+	}
+	p.flush()
+	p.held = ""
+	if !hadIndex {
+		p.diag(p.line, SeverityInfo, "cue without index line")
+	}
+	if rest != "" {
+		if reSRTCoordinates.MatchString(rest) {
+			p.diag(p.line, SeverityWarning, "position coordinates %q ignored", rest)
+		} else {
+			p.diag(p.line, SeverityWarning, "unexpected text after timing %q ignored", rest)
+		}
+	}
+	if end < start {
+		p.diag(p.line, SeverityWarning, "end time is before start time")
+	}
+	p.cur = &model.Cue{Start: start, End: end}
+	p.curLine = p.line
+	p.skipping = false
+}
+
+func (p *srtParser) appendText(line string, lineNo int) {
+	if p.cur == nil {
+		if !p.skipping {
+			p.diag(lineNo, SeverityError, "text outside of a cue dropped: %q", line)
+		}
+		p.blanks = 0
+		return
+	}
+	if p.blanks > 0 && len(p.text) > 0 {
+		p.diag(lineNo, SeverityInfo, "blank line inside cue text; paragraphs joined")
+	}
+	p.blanks = 0
+	p.text = append(p.text, line)
+}
+
+func (p *srtParser) flush() {
+	if p.cur == nil {
+		return
+	}
+	if len(p.text) == 0 {
+		p.diag(p.curLine, SeverityWarning, "cue has no text")
+	}
+	p.cur.Lines = strings.Join(p.text, "\n")
+	p.emit(p.cur)
+	p.cur = nil
+	p.text = nil
+	p.blanks = 0
+}
+
+// timestamp parses one timing value, warning on tolerated variants.
+func (p *srtParser) timestamp(s string) (time.Duration, error) {
+	d, err := parseSRTTime(s)
+	if err != nil {
+		return 0, fmt.Errorf("timestamp %q: %w", s, err)
+	}
+	if !reSRTCanonicalTime.MatchString(s) {
+		p.diag(p.line, SeverityWarning, "non-standard timestamp %q", s)
+	}
+	return d, nil
+}
+
+// parseSRTTime parses HH:MM:SS,mmm and tolerates '.' or ':' before the fraction,
+// missing leading zeros, a missing hours field (MM:SS,mmm) and short or long fractions.
+func parseSRTTime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	hms, frac, ok := cutLastAny(s, ",.")
+	if !ok {
+		// HH:MM:SS:mmm (colon before millis) seen in some broken exports
+		if strings.Count(s, ":") == 3 {
+			i := strings.LastIndexByte(s, ':')
+			hms, frac = s[:i], s[i+1:]
+		} else {
+			return 0, errors.New("missing millis")
+		}
+	}
+	parts := strings.Split(hms, ":")
+	if len(parts) == 2 {
+		parts = append([]string{"0"}, parts...)
+	}
+	if len(parts) != 3 {
+		return 0, errors.New("invalid h:m:s")
+	}
+	var fields [3]int
+	for i, part := range parts {
+		v, err := strconv.Atoi(part)
+		if err != nil || v < 0 {
+			return 0, errors.New("invalid h:m:s")
+		}
+		fields[i] = v
+	}
+	if fields[1] > 59 || fields[2] > 59 {
+		return 0, errors.New("minutes/seconds out of range")
+	}
+	if frac == "" {
+		return 0, errors.New("invalid millis")
+	}
+	if len(frac) > 3 {
+		frac = frac[:3]
+	}
+	ms, err := strconv.Atoi(frac)
+	if err != nil {
+		return 0, errors.New("invalid millis")
+	}
+	// Fractions shorter than 3 digits are decimal fractions of a second ("1,5" = 500ms).
+	for range 3 - len(frac) {
+		ms *= 10
+	}
+	return time.Duration(fields[0])*time.Hour +
+		time.Duration(fields[1])*time.Minute +
+		time.Duration(fields[2])*time.Second +
+		time.Duration(ms)*time.Millisecond, nil
+}
+
+func cutLastAny(s, chars string) (before, after string, found bool) {
+	i := strings.LastIndexAny(s, chars)
+	if i < 0 {
+		return s, "", false
+	}
+	return s[:i], s[i+1:], true
+}
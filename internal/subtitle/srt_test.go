@@ -0,0 +1,212 @@
+package subtitle
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+)
+
+func hasDiag(diags []Diagnostic, sev Severity, sub string) bool {
+	for _, d := range diags {
+		if d.Severity == sev && strings.Contains(d.Message, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseSRT_subExample(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "sub-example.srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, diags, err := ParseSRTDiagnostics(data, false)
+	if err != nil {
+		t.Fatalf("strict parse: %v (diags %v)", err, diags)
+	}
+	if len(doc.Cues) != 23 {
+		t.Fatalf("cues = %d, want 23", len(doc.Cues))
+	}
+	if got := doc.Cues[1].Lines; got != "[Nathan] <i>How long is\nyour family staying, Tommy?</i>" {
+		t.Fatalf("cue 2 lines = %q", got)
+	}
+	if len(diags) != 0 {
+		t.Fatalf("clean file should have no diagnostics, got %v", diags)
+	}
+}
+
+func TestParseSRT_multiParagraphCueKept(t *testing.T) {
+	raw := "1\n00:00:01,000 --> 00:00:02,000\nFirst part\n\nsecond part\n\n2\n00:00:03,000 --> 00:00:04,000\nNext\n"
+	doc, diags, err := ParseSRTDiagnostics([]byte(raw), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Cues) != 2 {
+		t.Fatalf("cues = %d, want 2", len(doc.Cues))
+	}
+	if doc.Cues[0].Lines != "First part\nsecond part" {
+		t.Fatalf("first cue = %q", doc.Cues[0].Lines)
+	}
+	if !hasDiag(diags, SeverityInfo, "paragraphs joined") {
+		t.Fatalf("want paragraph diagnostic, got %v", diags)
+	}
+}
+
+func TestParseSRT_numericTextLineIsNotAnIndex(t *testing.T) {
+	raw := "1\n00:00:01,000 --> 00:00:02,000\nRoom\n101\n\n2\n00:00:03,000 --> 00:00:04,000\nNext\n"
+	doc, err := ParseSRT([]byte(raw), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Cues) != 2 || doc.Cues[0].Lines != "Room\n101" {
+		t.Fatalf("cues: %+v", doc.Cues)
+	}
+}
+
+func TestParseSRT_numericTextLineAtEnd(t *testing.T) {
+	tests := []struct {
+		raw, want string
+		joined    bool // a blank line inside the cue text is reported
+	}{
+		{raw: "1\n00:00:01,000 --> 00:00:02,000\nRoom\n101\n", want: "Room\n101"},
+		{raw: "1\n00:00:01,000 --> 00:00:02,000\nRoom\n101\n\n", want: "Room\n101"},
+		{raw: "1\n00:00:01,000 --> 00:00:02,000\nRoom\n101", want: "Room\n101"},
+		{raw: "1\n00:00:01,000 --> 00:00:02,000\nRoom\n101\nis free\n", want: "Room\n101\nis free"},
+		{raw: "1\n00:00:01,000 --> 00:00:02,000\nRoom\n101\n\nis free\n", want: "Room\n101\nis free", joined: true},
+	}
+	for _, tt := range tests {
+		doc, diags, err := ParseSRTDiagnostics([]byte(tt.raw), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(doc.Cues) != 1 || doc.Cues[0].Lines != tt.want {
+			t.Fatalf("%q: cues: %+v", tt.raw, doc.Cues)
+		}
+		if hasDiag(diags, SeverityWarning, "without timing") {
+			t.Fatalf("%q: numeric text dropped: %v", tt.raw, diags)
+		}
+		var joined []Diagnostic
+		for _, d := range diags {
+			if strings.Contains(d.Message, "paragraphs joined") {
+				joined = append(joined, d)
+			}
+		}
+		if tt.joined != (len(joined) > 0) || tt.joined && joined[0].Line != 6 {
+			t.Fatalf("%q: diags: %v", tt.raw, diags)
+		}
+	}
+}
+
+func TestParseSRT_timingVariants(t *testing.T) {
+	tests := []struct {
+		name       string
+		timing     string
+		start, end time.Duration
+		diag       string
+	}{
+		{"dot separator", "00:00:01.250 --> 00:00:02.500", 1250 * time.Millisecond, 2500 * time.Millisecond, "non-standard timestamp"},
+		{"missing zeros", "0:0:1,5 --> 0:0:2,25", 1500 * time.Millisecond, 2250 * time.Millisecond, "non-standard timestamp"},
+		{"no hours", "00:01,000 --> 00:02,000", time.Second, 2 * time.Second, "non-standard timestamp"},
+		{"colon millis", "00:00:01:000 --> 00:00:02:000", time.Second, 2 * time.Second, "non-standard timestamp"},
+		{"coordinates", "00:00:01,000 --> 00:00:02,000 X1:100 X2:600 Y1:20 Y2:50", time.Second, 2 * time.Second, "position coordinates"},
+		{"short arrow", "00:00:01,000 -> 00:00:02,000", time.Second, 2 * time.Second, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := "1\n" + tt.timing + "\nHello\n"
+			doc, diags, err := ParseSRTDiagnostics([]byte(raw), false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(doc.Cues) != 1 {
+				t.Fatalf("cues = %d", len(doc.Cues))
+			}
+			c := doc.Cues[0]
+			if c.Start != tt.start || c.End != tt.end || c.Lines != "Hello" {
+				t.Fatalf("cue = %+v", c)
+			}
+			if tt.diag != "" && !hasDiag(diags, SeverityWarning, tt.diag) {
+				t.Fatalf("want warning %q, got %v", tt.diag, diags)
+			}
+		})
+	}
+}
+
+func TestParseSRT_missingBlankLineAndIndex(t *testing.T) {
+	raw := "00:00:01,000 --> 00:00:02,000\nOne\n00:00:03,000 --> 00:00:04,000\nTwo\n"
+	doc, diags, err := ParseSRTDiagnostics([]byte(raw), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Cues) != 2 || doc.Cues[1].Lines != "Two" || doc.Cues[1].Index != 2 {
+		t.Fatalf("cues: %+v", doc.Cues)
+	}
+	if !hasDiag(diags, SeverityWarning, "missing blank line") || !hasDiag(diags, SeverityInfo, "without index") {
+		t.Fatalf("diags: %v", diags)
+	}
+}
+
+func TestParseSRT_invalidTimingDropsCue(t *testing.T) {
+	raw := "1\n00:00:01,000 --> nonsense\nLost\n\n2\n00:00:03,000 --> 00:00:04,000\nKept\n"
+	doc, diags, err := ParseSRTDiagnostics([]byte(raw), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Cues) != 1 || doc.Cues[0].Lines != "Kept" {
+		t.Fatalf("cues: %+v", doc.Cues)
+	}
+	if len(diags) != 1 || diags[0].Line != 2 || diags[0].Severity != SeverityError {
+		t.Fatalf("want one error on line 2, got %v", diags)
+	}
+
+	if _, err := ParseSRT([]byte(raw), false); err == nil || !strings.Contains(err.Error(), "srt line 2") {
+		t.Fatalf("strict parse should fail on line 2, got %v", err)
+	}
+}
+
+func TestParseSRT_orphanTextAndBOM(t *testing.T) {
+	raw := "\ufeffstray text\n\n1\n00:00:01,000 --> 00:00:02,000\nHi\r\n"
+	doc, diags, err := ParseSRTDiagnostics([]byte(raw), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Cues) != 1 || doc.Cues[0].Lines != "Hi" {
+		t.Fatalf("cues: %+v", doc.Cues)
+	}
+	if !hasDiag(diags, SeverityError, "text outside of a cue") || diags[0].Line != 1 {
+		t.Fatalf("diags: %v", diags)
+	}
+}
+
+func TestParseSRT_trailingIndexDropped(t *testing.T) {
+	raw := "1\n00:00:01,000 --> 00:00:02,000\nHi\n\n2\n"
+	doc, diags, err := ParseSRTDiagnostics([]byte(raw), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Cues) != 1 || doc.Cues[0].Lines != "Hi" {
+		t.Fatalf("cues: %+v", doc.Cues)
+	}
+	if !hasDiag(diags, SeverityWarning, "without timing") {
+		t.Fatalf("diags: %v", diags)
+	}
+}
+
+func TestFormatSRT_roundTrip(t *testing.T) {
+	doc := model.Document{Format: model.SubtitleFormatSRT, Cues: []*model.Cue{
+		{Start: time.Second, End: 2 * time.Second, Lines: "One"},
+		{Start: 3 * time.Second, End: 4*time.Second + 5*time.Millisecond, Lines: "Two\nlines"},
+	}}
+	out := FormatSRT(doc)
+	back, err := ParseSRT(out, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(FormatSRT(*back)) != string(out) {
+		t.Fatalf("round trip mismatch:\n%s\nvs\n%s", FormatSRT(*back), out)
+	}
+}
@@ -0,0 +1,172 @@
+package subtitle
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+)
+
+// ReadCues streams cues from r in the given format (SRT is read best-effort).
+// Memory use is bounded by the longest cue, not the file size.
+func ReadCues(r io.Reader, format model.SubtitleFormat) iter.Seq2[*model.Cue, error] {
+	switch format {
+	case model.SubtitleFormatSRT:
+		return ReadSRT(r, true)
+	case model.SubtitleFormatASS:
+		return ReadASS(r)
+	default:
+		return func(yield func(*model.Cue, error) bool) {
+			yield(nil, errors.New("unsupported subtitle format"))
+		}
+	}
+}
+
+// ReadSRT streams SRT cues numbered 1..N. Unless ignoreMinorErrors is set, the first error
+// diagnostic is yielded as an error and iteration stops.
+func ReadSRT(r io.Reader, ignoreMinorErrors bool) iter.Seq2[*model.Cue, error] {
+	return func(yield func(*model.Cue, error) bool) {
+		var ready []*model.Cue
+		p := newSRTParser(func(c *model.Cue) { ready = append(ready, c) })
+		index := 0
+		drain := func() bool {
+			for _, c := range ready {
+				index++
+				c.Index = index
+				if !yield(c, nil) {
+					return false
+				}
+			}
+			ready = ready[:0]
+			if !ignoreMinorErrors {
+				if d, ok := firstError(p.diags); ok {
+					yield(nil, fmt.Errorf("srt line %d: %s", d.Line, d.Message))
+					return false
+				}
+			}
+			// Diagnostics are not retained while streaming; keep memory flat.
+			p.diags = p.diags[:0]
+			return true
+		}
+
+		stopped := false
+		err := readLines(r, func(line string) bool {
+			// NUL bytes are treated as line breaks, same as ParseSRT.
+			for part := range strings.SplitSeq(line, "\x00") {
+				p.feed(part)
+			}
+			stopped = !drain()
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		p.finish()
+		drain()
+	}
+}
+
+// ReadASS streams "Dialogue:" events from the [Events] section, numbered 1..N.
+func ReadASS(r io.Reader) iter.Seq2[*model.Cue, error] {
+	return func(yield func(*model.Cue, error) bool) {
+		inEvents := false
+		index := 0
+		stopped := false
+		err := readLines(r, func(line string) bool {
+			line = strings.TrimSpace(strings.ReplaceAll(line, "\x00", ""))
+			if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+				inEvents = strings.EqualFold(line, "[Events]")
+				return true
+			}
+			if !inEvents || !strings.HasPrefix(line, "Dialogue:") {
+				return true
+			}
+			cue, err := parseASSDialogue(line)
+			if err != nil {
+				yield(nil, err)
+				stopped = true
+				return false
+			}
+			index++
+			cue.Index = index
+			if !yield(cue, nil) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
+
+// readLines calls fn for each line of r (without "\n" or "\r\n") until fn returns false.
+func readLines(r io.Reader, fn func(line string) bool) error {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" || err == nil {
+			line = strings.TrimSuffix(line, "\n")
+			line = strings.TrimSuffix(line, "\r")
+			if !fn(line) {
+				return nil
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// SRTWriter streams cues as SRT, numbering them from 1 and skipping cues without text.
+// Call Flush after the last cue.
+type SRTWriter struct {
+	w     *bufio.Writer
+	index int
+}
+
+// NewSRTWriter returns a buffered SRT writer on w.
+func NewSRTWriter(w io.Writer) *SRTWriter {
+	return &SRTWriter{w: bufio.NewWriter(w)}
+}
+
+// WriteCue appends one cue; cues with only whitespace are dropped.
+func (sw *SRTWriter) WriteCue(cue *model.Cue) error {
+	if strings.TrimSpace(cue.Lines) == "" {
+		return nil
+	}
+	sw.index++
+	if sw.index > 1 {
+		sw.w.WriteString("\n")
+	}
+	sw.w.WriteString(strconv.Itoa(sw.index))
+	sw.w.WriteString("\n")
+	sw.w.WriteString(formatSRTTime(cue.Start))
+	sw.w.WriteString(" --> ")
+	sw.w.WriteString(formatSRTTime(cue.End))
+	sw.w.WriteString("\n")
+	sw.w.WriteString(cue.Lines)
+	_, err := sw.w.WriteString("\n")
+	return err
+}
+
+// Count returns the number of cues written so far.
+func (sw *SRTWriter) Count() int { return sw.index }
+
+// Flush writes any buffered data to the underlying writer.
+func (sw *SRTWriter) Flush() error {
+	return sw.w.Flush()
+}
@@ -0,0 +1,138 @@
+package subtitle
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+)
+
+func collect(t *testing.T, seq func(func(*model.Cue, error) bool)) []*model.Cue {
+	t.Helper()
+	var cues []*model.Cue
+	for c, err := range seq {
+		if err != nil {
+			t.Fatal(err)
+		}
+		cues = append(cues, c)
+	}
+	return cues
+}
+
+func TestReadSRT_matchesParseSRT(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "sub-example.srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseSRT(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collect(t, ReadSRT(bytes.NewReader(data), true))
+	if len(got) != len(doc.Cues) {
+		t.Fatalf("streamed %d cues, parsed %d", len(got), len(doc.Cues))
+	}
+	for i := range got {
+		if *got[i] != *doc.Cues[i] {
+			t.Fatalf("cue %d: streamed %+v, parsed %+v", i, got[i], doc.Cues[i])
+		}
+	}
+}
+
+func TestReadSRT_strictStopsOnError(t *testing.T) {
+	raw := "1\n00:00:01,000 --> 00:00:02,000\nOne\n\n2\n00:00:03,000 --> bad\nTwo\n"
+	var cues int
+	var gotErr error
+	for c, err := range ReadSRT(strings.NewReader(raw), false) {
+		if err != nil {
+			gotErr = err
+			break
+		}
+		if c != nil {
+			cues++
+		}
+	}
+	if gotErr == nil || !strings.Contains(gotErr.Error(), "srt line 6") {
+		t.Fatalf("want error on line 6, got %v", gotErr)
+	}
+	if cues != 1 {
+		t.Fatalf("want first cue before the error, got %d", cues)
+	}
+}
+
+func TestReadSRT_earlyBreak(t *testing.T) {
+	raw := strings.Repeat("1\n00:00:01,000 --> 00:00:02,000\nx\n\n", 10)
+	n := 0
+	for range ReadSRT(strings.NewReader(raw), true) {
+		n++
+		if n == 3 {
+			break
+		}
+	}
+	if n != 3 {
+		t.Fatalf("n = %d", n)
+	}
+}
+
+func TestReadASS_matchesParseASS(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "sub-example.ass"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseASS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collect(t, ReadCues(bytes.NewReader(data), model.SubtitleFormatASS))
+	if len(got) != len(doc.Cues) || len(got) != 63 {
+		t.Fatalf("streamed %d cues, parsed %d, want 63", len(got), len(doc.Cues))
+	}
+	if got[62].Index != 63 {
+		t.Fatalf("last index = %d", got[62].Index)
+	}
+}
+
+func TestReadASS_invalidDialogue(t *testing.T) {
+	raw := "[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,broken\n"
+	for _, err := range ReadASS(strings.NewReader(raw)) {
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		return
+	}
+	t.Fatal("expected one error")
+}
+
+func TestSRTWriter_matchesFormatSRT(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "sub-example.srt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := ParseSRT(data, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Cues = append(doc.Cues, &model.Cue{Lines: "  "}) // dropped, not numbered
+	var buf bytes.Buffer
+	sw := NewSRTWriter(&buf)
+	for c, err := range ReadSRT(bytes.NewReader(data), true) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := sw.WriteCue(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sw.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != string(FormatSRT(*doc)) {
+		t.Fatal("streamed SRT differs from FormatSRT")
+	}
+	if sw.Count() != 23 {
+		t.Fatalf("count = %d", sw.Count())
+	}
+}
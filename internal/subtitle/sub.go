@@ -2,6 +2,8 @@ package subtitle
 
 import (
 	"errors"
+	"path/filepath"
+	"strings"
 
 	"github.com/luismascotto/subtitle-sanitizer/internal/model"
 )
@@ -17,3 +19,15 @@ func Parse(data []byte, format model.SubtitleFormat) (*model.Document, error) {
 		return nil, errors.New("unsupported subtitle format")
 	}
 }
+
+// FormatFromPath maps a file extension (.srt, .ass, .ssa) to its subtitle format.
+func FormatFromPath(path string) model.SubtitleFormat {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".srt":
+		return model.SubtitleFormatSRT
+	case ".ass", ".ssa":
+		return model.SubtitleFormatASS
+	default:
+		return model.SubtitleFormatUnknown
+	}
+}
@@ -0,0 +1,178 @@
+package transform
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+)
+
+// Suppression is a rule that would have changed a cue but was not applied, and why
+// (eg: `allow phrase "NOTE:"`, `allow cues containing "MR. T:"`).
+type Suppression struct {
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+// allowRules is the compiled AllowConfig of Rules.
+type allowRules struct {
+	phrases    []string
+	patterns   []*regexp.Regexp
+	containing []string       // cues containing one of these are exempt
+	cues       map[int]string // set by ForFile: allowed cue index -> AllowCues.File
+}
+
+// compileAllow compiles conf; invalid patterns are skipped (rules.Validate reports them).
+func compileAllow(conf *rules.AllowConfig) allowRules {
+	var a allowRules
+	if conf == nil {
+		return a
+	}
+	a.containing = conf.CuesContaining
+	for _, p := range conf.Phrases {
+		if p != "" {
+			a.phrases = append(a.phrases, p)
+		}
+	}
+	for _, p := range conf.Patterns {
+		if re, err := regexp.Compile(p); err == nil {
+			a.patterns = append(a.patterns, re)
+		}
+	}
+	return a
+}
+
+// ForFile returns r with the allowed cues of the file at path (allow.cues entries
+// whose file matches its base name). Other rules are shared with r.
+func (r Rules) ForFile(path string) Rules {
+	r.allow.cues = nil
+	if r.conf.Allow == nil {
+		return r
+	}
+	for _, c := range r.conf.Allow.Cues {
+		if !c.Matches(path) {
+			continue
+		}
+		if r.allow.cues == nil {
+			r.allow.cues = make(map[int]string)
+		}
+		for _, index := range c.Cues {
+			r.allow.cues[index] = c.File
+		}
+	}
+	return r
+}
+
+// exempt returns why no rule may change the cue, or "" when rules apply.
+func (a allowRules) exempt(index int, text string) string {
+	if file, ok := a.cues[index]; ok {
+		return fmt.Sprintf("allow cue %d of %q", index, file)
+	}
+	if p := phraseIn(text, a.containing); p != "" {
+		return fmt.Sprintf("allow cues containing %q", p)
+	}
+	return ""
+}
+
+// lost returns why after may not replace before: a protected phrase or pattern match
+// of before occurs fewer times in after. It returns "" when nothing protected was lost.
+func (a allowRules) lost(before, after string) string {
+	for _, p := range a.phrases {
+		if strings.Count(after, p) < strings.Count(before, p) {
+			return fmt.Sprintf("allow phrase %q", p)
+		}
+	}
+	for _, re := range a.patterns {
+		for _, m := range re.FindAllString(before, -1) {
+			if m != "" && strings.Count(after, m) < strings.Count(before, m) {
+				return fmt.Sprintf("allow pattern %q", re.String())
+			}
+		}
+	}
+	return ""
+}
+
+// cueEdit is the text of one cue while rules apply, with the rules applied, their edits
+// and the rules the allowlist suppressed.
+type cueEdit struct {
+	allow      allowRules
+	text       string
+	applied    []string
+	edits      []Edit
+	suppressed []Suppression
+}
+
+// apply replaces the text with next unless that loses protected text, in which case the
+// rule is recorded as suppressed.
+func (e *cueEdit) apply(rule, pattern, next string) bool {
+	if !e.tidy(rule, pattern, next) {
+		return false
+	}
+	e.applied = append(e.applied, rule)
+	return true
+}
+
+// tidy is apply for the steps that do not count as a rule of their own (cleanup,
+// trimming): the text is replaced with next unless that loses protected text.
+func (e *cueEdit) tidy(rule, pattern, next string) bool {
+	if reason := e.allow.lost(e.text, next); reason != "" {
+		e.suppress(rule, reason)
+		return false
+	}
+	e.set(rule, pattern, next)
+	return true
+}
+
+// set replaces the text with next, recording the edits as made by rule.
+func (e *cueEdit) set(rule, pattern, next string) {
+	if next != e.text {
+		e.edits = append(e.edits, diffEdits(rule, pattern, e.text, next)...)
+		e.text = next
+	}
+}
+
+// suppress records rule as suppressed for reason, once.
+func (e *cueEdit) suppress(rule, reason string) {
+	s := Suppression{Rule: rule, Reason: reason}
+	if !slices.Contains(e.suppressed, s) {
+		e.suppressed = append(e.suppressed, s)
+	}
+}
+
+// replaceAll removes the matches of re from the text, keeping the ones whose removal
+// would lose protected text (rule is recorded as suppressed). It reports whether the
+// text changed.
+func (e *cueEdit) replaceAll(re *regexp.Regexp, rule string) bool {
+	var b strings.Builder
+	last, removed := 0, false
+	for _, m := range re.FindAllStringIndex(e.text, -1) {
+		if m[0] == m[1] {
+			continue
+		}
+		if reason := e.allow.lost(e.text, e.text[:m[0]]+e.text[m[1]:]); reason != "" {
+			e.suppress(rule, reason)
+			continue
+		}
+		b.WriteString(e.text[last:m[0]])
+		e.edits = append(e.edits, newEdit(rule, re.String(), b.String(), e.text[m[0]:m[1]], ""))
+		last, removed = m[1], true
+	}
+	if !removed {
+		return false
+	}
+	b.WriteString(e.text[last:])
+	e.text = b.String()
+	return true
+}
+
+// phraseIn returns the first of phrases text contains, or "".
+func phraseIn(text string, phrases []string) string {
+	for _, p := range phrases {
+		if p != "" && strings.Contains(text, p) {
+			return p
+		}
+	}
+	return ""
+}
@@ -0,0 +1,153 @@
+package transform
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+)
+
+func TestApplyAll_allow(t *testing.T) {
+	t.Parallel()
+
+	conf := rules.DefaultConfig()
+	conf.RemoveLineIfAllCapsAction = true
+	conf.Allow = &rules.AllowConfig{
+		Phrases:        []string{"NOTE:", "He said:", "Dr. House"},
+		Patterns:       []string{`GET OUT( NOW)?`},
+		CuesContaining: []string{"MR. T"},
+		Cues:           []rules.AllowCues{{File: "*.en.srt", Cues: []int{7}}},
+	}
+	doc := model.Document{
+		Format: model.SubtitleFormatSRT,
+		Cues: []*model.Cue{
+			{Index: 1, Lines: "NOTE: the door is open"},
+			{Index: 2, Lines: "GET OUT NOW"},
+			{Index: 3, Lines: "He said:\nGo home."},
+			{Index: 4, Lines: "(Dr. House) (sighs) Hello"},
+			{Index: 5, Lines: "PHONE RINGS"},
+			{Index: 6, Lines: "MR. T: Hi"},
+			{Index: 7, Lines: "GUARD: Stop!"},
+		},
+	}
+
+	tests := []struct {
+		file       string
+		want       map[int]string // kept cue text by index
+		rules      map[int][]string
+		suppressed map[int][]Suppression
+	}{
+		{
+			file: "Movie.en.srt",
+			want: map[int]string{
+				1: "NOTE: the door is open", 2: "GET OUT NOW", 3: "He said:\nGo home.",
+				4: "(Dr. House) Hello", 6: "MR. T: Hi", 7: "GUARD: Stop!",
+			},
+			rules: map[int][]string{
+				4: {"\\ Delims / ( )"},
+				5: {string(rules.RuleRemoveLineIfAllCapsAction)},
+			},
+			suppressed: map[int][]Suppression{
+				1: {{Rule: string(rules.RuleRemoveTextBeforeColonIfUppercase), Reason: `allow phrase "NOTE:"`}},
+				2: {{Rule: string(rules.RuleRemoveLineIfAllCapsAction), Reason: `allow pattern "GET OUT( NOW)?"`}},
+				3: {{Rule: string(rules.RuleRemoveSingleLineColon), Reason: `allow phrase "He said:"`}},
+				4: {{Rule: "\\ Delims / ( )", Reason: `allow phrase "Dr. House"`}},
+				6: {{Rule: string(rules.RuleRemoveTextBeforeColonIfUppercase), Reason: `allow cues containing "MR. T"`}},
+				7: {{Rule: string(rules.RuleRemoveTextBeforeColonIfUppercase), Reason: `allow cue 7 of "*.en.srt"`}},
+			},
+		},
+		{
+			file: "Movie.es.srt",
+			want: map[int]string{
+				1: "NOTE: the door is open", 2: "GET OUT NOW", 3: "He said:\nGo home.",
+				4: "(Dr. House) Hello", 6: "MR. T: Hi", 7: "Stop!",
+			},
+			rules: map[int][]string{
+				4: {"\\ Delims / ( )"},
+				5: {string(rules.RuleRemoveLineIfAllCapsAction)},
+				7: {string(rules.RuleRemoveTextBeforeColonIfUppercase)},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.file, func(t *testing.T) {
+			t.Parallel()
+			out, changes := ApplyAllWithRules(doc, NewRules(conf).ForFile("/media/"+tc.file))
+
+			if len(out.Cues) != len(tc.want) {
+				t.Fatalf("kept %d cues, want %d", len(out.Cues), len(tc.want))
+			}
+			for _, c := range out.Cues {
+				if c.Lines != tc.want[c.Index] {
+					t.Fatalf("cue %d = %q, want %q", c.Index, c.Lines, tc.want[c.Index])
+				}
+			}
+			for _, ch := range changes {
+				if want := tc.rules[ch.CueIndex]; !slices.Equal(ch.Rules, want) {
+					t.Fatalf("cue %d rules = %q, want %q", ch.CueIndex, ch.Rules, want)
+				}
+				if want, ok := tc.suppressed[ch.CueIndex]; ok && !slices.Equal(ch.Suppressed, want) {
+					t.Fatalf("cue %d suppressed = %+v, want %+v", ch.CueIndex, ch.Suppressed, want)
+				}
+				if ch.Applied() != (len(tc.rules[ch.CueIndex]) > 0) {
+					t.Fatalf("cue %d: Applied() = %t", ch.CueIndex, ch.Applied())
+				}
+			}
+		})
+	}
+}
+
+// Lines without letters or digits are dropped by the cleanup after a rule applied
+// unless they hold protected text.
+func TestApplyAll_allowSymbolLines(t *testing.T) {
+	t.Parallel()
+
+	conf := rules.DefaultConfig()
+	conf.Allow = &rules.AllowConfig{Phrases: []string{"♪ ♪", "--"}}
+	doc := model.Document{
+		Format: model.SubtitleFormatSRT,
+		Cues: []*model.Cue{
+			{Index: 1, Lines: "♪ ♪\n(sighs) Hello"},
+			{Index: 2, Lines: "--\nMAN: Wait"},
+			{Index: 3, Lines: "♪\n(sighs) Bye"},
+		},
+	}
+	want := map[int]string{1: "♪ ♪\nHello", 2: "--\nWait", 3: "Bye"}
+	suppressed := map[int][]Suppression{
+		1: {{Rule: string(rules.RuleCleanup), Reason: `allow phrase "♪ ♪"`}},
+		2: {{Rule: string(rules.RuleCleanup), Reason: `allow phrase "--"`}},
+	}
+
+	out, changes := ApplyAllWithRules(doc, NewRules(conf))
+	for _, c := range out.Cues {
+		if c.Lines != want[c.Index] {
+			t.Fatalf("cue %d = %q, want %q", c.Index, c.Lines, want[c.Index])
+		}
+	}
+	if len(changes) != 3 {
+		t.Fatalf("changes = %+v", changes)
+	}
+	for _, ch := range changes {
+		if !ch.Applied() || !slices.Equal(ch.Suppressed, suppressed[ch.CueIndex]) {
+			t.Fatalf("cue %d: rules %q, suppressed %+v, want %+v", ch.CueIndex, ch.Rules, ch.Suppressed, suppressed[ch.CueIndex])
+		}
+	}
+}
+
+func TestApplyForced_allow(t *testing.T) {
+	t.Parallel()
+
+	conf := rules.Config{Allow: &rules.AllowConfig{Phrases: []string{"in Klingon"}}}
+	doc := model.Document{
+		Format: model.SubtitleFormatSRT,
+		Cues:   []*model.Cue{{Index: 1, Lines: "[in Klingon] Qapla'"}, {Index: 2, Lines: "[in Spanish] Hola"}},
+	}
+	out, changes := ApplyForced(doc, NewRules(conf))
+	if len(out.Cues) != 2 || out.Cues[0].Lines != "[in Klingon] Qapla'" || out.Cues[1].Lines != "Hola" {
+		t.Fatalf("forced cues: %+v", out.Cues)
+	}
+	if len(changes[0].Suppressed) != 1 || changes[0].Suppressed[0].Rule != string(rules.RuleForcedPattern) {
+		t.Fatalf("suppressed: %+v", changes[0].Suppressed)
+	}
+}
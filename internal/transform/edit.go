@@ -0,0 +1,147 @@
+package transform
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/diff"
+)
+
+// Edit is one replacement a rule made in a cue's text: Old, at bytes [Start, End) (runes
+// [RuneStart, RuneEnd)) of the text as it was when the rule ran, became New. Line is the
+// 1-based line Start is on and Pattern the regex or phrase that matched, when the rule
+// has one. Replaying the edits of a CueChange in order on its base text (SRTText of
+// Original) yields Transformed; see Replay and Revert.
+type Edit struct {
+	Rule      string `json:"rule"`
+	Pattern   string `json:"pattern,omitempty"`
+	Line      int    `json:"line"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	RuneStart int    `json:"runeStart"`
+	RuneEnd   int    `json:"runeEnd"`
+	Old       string `json:"old"`
+	New       string `json:"new,omitempty"`
+}
+
+// Span is a range of a change's base text that an edit removed or replaced, for
+// highlighting: bytes [Start, End), runes [RuneStart, RuneEnd).
+type Span struct {
+	Rule      string `json:"rule"`
+	Start     int    `json:"start"`
+	End       int    `json:"end"`
+	RuneStart int    `json:"runeStart"`
+	RuneEnd   int    `json:"runeEnd"`
+}
+
+// newEdit returns the edit replacing old with new right after prefix.
+func newEdit(rule, pattern, prefix, old, new string) Edit {
+	start, runeStart := len(prefix), utf8.RuneCountInString(prefix)
+	return Edit{
+		Rule:      rule,
+		Pattern:   pattern,
+		Line:      strings.Count(prefix, "\n") + 1,
+		Start:     start,
+		End:       start + len(old),
+		RuneStart: runeStart,
+		RuneEnd:   runeStart + utf8.RuneCountInString(old),
+		Old:       old,
+		New:       new,
+	}
+}
+
+// diffEdits returns the edits turning before into after, one per run of changed runes.
+func diffEdits(rule, pattern, before, after string) []Edit {
+	a, b := []rune(before), []rune(after)
+	script := diff.Script(a, b)
+	var edits []Edit
+	for i := 0; i < len(script); {
+		if script[i].Kind == diff.Equal {
+			i++
+			continue
+		}
+		// Earlier runs are applied: the text before this one is b[:bStart].
+		bStart := script[i].B
+		var old, new []rune
+		for ; i < len(script) && script[i].Kind != diff.Equal; i++ {
+			if script[i].Kind == diff.Delete {
+				old = append(old, a[script[i].A])
+			} else {
+				new = append(new, b[script[i].B])
+			}
+		}
+		edits = append(edits, newEdit(rule, pattern, string(b[:bStart]), string(old), string(new)))
+	}
+	return edits
+}
+
+// Replay applies edits in order to base (the SRT text of a change's Original) and
+// returns the transformed text. It fails when an edit's Old is not where it points.
+func Replay(base string, edits []Edit) (string, error) {
+	text := base
+	for i, e := range edits {
+		if e.Start < 0 || e.End > len(text) || e.Start > e.End || text[e.Start:e.End] != e.Old {
+			return "", fmt.Errorf("edit %d (%s): %q not found at %d", i+1, e.Rule, e.Old, e.Start)
+		}
+		text = text[:e.Start] + e.New + text[e.End:]
+	}
+	return text, nil
+}
+
+// Revert undoes edits, last first, on the transformed text and returns the base text.
+func Revert(transformed string, edits []Edit) (string, error) {
+	text := transformed
+	for i := len(edits) - 1; i >= 0; i-- {
+		e := edits[i]
+		end := e.Start + len(e.New)
+		if e.Start < 0 || end > len(text) || text[e.Start:end] != e.New {
+			return "", fmt.Errorf("edit %d (%s): %q not found at %d", i+1, e.Rule, e.New, e.Start)
+		}
+		text = text[:e.Start] + e.Old + text[end:]
+	}
+	return text, nil
+}
+
+// BaseSpans maps edits to the ranges of base they removed or replaced, in order, merging
+// adjacent ranges of the same rule. Text inserted by an edit and removed by a later one
+// has no range in base and is left out.
+func BaseSpans(base string, edits []Edit) []Span {
+	// origin[i] is the base byte offset of byte i of the current text (-1: inserted).
+	origin := make([]int, len(base))
+	for i := range origin {
+		origin[i] = i
+	}
+	removedBy := make([]string, len(base))
+	for _, e := range edits {
+		if e.Start < 0 || e.End > len(origin) || e.Start > e.End {
+			break
+		}
+		for _, o := range origin[e.Start:e.End] {
+			if o >= 0 {
+				removedBy[o] = e.Rule
+			}
+		}
+		inserted := make([]int, len(e.New))
+		for i := range inserted {
+			inserted[i] = -1
+		}
+		origin = append(origin[:e.Start:e.Start], append(inserted, origin[e.End:]...)...)
+	}
+
+	var spans []Span
+	runes := 0
+	for i := 0; i < len(base); {
+		_, size := utf8.DecodeRuneInString(base[i:])
+		if rule := removedBy[i]; rule != "" {
+			if n := len(spans); n > 0 && spans[n-1].End == i && spans[n-1].Rule == rule {
+				spans[n-1].End, spans[n-1].RuneEnd = i+size, runes+1
+			} else {
+				spans = append(spans, Span{Rule: rule, Start: i, End: i + size, RuneStart: runes, RuneEnd: runes + 1})
+			}
+		}
+		i += size
+		runes++
+	}
+	return spans
+}
@@ -0,0 +1,107 @@
+package transform
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+)
+
+func TestCueChange_edits(t *testing.T) {
+	t.Parallel()
+
+	conf := rules.DefaultConfig()
+	conf.RemoveBetweenDelimiters = append(conf.RemoveBetweenDelimiters, rules.Delimiter{Left: "♪", Right: "♪"})
+	doc := model.Document{
+		Format: model.SubtitleFormatSRT,
+		Cues:   []*model.Cue{{Index: 1, Lines: "KAREN: Olá (suspira) tudo bem?\n- ♪♪ la la ♪♪"}},
+	}
+	_, changes := ApplyAll(doc, conf)
+	if len(changes) != 1 {
+		t.Fatalf("changes: %+v", changes)
+	}
+	delims, notes := "\\ Delims / ( )", "\\ Delims / ♪ ♪"
+	want := []Edit{
+		{Rule: "TEXT:", Pattern: reUppercaseTextWithColon.String(), Line: 1, Start: 0, End: 7, RuneStart: 0, RuneEnd: 7, Old: "KAREN: "},
+		{Rule: delims, Pattern: `\([^\)]{0,}\)`, Line: 1, Start: 5, End: 14, RuneStart: 4, RuneEnd: 13, Old: "(suspira)"},
+		{Rule: "Cleanup", Pattern: "♪♪", Line: 2, Start: 21, End: 24, RuneStart: 18, RuneEnd: 19, Old: "♪"},
+		{Rule: "Cleanup", Pattern: "♪♪", Line: 2, Start: 28, End: 31, RuneStart: 25, RuneEnd: 26, Old: "♪"},
+		{Rule: notes, Pattern: `♪[^♪]{0,}♪`, Line: 2, Start: 18, End: 31, RuneStart: 17, RuneEnd: 26, Old: "♪ la la ♪"},
+		{Rule: notes, Line: 2, Start: 17, End: 18, RuneStart: 16, RuneEnd: 17, Old: " "},
+		{Rule: "Cleanup", Line: 1, Start: 5, End: 6, RuneStart: 4, RuneEnd: 5, Old: " "},
+		{Rule: "Cleanup", Line: 1, Start: 14, End: 16, RuneStart: 13, RuneEnd: 15, Old: "\n-"},
+	}
+	got := changes[0].Edits
+	if !slices.Equal(got, want) {
+		for _, e := range got {
+			t.Logf("%+v", e)
+		}
+		t.Fatalf("edits differ from want")
+	}
+}
+
+func TestReplayRevert(t *testing.T) {
+	t.Parallel()
+
+	conf := rules.DefaultConfig()
+	conf.RemoveLineIfAllCapsAction = true
+	conf.RemoveBetweenDelimiters = append(conf.RemoveBetweenDelimiters,
+		rules.Delimiter{Left: "♪", Right: "♪"}, rules.Delimiter{Left: "<", Right: ">"})
+	conf.Allow = &rules.AllowConfig{Phrases: []string{"(Dr. House)"}}
+	lines := []string{
+		"KAREN: Olá (suspira) tudo bem?",
+		"He said:\nGo home.",
+		"PHONE RINGS\nWho is it?",
+		"(Dr. House) (sighs)   Hello",
+		"♪♪ la la ♪♪",
+		"[door closes]",
+		"- (gasps)\n- What?  Really?",
+		"<font color=red>Hi</font> <i>[thunder]</i>",
+		"* tense music *",
+		`{\i1}GUARD 2: Halt!{\i0}`,
+		"Hello there",
+	}
+	for _, format := range []model.SubtitleFormat{model.SubtitleFormatSRT, model.SubtitleFormatASS} {
+		doc := model.Document{Format: format}
+		for i, l := range lines {
+			doc.Cues = append(doc.Cues, &model.Cue{Index: i + 1, Lines: l})
+		}
+		_, changes := ApplyAll(doc, conf)
+		_, forced := ApplyForced(doc, NewRules(conf))
+		for _, ch := range append(changes, forced...) {
+			base := SRTText(ch.Original, format)
+			got, err := Replay(base, ch.Edits)
+			if err != nil || got != ch.Transformed {
+				t.Fatalf("cue %d: Replay = %q, %v; want %q (edits %+v)", ch.CueIndex, got, err, ch.Transformed, ch.Edits)
+			}
+			if got, err := Revert(ch.Transformed, ch.Edits); err != nil || got != base {
+				t.Fatalf("cue %d: Revert = %q, %v; want %q", ch.CueIndex, got, err, base)
+			}
+		}
+	}
+
+	if _, err := Replay("Hi", []Edit{{Rule: "x", Start: 0, End: 2, Old: "Yo"}}); err == nil {
+		t.Fatal("Replay of a mismatched edit should fail")
+	}
+}
+
+func TestBaseSpans(t *testing.T) {
+	t.Parallel()
+
+	base := "♪ (a) b (c)"
+	edits := []Edit{
+		{Rule: "D", Start: 4, End: 7, Old: "(a)"},
+		{Rule: "D", Start: 7, End: 10, Old: "(c)"},
+		{Rule: "X", Start: 4, End: 6, Old: " b", New: "B"},
+		{Rule: "Y", Start: 4, End: 5, Old: "B"},
+	}
+	want := []Span{
+		{Rule: "D", Start: 4, End: 7, RuneStart: 2, RuneEnd: 5},
+		{Rule: "X", Start: 7, End: 9, RuneStart: 5, RuneEnd: 7},
+		{Rule: "D", Start: 10, End: 13, RuneStart: 8, RuneEnd: 11},
+	}
+	if got := BaseSpans(base, edits); !slices.Equal(got, want) {
+		t.Fatalf("BaseSpans = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package transform
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/langdetect"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+)
+
+var (
+	reItalicSpan = regexp.MustCompile(`(?s)<i>.*?</i>`)
+	reSRTTags    = regexp.MustCompile(`<[^>]*>`)
+)
+
+// forcedRules is the compiled ForcedConfig of Rules.
+type forcedRules struct {
+	patterns []*regexp.Regexp
+	italics  bool
+	detect   bool
+	language string
+}
+
+// compileForced compiles conf (DefaultForcedConfig when nil); invalid patterns are skipped.
+func compileForced(conf *rules.ForcedConfig) forcedRules {
+	c := rules.DefaultForcedConfig()
+	if conf != nil {
+		c = *conf
+	}
+	f := forcedRules{italics: c.Italics, detect: c.DetectLanguage, language: langdetect.Normalize(c.Language)}
+	for _, p := range c.Patterns {
+		if re, err := regexp.Compile(p); err == nil {
+			f.patterns = append(f.patterns, re)
+		}
+	}
+	return f
+}
+
+// ApplyForced builds a forced track from a full one: only cues selected by the forced
+// config are kept (pattern matches removed, then sanitized with the regular rules).
+// Changes list the kept cues, with the forced rule that selected each one first.
+func ApplyForced(doc model.Document, r Rules) (model.Document, []CueChange) {
+	main := r.forced.language
+	if main == "" && r.forced.detect {
+		lines := make([]string, 0, len(doc.Cues))
+		for _, cue := range doc.Cues {
+			for line := range strings.SplitSeq(plainText(cue.Lines, doc.Format), "\n") {
+				lines = append(lines, line)
+			}
+		}
+		main = langdetect.Majority(lines)
+	}
+	outcomes := mapCuesParallel(doc.Cues, func(cue *model.Cue) cueOutcome {
+		return forcedCue(cue, doc.Format, r, main)
+	})
+	return assembleDocument(doc, outcomes)
+}
+
+// forcedCue keeps cue when a forced rule selects it; main is the document language.
+func forcedCue(cue *model.Cue, format model.SubtitleFormat, r Rules, main string) cueOutcome {
+	text := cue.Lines
+	if format == model.SubtitleFormatASS {
+		text = convertASSFormattingToSRT(text)
+	}
+
+	var reasons []string
+	edit := cueEdit{allow: r.allow, text: text}
+	for _, re := range r.forced.patterns {
+		if re.MatchString(edit.text) {
+			edit.replaceAll(re, string(rules.RuleForcedPattern))
+			if len(reasons) == 0 {
+				reasons = append(reasons, string(rules.RuleForcedPattern))
+			}
+		}
+	}
+	text = edit.text
+	if len(reasons) == 0 && r.forced.italics && isItalicOnly(text) {
+		reasons = append(reasons, string(rules.RuleForcedItalics))
+	}
+	if len(reasons) == 0 && r.forced.detect && main != "" {
+		for line := range strings.SplitSeq(reSRTTags.ReplaceAllString(text, ""), "\n") {
+			if lang, ok := langdetect.Detect(line); ok && lang != main {
+				reasons = append(reasons, string(rules.RuleForcedLanguage))
+				break
+			}
+		}
+	}
+	if len(reasons) == 0 {
+		return cueOutcome{}
+	}
+
+	sanitized := applyCue(&model.Cue{Index: cue.Index, Start: cue.Start, End: cue.End, Lines: text}, model.SubtitleFormatSRT, r)
+	change := &CueChange{CueIndex: cue.Index, Original: cue.Lines, Rules: reasons, Edits: edit.edits, Suppressed: edit.suppressed}
+	current := text
+	if sanitized.change != nil {
+		change.Rules = append(change.Rules, sanitized.change.Rules...)
+		change.Edits = append(change.Edits, sanitized.change.Edits...)
+		change.Suppressed = append(change.Suppressed, sanitized.change.Suppressed...)
+		current = sanitized.change.Transformed
+	}
+	lines := make([]string, 0, 2)
+	if sanitized.kept != nil {
+		for line := range strings.SplitSeq(sanitized.kept.Lines, "\n") {
+			if line = strings.TrimSpace(collapseSpaces(line)); lineHasAlphanumeric(line) {
+				lines = append(lines, line)
+			}
+		}
+	}
+	change.Transformed = strings.Join(lines, "\n")
+	change.Edits = append(change.Edits, diffEdits(string(rules.RuleCleanup), "", current, change.Transformed)...)
+	if len(lines) == 0 {
+		return cueOutcome{change: change}
+	}
+	outcome := keepCue(cue, change.Transformed)
+	outcome.change = change
+	return outcome
+}
+
+// isItalicOnly reports whether all text of s is inside <i>...</i>.
+func isItalicOnly(s string) bool {
+	return strings.Contains(s, "<i>") && !lineHasAlphanumeric(reItalicSpan.ReplaceAllString(s, ""))
+}
+
+// plainText is the cue text without SRT/ASS markup (language detection input).
+func plainText(s string, format model.SubtitleFormat) string {
+	if format == model.SubtitleFormatASS {
+		s = convertASSFormattingToSRT(s)
+	}
+	return reSRTTags.ReplaceAllString(s, "")
+}
@@ -0,0 +1,66 @@
+package transform
+
+import (
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+)
+
+func TestApplyForced(t *testing.T) {
+	t.Parallel()
+
+	doc := model.Document{
+		Format: model.SubtitleFormatSRT,
+		Cues: []*model.Cue{
+			{Index: 1, Lines: "Where are you going with the car?"},
+			{Index: 2, Lines: "[in Spanish] ¿Dónde está el coche?"},
+			{Index: 3, Lines: "I think that we should go home now."},
+			{Index: 4, Lines: "<i>Signs of the times</i>"},
+			{Index: 5, Lines: "Nous sommes dans la maison avec les enfants."},
+			{Index: 6, Lines: "And what is the name of this place?"},
+			{Index: 7, Lines: "[IN RUSSIAN]"},
+		},
+	}
+	out, changes := ApplyForced(doc, NewRules(rules.Config{}))
+
+	want := map[int]string{
+		2: "¿Dónde está el coche?",
+		4: "<i>Signs of the times</i>",
+		5: "Nous sommes dans la maison avec les enfants.",
+	}
+	if len(out.Cues) != len(want) {
+		t.Fatalf("kept %d cues, want %d: %+v", len(out.Cues), len(want), out.Cues)
+	}
+	for _, c := range out.Cues {
+		if want[c.Index] != c.Lines {
+			t.Fatalf("cue %d = %q, want %q", c.Index, c.Lines, want[c.Index])
+		}
+	}
+	reasons := map[int]string{}
+	for _, ch := range changes {
+		reasons[ch.CueIndex] = ch.Rules[0]
+	}
+	if reasons[2] != string(rules.RuleForcedPattern) || reasons[4] != string(rules.RuleForcedItalics) ||
+		reasons[5] != string(rules.RuleForcedLanguage) || reasons[7] != string(rules.RuleForcedPattern) {
+		t.Fatalf("unexpected change rules: %+v", changes)
+	}
+}
+
+func TestApplyForced_config(t *testing.T) {
+	t.Parallel()
+
+	doc := model.Document{
+		Format: model.SubtitleFormatASS,
+		Cues: []*model.Cue{
+			{Index: 1, Lines: `{\i1}Italic only{\i0}`},
+			{Index: 2, Lines: "Nous sommes dans la maison avec les enfants."},
+			{Index: 3, Lines: "(speaking French) Bonjour"},
+		},
+	}
+	conf := rules.Config{Forced: &rules.ForcedConfig{Patterns: []string{`\(speaking \w+\)`}}}
+	out, _ := ApplyForced(doc, NewRules(conf))
+	if len(out.Cues) != 1 || out.Cues[0].Index != 3 || out.Cues[0].Lines != "Bonjour" {
+		t.Fatalf("unexpected cues: %+v", out.Cues)
+	}
+}
@@ -3,10 +3,12 @@ package transform
 import (
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/luismascotto/subtitle-sanitizer/internal/diff"
 	"github.com/luismascotto/subtitle-sanitizer/internal/model"
 	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
 )
@@ -27,12 +29,32 @@ var (
 	reASSOtherTags = regexp.MustCompile(`{\\[^bius][^}]*}`)
 )
 
-// CueChange records one cue that had at least one rule applied (including full-line removal).
+// CueChange records one cue that had at least one rule applied (including full-line
+// removal) or suppressed.
 type CueChange struct {
 	CueIndex    int      `json:"cueIndex"`
 	Original    string   `json:"original"`
 	Transformed string   `json:"transformed"`
 	Rules       []string `json:"rules"`
+	// Edits are the exact replacements behind Rules, in the order they were made (the
+	// RuleCleanup ones included), on the SRT text of Original.
+	Edits []Edit `json:"edits,omitempty"`
+	// Suppressed lists the rules the allowlist kept from changing the cue; a change
+	// with only suppressions leaves the cue as it was.
+	Suppressed []Suppression `json:"suppressed,omitempty"`
+}
+
+// Applied reports whether a rule changed the cue (false when every rule was suppressed).
+func (c CueChange) Applied() bool { return len(c.Rules) > 0 }
+
+// RulesLabel joins the applied rules and the suppressed ones with their reasons, eg:
+// `TEXT:, suppressed ALL CAPS (allow phrase "GET OUT NOW")`.
+func (c CueChange) RulesLabel() string {
+	parts := slices.Clone(c.Rules)
+	for _, s := range c.Suppressed {
+		parts = append(parts, fmt.Sprintf("suppressed %s (%s)", s.Rule, s.Reason))
+	}
+	return strings.Join(parts, ", ")
 }
 
 // ApplyFn transforms a document using prepared Rules (compiled delimiters included).
@@ -49,13 +71,18 @@ type cueOutcome struct {
 type Rules struct {
 	conf   rules.Config
 	delims []compiledDelimiter
+	forced forcedRules
+	allow  allowRules
 }
 
-// NewRules compiles delimiter regexes from conf. Call once per config, not per file.
+// NewRules compiles delimiter regexes (and forced and allow patterns) from conf. Call once per
+// config, not per file.
 func NewRules(conf rules.Config) Rules {
 	return Rules{
 		conf:   conf,
 		delims: compileDelimiters(conf.RemoveBetweenDelimiters),
+		forced: compileForced(conf.Forced),
+		allow:  compileAllow(conf.Allow),
 	}
 }
 
@@ -112,94 +139,122 @@ func assembleDocument(doc model.Document, outcomes []cueOutcome) (model.Document
 // applyCue transforms a single cue. Safe for concurrent calls: no shared mutable state
 // (delimiter regexes on Rules are read-only).
 func applyCue(cue *model.Cue, format model.SubtitleFormat, r Rules) cueOutcome {
-	var rulesApplied []string
-	ruleTriggered := false
-	conf := r.conf
-
 	text := cue.Lines
 
 	if format == model.SubtitleFormatASS {
 		text = convertASSFormattingToSRT(text)
 	}
 
-	if conf.RemoveLineIfContains != "" {
-		lstRemoveLineIfContains := strings.SplitSeq(conf.RemoveLineIfContains, "\n")
-		for removeLineIfContains := range lstRemoveLineIfContains {
-			if strings.Contains(text, removeLineIfContains) {
-				text = ""
-				rulesApplied = append(rulesApplied, string(rules.RuleRemoveLineIfContains))
-				break
+	if reason := r.allow.exempt(cue.Index, text); reason != "" {
+		// Keep the cue, but report the rules that would have changed it.
+		outcome := keepCue(cue, text)
+		if edit := sanitizeText(text, r, allowRules{}); len(edit.applied) > 0 {
+			change := &CueChange{CueIndex: cue.Index, Original: cue.Lines, Transformed: text, Rules: []string{}}
+			for _, rule := range edit.applied {
+				change.Suppressed = append(change.Suppressed, Suppression{Rule: rule, Reason: reason})
 			}
+			outcome.change = change
 		}
+		return outcome
 	}
 
-	if text != "" {
-		if conf.RemoveSingleLineColon {
-			ruleTriggered, text = removeSingleLineColon(text)
-			if ruleTriggered {
-				rulesApplied = append(rulesApplied, string(rules.RuleRemoveSingleLineColon))
-			}
+	edit := sanitizeText(text, r, r.allow)
+	text = edit.text
+
+	var change *CueChange
+	if len(edit.applied) > 0 || len(edit.suppressed) > 0 {
+		if edit.applied == nil {
+			edit.applied = []string{} // every rule suppressed
 		}
-		if conf.RemoveLineIfAllCapsAction {
-			ruleTriggered, text = removeLineIfAllCapsAction(text)
-			if ruleTriggered {
-				rulesApplied = append(rulesApplied, string(rules.RuleRemoveLineIfAllCapsAction))
-			}
+		change = &CueChange{
+			CueIndex:    cue.Index,
+			Original:    cue.Lines,
+			Transformed: text,
+			Rules:       edit.applied, // ownership transfer; not reused after this
+			Edits:       edit.edits,
+			Suppressed:  edit.suppressed,
 		}
+	}
 
-		if conf.RemoveTextBeforeColonIfUppercase {
-			ruleTriggered, text = removeUppercaseTextWithColon(text)
-			if ruleTriggered {
-				rulesApplied = append(rulesApplied, string(rules.RuleRemoveTextBeforeColonIfUppercase))
-			}
-		} else if conf.RemoveTextBeforeColon {
-			ruleTriggered, text = removeTextBeforeColon(text)
-			if ruleTriggered {
-				rulesApplied = append(rulesApplied, string(rules.RuleRemoveTextBeforeColon))
+	if text == "" {
+		return cueOutcome{change: change}
+	}
+	outcome := keepCue(cue, text)
+	outcome.change = change
+	return outcome
+}
+
+// sanitizeText runs the enabled rules on text in order; each rule consults allow
+// before removing anything.
+func sanitizeText(text string, r Rules, allow allowRules) cueEdit {
+	conf := r.conf
+	edit := cueEdit{allow: allow, text: text}
+
+	if conf.RemoveLineIfContains != "" {
+		for removeLineIfContains := range strings.SplitSeq(conf.RemoveLineIfContains, "\n") {
+			if strings.Contains(edit.text, removeLineIfContains) {
+				edit.apply(string(rules.RuleRemoveLineIfContains), removeLineIfContains, "")
+				break
 			}
 		}
 	}
 
-	if text != "" && conf.RemoveOnlySymbolsLine {
-		if !lineHasAlphanumeric(text) {
-			text = ""
-			rulesApplied = append(rulesApplied, string(rules.RuleRemoveOnlySymbolsLine))
+	for _, step := range []struct {
+		enabled bool
+		rule    rules.AbbreviatedRuleDescription
+		pattern *regexp.Regexp
+		fn      func(string) (bool, string)
+	}{
+		{conf.RemoveSingleLineColon, rules.RuleRemoveSingleLineColon, nil, removeSingleLineColon},
+		{conf.RemoveLineIfAllCapsAction, rules.RuleRemoveLineIfAllCapsAction, nil, removeLineIfAllCapsAction},
+		{conf.RemoveTextBeforeColonIfUppercase, rules.RuleRemoveTextBeforeColonIfUppercase, reUppercaseTextWithColon, removeUppercaseTextWithColon},
+		{!conf.RemoveTextBeforeColonIfUppercase && conf.RemoveTextBeforeColon, rules.RuleRemoveTextBeforeColon, reTextWithColon, removeTextBeforeColon},
+	} {
+		if !step.enabled || edit.text == "" {
+			continue
 		}
+		if triggered, next := step.fn(edit.text); triggered {
+			pattern := ""
+			if step.pattern != nil {
+				pattern = step.pattern.String()
+			}
+			edit.apply(string(step.rule), pattern, next)
+		}
+	}
+
+	if edit.text != "" && conf.RemoveOnlySymbolsLine && !lineHasAlphanumeric(edit.text) {
+		edit.apply(string(rules.RuleRemoveOnlySymbolsLine), "", "")
 	}
 
-	if text != "" && len(r.delims) > 0 {
-		text, rulesApplied = removeTextBetweenCompiledDelimiters(text, r.delims, rulesApplied)
+	if edit.text != "" && len(r.delims) > 0 {
+		edit.removeDelimited(r.delims)
 	}
 
-	if text != "" && len(rulesApplied) > 0 {
+	if edit.text != "" && len(edit.applied) > 0 {
 		var finalTextLines []string
-		for line := range strings.SplitSeq(text, "\n") {
-			if lineHasAlphanumeric(line) {
-				sanitizedLine := strings.TrimSpace(collapseSpaces(line))
-				if sanitizedLine != "" {
-					finalTextLines = append(finalTextLines, sanitizedLine)
+		for line := range strings.SplitSeq(edit.text, "\n") {
+			if !lineHasAlphanumeric(line) {
+				reason := edit.allow.lost(line, "")
+				if reason == "" {
+					continue
 				}
+				edit.suppress(string(rules.RuleCleanup), reason)
+			}
+			sanitizedLine := strings.TrimSpace(collapseSpaces(line))
+			if sanitizedLine != "" {
+				finalTextLines = append(finalTextLines, sanitizedLine)
 			}
 		}
-		text = strings.Join(finalTextLines, "\n")
-	}
-
-	var change *CueChange
-	if len(rulesApplied) > 0 {
-		change = &CueChange{
-			CueIndex:    cue.Index,
-			Original:    cue.Lines,
-			Transformed: text,
-			Rules:       rulesApplied, // ownership transfer; not reused after this
-		}
+		edit.tidy(string(rules.RuleCleanup), "", strings.Join(finalTextLines, "\n"))
 	}
+	return edit
+}
 
-	if text == "" {
-		return cueOutcome{change: change}
-	}
-	// Reuse input cue when unchanged; allocate only when text diverged (rules or ASS convert).
+// keepCue returns cue with text. The input cue is reused when unchanged; a copy is
+// allocated only when text diverged (rules or ASS convert).
+func keepCue(cue *model.Cue, text string) cueOutcome {
 	if text == cue.Lines {
-		return cueOutcome{kept: cue, change: change}
+		return cueOutcome{kept: cue}
 	}
 	return cueOutcome{
 		kept: &model.Cue{
@@ -208,10 +263,53 @@ func applyCue(cue *model.Cue, format model.SubtitleFormat, r Rules) cueOutcome {
 			End:   cue.End,
 			Lines: text,
 		},
-		change: change,
 	}
 }
 
+// SRTText returns cue text as it is written to SRT output: ASS override tags are
+// converted to SRT tags, anything else is returned unchanged.
+func SRTText(text string, format model.SubtitleFormat) string {
+	if format == model.SubtitleFormatASS {
+		return convertASSFormattingToSRT(text)
+	}
+	return text
+}
+
+// RejectedPattern suggests the allow.cuesContaining phrase for a change the reviewer
+// rejected: the longest run of text the rules removed, or the longest line when the cue
+// was dropped.
+func RejectedPattern(ch CueChange) string {
+	original := convertASSFormattingToSRT(ch.Original)
+	if strings.TrimSpace(ch.Transformed) == "" {
+		longest := ""
+		for line := range strings.SplitSeq(original, "\n") {
+			if line = strings.TrimSpace(line); len(line) > len(longest) {
+				longest = line
+			}
+		}
+		return longest
+	}
+
+	a, b := []rune(original), []rune(ch.Transformed)
+	longest, run := "", []rune(nil)
+	flush := func() {
+		if s := strings.TrimSpace(string(run)); len(s) > len(longest) {
+			longest = s
+		}
+		run = run[:0]
+	}
+	for _, e := range diff.Script(a, b) {
+		switch e.Kind {
+		case diff.Delete:
+			run = append(run, a[e.A])
+		case diff.Equal:
+			flush()
+		}
+	}
+	flush()
+	return longest
+}
+
 func removeTextBetweenDelimiters(text string, delimiters []rules.Delimiter, rulesApplied []string) (string, []string) {
 	// Rerun delimiter scan if any rule was triggered for recursive processing.
 	for {
@@ -285,18 +383,9 @@ func compileDelimiters(delimiters []rules.Delimiter) []compiledDelimiter {
 }
 
 func compileDelimiter(delimiter rules.Delimiter) (compiledDelimiter, bool) {
-	controlEscape := ""
-	minContentLen := 0
-	if delimiter.Left == "<" {
-		// SRT format uses angle brackets for formatting (italic, bold, etc.), <i>Text</i>
-		minContentLen = 3
-		controlEscape = "/="
-	}
-	left := regexp.QuoteMeta(delimiter.Left)
-	right := regexp.QuoteMeta(delimiter.Right)
-	re, err := regexp.Compile(fmt.Sprintf(`%s[^%s%s]{%d,}%s`, left, controlEscape, right, minContentLen, right))
+	re, err := regexp.Compile(delimiter.Pattern())
 	if err != nil {
-		fmt.Println("Error compiling regex:", err, "for delimiter:", delimiter)
+		// rules.Validate reports these when the config is loaded.
 		return compiledDelimiter{}, false
 	}
 	cd := compiledDelimiter{
@@ -314,30 +403,35 @@ func compileDelimiter(delimiter rules.Delimiter) (compiledDelimiter, bool) {
 // removeTextBetweenCompiledDelimiters is the precompiled-regex counterpart of
 // removeTextBetweenDelimiters (same recursive scan / rule labels).
 func removeTextBetweenCompiledDelimiters(text string, delimiters []compiledDelimiter, rulesApplied []string) (string, []string) {
+	edit := cueEdit{text: text, applied: rulesApplied}
+	edit.removeDelimited(delimiters)
+	return edit.text, edit.applied
+}
+
+// removeDelimited removes delimited text until no delimiter matches; matches holding
+// protected text are kept.
+func (e *cueEdit) removeDelimited(delimiters []compiledDelimiter) {
 	for {
 		ruleTriggered := false
 
 		for _, d := range delimiters {
 			if d.collapse != "" {
-				text = strings.ReplaceAll(text, d.collapse, d.left)
+				e.tidy(string(rules.RuleCleanup), d.collapse, strings.ReplaceAll(e.text, d.collapse, d.left))
 			}
-			// Single replace pass; regexp returns src unchanged when there is no match.
-			next := d.re.ReplaceAllString(text, "")
-			if next != text {
+			if e.replaceAll(d.re, d.label) {
 				ruleTriggered = true
-				rulesApplied = append(rulesApplied, d.label)
-				text = strings.TrimSpace(next)
-				if text == "" {
+				e.applied = append(e.applied, d.label)
+				e.tidy(d.label, "", strings.TrimSpace(e.text))
+				if e.text == "" {
 					break
 				}
 			}
 		}
 
-		if !ruleTriggered || text == "" {
+		if !ruleTriggered || e.text == "" {
 			break
 		}
 	}
-	return text, rulesApplied
 }
 
 func RemoveTextBetweenOpenCloseMatchingDelimiter(text string, delimiter rules.Delimiter, rulesApplied []string) (string, []string) {
@@ -368,7 +462,7 @@ func MarkdownRows(entries []CueChange) string {
 			e.CueIndex,
 			strings.ReplaceAll(e.Original, "\n", " \\n "),
 			strings.ReplaceAll(e.Transformed, "\n", " \\n "),
-			strings.Join(e.Rules, ", "))
+			e.RulesLabel())
 	}
 	return sb.String()
 }
@@ -10,11 +10,20 @@ import (
 // applyAllParallel transforms cues concurrently with a GOMAXPROCS-bounded worker pool,
 // then assembles results in input order (same observable output as sequential).
 func applyAllParallel(doc model.Document, r Rules) (model.Document, []CueChange) {
-	n := len(doc.Cues)
-	if n == 0 {
+	if len(doc.Cues) == 0 {
 		return assembleDocument(doc, nil)
 	}
+	return assembleDocument(doc, applyCuesParallel(doc.Cues, doc.Format, r))
+}
+
+// applyCuesParallel returns one outcome per cue, in input order.
+func applyCuesParallel(cues []*model.Cue, format model.SubtitleFormat, r Rules) []cueOutcome {
+	return mapCuesParallel(cues, func(cue *model.Cue) cueOutcome { return applyCue(cue, format, r) })
+}
 
+// mapCuesParallel runs apply on every cue with a GOMAXPROCS-bounded worker pool.
+func mapCuesParallel(cues []*model.Cue, apply func(*model.Cue) cueOutcome) []cueOutcome {
+	n := len(cues)
 	outcomes := make([]cueOutcome, n)
 	workers := max(min(runtime.GOMAXPROCS(0), n), 1)
 
@@ -30,11 +39,11 @@ func applyAllParallel(doc model.Document, r Rules) (model.Document, []CueChange)
 		go func() {
 			defer wg.Done()
 			for i := range jobs {
-				outcomes[i] = applyCue(doc.Cues[i], doc.Format, r)
+				outcomes[i] = apply(cues[i])
 			}
 		}()
 	}
 	wg.Wait()
 
-	return assembleDocument(doc, outcomes)
+	return outcomes
 }
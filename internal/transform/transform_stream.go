@@ -0,0 +1,55 @@
+package transform
+
+import (
+	"iter"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+)
+
+// DefaultWindow is the number of cues ApplyWindowed transforms at once when window <= 0.
+const DefaultWindow = 256
+
+// Outcome is the streamed result for one input cue. Cue is nil when the cue was dropped;
+// Change is nil when no rule fired.
+type Outcome struct {
+	Cue    *model.Cue
+	Change *CueChange
+}
+
+// ApplyWindowed transforms a cue stream in windows of at most window cues (each window in
+// parallel) and yields outcomes in input order. Memory is bounded by the window, not the
+// stream length. A source error is yielded after the outcomes of the cues read before it.
+func ApplyWindowed(cues iter.Seq2[*model.Cue, error], format model.SubtitleFormat, r Rules, window int) iter.Seq2[Outcome, error] {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	return func(yield func(Outcome, error) bool) {
+		batch := make([]*model.Cue, 0, window)
+		flush := func() bool {
+			if len(batch) == 0 {
+				return true
+			}
+			for _, o := range applyCuesParallel(batch, format, r) {
+				if !yield(Outcome{Cue: o.kept, Change: o.change}, nil) {
+					return false
+				}
+			}
+			clear(batch) // drop cue references before reuse
+			batch = batch[:0]
+			return true
+		}
+		for cue, err := range cues {
+			if err != nil {
+				if flush() {
+					yield(Outcome{}, err)
+				}
+				return
+			}
+			batch = append(batch, cue)
+			if len(batch) == window && !flush() {
+				return
+			}
+		}
+		flush()
+	}
+}
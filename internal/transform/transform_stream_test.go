@@ -0,0 +1,70 @@
+package transform
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
+)
+
+func cueSeq(cues []*model.Cue, tail error) func(func(*model.Cue, error) bool) {
+	return func(yield func(*model.Cue, error) bool) {
+		for _, c := range cues {
+			if !yield(c, nil) {
+				return
+			}
+		}
+		if tail != nil {
+			yield(nil, tail)
+		}
+	}
+}
+
+func TestApplyWindowed_matchesSequential(t *testing.T) {
+	doc := scaleDocument(loadBenchDocument(t), 3)
+	r := NewRules(rules.DefaultConfig())
+	wantDoc, wantCh := ApplyAllSequential(doc, r)
+	for _, window := range []int{0, 1, 7, 1000} {
+		var gotCues []*model.Cue
+		var gotCh []CueChange
+		for o, err := range ApplyWindowed(cueSeq(doc.Cues, nil), doc.Format, r, window) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.Cue != nil {
+				gotCues = append(gotCues, o.Cue)
+			}
+			if o.Change != nil {
+				gotCh = append(gotCh, *o.Change)
+			}
+		}
+		if len(gotCues) != len(wantDoc.Cues) || len(gotCh) != len(wantCh) {
+			t.Fatalf("window=%d cues %d/%d changes %d/%d", window, len(gotCues), len(wantDoc.Cues), len(gotCh), len(wantCh))
+		}
+		for i := range gotCues {
+			if gotCues[i].Lines != wantDoc.Cues[i].Lines {
+				t.Fatalf("window=%d cue %d: %q vs %q", window, i, gotCues[i].Lines, wantDoc.Cues[i].Lines)
+			}
+		}
+	}
+}
+
+func TestApplyWindowed_sourceErrorAfterPendingCues(t *testing.T) {
+	cues := []*model.Cue{{Index: 1, Lines: "a"}, {Index: 2, Lines: "b"}}
+	boom := errors.New("boom")
+	var seen int
+	var gotErr error
+	for o, err := range ApplyWindowed(cueSeq(cues, boom), model.SubtitleFormatSRT, NewRules(rules.Config{}), 10) {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		if o.Cue != nil {
+			seen++
+		}
+	}
+	if seen != 2 || !errors.Is(gotErr, boom) {
+		t.Fatalf("seen=%d err=%v", seen, gotErr)
+	}
+}
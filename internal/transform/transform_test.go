@@ -941,3 +941,24 @@ func Test_RemoveTextBetweenOpenCloseMatchingDelimiter(t *testing.T) {
 		})
 	}
 }
+
+func TestRejectedPattern(t *testing.T) {
+	tests := []struct {
+		name string
+		ch   CueChange
+		want string
+	}{
+		{"speaker", CueChange{Original: "DR. HOUSE: Hello there", Transformed: "Hello there"}, "DR. HOUSE:"},
+		{"delimiters", CueChange{Original: "I'm (Bond) here", Transformed: "I'm here"}, "(Bond)"},
+		{"longest run", CueChange{Original: "(a) mid [longer one] end", Transformed: "mid end"}, "[longer one]"},
+		{"dropped cue", CueChange{Original: "-\n[DOOR SLAMS]", Transformed: ""}, "[DOOR SLAMS]"},
+		{"ass tags ignored", CueChange{Original: `{\i1}(Bond){\i0} here`, Transformed: "<i></i> here"}, "(Bond)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RejectedPattern(tt.ch); got != tt.want {
+				t.Fatalf("RejectedPattern() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
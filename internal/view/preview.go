@@ -0,0 +1,237 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/diff"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+var (
+	previewRemovedStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("203")).Strikethrough(true)
+	previewInsertedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("42")).Underline(true)
+	previewTimeStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("111"))
+	previewHeadStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("252")).Bold(true)
+	previewSepStyle      = lipgloss.NewStyle().Foreground(lipgloss.Color("62"))
+)
+
+// spanKind marks runes of the character-level diff.
+type spanKind int
+
+const (
+	spanEqual spanKind = iota
+	spanRemoved
+	spanInserted
+)
+
+type styledRune struct {
+	r    rune
+	kind spanKind
+}
+
+// previewBody renders changes side by side from the cursor down, as many as fit:
+// original text with removed spans on the left, the written text with inserted spans on
+// the right, under a line with the cue timecodes and rules.
+func (m ReviewTransformationsModel) previewBody() string {
+	if len(m.visible) == 0 {
+		return "Nothing to remove...\n"
+	}
+	budget := m.height - 3 - 3 // header, help
+	if m.showRules {
+		budget -= lipgloss.Height(m.rules) + 2
+	}
+	if m.editing {
+		budget -= m.editor.Height() + 1
+	}
+
+	var b strings.Builder
+	for pos := m.cursor; pos < len(m.visible); pos++ {
+		block := m.previewBlock(m.visible[pos], pos == m.cursor)
+		h := lipgloss.Height(block)
+		if h > budget && pos != m.cursor {
+			break
+		}
+		b.WriteString(block + "\n")
+		budget -= h
+	}
+	for ; budget > 0; budget-- {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func (m ReviewTransformationsModel) previewBlock(i int, selected bool) string {
+	ch, d := m.changes[i], m.Decisions[i]
+	colWidth := max(10, (m.width-3)/2)
+
+	timing := "--:--:--,--- → --:--:--,---"
+	if cue, ok := m.cues[ch.CueIndex]; ok {
+		timing = subtitle.FormatTimecode(cue.Start) + " → " + subtitle.FormatTimecode(cue.End)
+	}
+	state := "accepted"
+	switch {
+	case !ch.Applied() && d.Text == ch.Transformed:
+		state = "suppressed"
+	case !d.Accept:
+		state = "rejected"
+	case d.Text != ch.Transformed:
+		state = "edited"
+	}
+	head := fmt.Sprintf("#%-4d %s  %s  %s", ch.CueIndex, previewTimeStyle.Render(timing), state,
+		reviewRulesStyle.Render(ch.RulesLabel()))
+	head = ansi.Truncate(head, max(10, m.width-1), "…")
+	if selected {
+		head = reviewCursorStyle.Render(ansi.Strip(head))
+	} else {
+		head = previewHeadStyle.Render(head)
+	}
+
+	original := transform.SRTText(ch.Original, m.format)
+	result := d.Text
+	if !d.Accept {
+		result = original
+	}
+	left, right := charDiff(original, result)
+	if d.Accept && d.Text == ch.Transformed && len(ch.Edits) > 0 {
+		// Unedited: strike exactly what the rules removed.
+		left = spanRunes(original, transform.BaseSpans(original, ch.Edits))
+	}
+	leftLines := wrapStyled(left, colWidth)
+	rightLines := wrapStyled(right, colWidth)
+	if strings.TrimSpace(result) == "" {
+		rightLines = []string{reviewRulesStyle.Render("(removed)")}
+	}
+
+	column := lipgloss.NewStyle().Width(colWidth)
+	sep := previewSepStyle.Render(strings.TrimSuffix(strings.Repeat(" │\n", max(len(leftLines), len(rightLines))), "\n"))
+	body := lipgloss.JoinHorizontal(lipgloss.Top,
+		column.Render(strings.Join(leftLines, "\n")),
+		sep+" ",
+		column.Render(strings.Join(rightLines, "\n")),
+	)
+	return head + "\n" + body
+}
+
+// charDiff splits the rune-level edit script of a → b into the left (equal and removed)
+// and right (equal and inserted) columns.
+func charDiff(a, b string) (left, right []styledRune) {
+	ar, br := []rune(a), []rune(b)
+	for _, e := range diff.Script(ar, br) {
+		switch e.Kind {
+		case diff.Equal:
+			left = append(left, styledRune{ar[e.A], spanEqual})
+			right = append(right, styledRune{br[e.B], spanEqual})
+		case diff.Delete:
+			left = append(left, styledRune{ar[e.A], spanRemoved})
+		case diff.Insert:
+			right = append(right, styledRune{br[e.B], spanInserted})
+		}
+	}
+	return left, right
+}
+
+// spanRunes marks the runes of s inside spans as removed.
+func spanRunes(s string, spans []transform.Span) []styledRune {
+	out := make([]styledRune, 0, len(s))
+	i := 0
+	for _, r := range s {
+		for i < len(spans) && spans[i].RuneEnd <= len(out) {
+			i++
+		}
+		kind := spanEqual
+		if i < len(spans) && spans[i].RuneStart <= len(out) {
+			kind = spanRemoved
+		}
+		out = append(out, styledRune{r, kind})
+	}
+	return out
+}
+
+// highlightSpans renders s with spans struck through, styling each line on its own.
+func highlightSpans(s string, spans []transform.Span) string {
+	runes := spanRunes(s, spans)
+	var lines []string
+	start := 0
+	for i, sr := range runes {
+		if sr.r == '\n' {
+			lines = append(lines, renderStyled(runes[start:i]))
+			start = i + 1
+		}
+	}
+	lines = append(lines, renderStyled(runes[start:]))
+	return strings.Join(lines, "\n")
+}
+
+// wrapStyled renders runes as lines of at most width cells, breaking after spaces when
+// possible and styling removed and inserted spans. Changed line breaks show as ⏎.
+func wrapStyled(runes []styledRune, width int) []string {
+	var lines [][]styledRune
+	var line []styledRune
+	w, lastSpace := 0, -1
+	for _, sr := range runes {
+		if sr.r == '\n' {
+			if sr.kind != spanEqual {
+				line = append(line, styledRune{'⏎', sr.kind})
+			}
+			lines = append(lines, line)
+			line, w, lastSpace = nil, 0, -1
+			continue
+		}
+		rw := ansi.StringWidth(string(sr.r))
+		if w+rw > width && len(line) > 0 {
+			cut := len(line)
+			if lastSpace >= 0 {
+				cut = lastSpace + 1
+			}
+			lines = append(lines, line[:cut:cut])
+			line = append([]styledRune(nil), line[cut:]...)
+			w, lastSpace = 0, -1
+			for j, r := range line {
+				w += ansi.StringWidth(string(r.r))
+				if r.r == ' ' {
+					lastSpace = j
+				}
+			}
+		}
+		if sr.r == ' ' {
+			lastSpace = len(line)
+		}
+		line = append(line, sr)
+		w += rw
+	}
+	lines = append(lines, line)
+
+	out := make([]string, len(lines))
+	for n, l := range lines {
+		out[n] = renderStyled(l)
+	}
+	return out
+}
+
+// renderStyled joins runes, wrapping each run of removed or inserted runes in its style.
+func renderStyled(runes []styledRune) string {
+	var b strings.Builder
+	for start := 0; start < len(runes); {
+		end := start
+		var run []rune
+		for end < len(runes) && runes[end].kind == runes[start].kind {
+			run = append(run, runes[end].r)
+			end++
+		}
+		switch runes[start].kind {
+		case spanRemoved:
+			b.WriteString(previewRemovedStyle.Render(string(run)))
+		case spanInserted:
+			b.WriteString(previewInsertedStyle.Render(string(run)))
+		default:
+			b.WriteString(string(run))
+		}
+		start = end
+	}
+	return b.String()
+}
@@ -0,0 +1,516 @@
+package view
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"charm.land/bubbles/v2/textarea"
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/sanitize"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+var (
+	reviewTitleStyle    = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+	reviewCursorStyle   = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("229")).Background(lipgloss.Color("57"))
+	reviewRejectedStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("243")).Strikethrough(true)
+	reviewLabelStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("63")).Bold(true)
+	reviewRulesStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("#6B7280"))
+	reviewStatusStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+	reviewPanelStyle    = lipgloss.NewStyle().
+				BorderStyle(lipgloss.RoundedBorder()).
+				BorderForeground(lipgloss.Color("62")).
+				Padding(0, 1)
+)
+
+const (
+	reviewDefaultWidth  = 100
+	reviewDefaultHeight = 32
+	reviewDetailLines   = 4 // per text block in the details panel
+)
+
+// ---------------- Review Transformations Model ----------------
+
+// ReviewTransformationsModel lists every CueChange of one file. Each change can be
+// accepted, rejected or edited; Decisions holds the verdicts (indexed like the changes)
+// and CuesContaining the rejected patterns to save to allow.cuesContaining. The list can be
+// filtered by rule label and switched to a side-by-side preview with timecodes.
+type ReviewTransformationsModel struct {
+	title   string
+	rules   string
+	changes []transform.CueChange
+	cues    map[int]*model.Cue // original cues by index, for timecodes
+	format  model.SubtitleFormat
+
+	Decisions      []sanitize.Decision
+	CuesContaining []string
+
+	labels  []string // distinct rule labels, in order of first use
+	filter  int      // 0: all changes, otherwise labels[filter-1]
+	visible []int    // change indexes passing the filter; cursor indexes this slice
+
+	cursor    int
+	offset    int
+	width     int
+	height    int
+	showRules bool
+	preview   bool
+	editing   bool
+	editor    textarea.Model
+	status    string
+
+	Quit      bool
+	Apply     bool
+	Skip      bool
+	Overwrite bool
+}
+
+// NewReviewModel starts with every change accepted as transformed. doc is the parsed
+// original, used for cue timecodes.
+func NewReviewModel(title, rulesDisplay string, doc model.Document, changes []transform.CueChange) ReviewTransformationsModel {
+	editor := textarea.New()
+	editor.ShowLineNumbers = false
+	editor.SetHeight(4)
+
+	cues := make(map[int]*model.Cue, len(changes))
+	for _, cue := range doc.Cues {
+		cues[cue.Index] = cue
+	}
+	var labels []string
+	for _, ch := range changes {
+		for _, rule := range changeRules(ch) {
+			if !slices.Contains(labels, rule) {
+				labels = append(labels, rule)
+			}
+		}
+	}
+
+	m := ReviewTransformationsModel{
+		title:     title,
+		rules:     rulesDisplay,
+		changes:   changes,
+		cues:      cues,
+		format:    doc.Format,
+		Decisions: sanitize.AcceptAll(changes),
+		labels:    labels,
+		width:     reviewDefaultWidth,
+		height:    reviewDefaultHeight,
+		editor:    editor,
+	}
+	m.applyFilter()
+	return m
+}
+
+func (m ReviewTransformationsModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m ReviewTransformationsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width, m.height = msg.Width, msg.Height
+		m.editor.SetWidth(max(20, m.width-4))
+		m.clampOffset()
+		return m, nil
+	case tea.MouseWheelMsg:
+		if msg.Button == tea.MouseWheelUp {
+			m.move(-1)
+		} else if msg.Button == tea.MouseWheelDown {
+			m.move(1)
+		}
+		return m, nil
+	case tea.KeyPressMsg:
+		if m.editing {
+			return m.updateEditor(msg)
+		}
+		m.status = ""
+		if m.preview {
+			return m.updatePreview(msg)
+		}
+		switch msg.String() {
+		case "q", "x", "ctrl+c":
+			m.Quit = true
+			return m, tea.Quit
+		case "n", "esc":
+			m.Skip = true
+			return m, tea.Quit
+		case "a", "enter":
+			m.Apply = true
+			return m, tea.Quit
+		case "o", "w":
+			m.Apply = true
+			m.Overwrite = true
+			return m, tea.Quit
+		case "s":
+			m.Overwrite = true
+			return m, tea.Quit
+		case "up", "k":
+			m.move(-1)
+		case "down", "j":
+			m.move(1)
+		case "pgup":
+			m.move(-m.listHeight())
+		case "pgdown":
+			m.move(m.listHeight())
+		case "home", "g":
+			m.move(-len(m.visible))
+		case "end", "G":
+			m.move(len(m.visible))
+		case "v":
+			m.preview = true
+		default:
+			return m.updateCommon(msg)
+		}
+	}
+	return m, nil
+}
+
+// updatePreview handles keys of the side-by-side preview.
+func (m ReviewTransformationsModel) updatePreview(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "ctrl+c":
+		m.Quit = true
+		return m, tea.Quit
+	case "v", "esc":
+		m.preview = false
+		m.clampOffset()
+	case "n", "down", "j":
+		m.move(1)
+	case "p", "up", "k":
+		m.move(-1)
+	case "home", "g":
+		m.move(-len(m.visible))
+	case "end", "G":
+		m.move(len(m.visible))
+	default:
+		return m.updateCommon(msg)
+	}
+	return m, nil
+}
+
+// updateCommon handles the decision and filter keys shared by the list and the preview.
+func (m ReviewTransformationsModel) updateCommon(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	i := m.current()
+	switch msg.String() {
+	case "space", "t":
+		if i >= 0 {
+			m.Decisions[i].Accept = !m.Decisions[i].Accept
+		}
+	case "A":
+		m.setAll(true)
+	case "R":
+		m.setAll(false)
+	case "e":
+		if i >= 0 {
+			m.editing = true
+			m.editor.SetWidth(max(20, m.width-4))
+			m.editor.SetValue(m.Decisions[i].Text)
+			return m, m.editor.Focus()
+		}
+	case "i":
+		m.allowPattern()
+	case "f":
+		m.filter = (m.filter + 1) % (len(m.labels) + 1)
+		m.applyFilter()
+	case "F":
+		m.filter = 0
+		m.applyFilter()
+	case "r":
+		m.showRules = !m.showRules
+		m.clampOffset()
+	}
+	return m, nil
+}
+
+// applyFilter recomputes the visible changes, keeping the cursor on the same change
+// when it is still visible.
+func (m *ReviewTransformationsModel) applyFilter() {
+	prev := m.current()
+	label := m.filterLabel()
+	m.visible = m.visible[:0]
+	m.cursor = 0
+	for i, ch := range m.changes {
+		if label == "" || slices.Contains(changeRules(ch), label) {
+			if i == prev {
+				m.cursor = len(m.visible)
+			}
+			m.visible = append(m.visible, i)
+		}
+	}
+	m.clampOffset()
+}
+
+// changeRules lists the rules applied to ch, then the suppressed ones.
+func changeRules(ch transform.CueChange) []string {
+	rules := slices.Clone(ch.Rules)
+	for _, s := range ch.Suppressed {
+		rules = append(rules, s.Rule)
+	}
+	return rules
+}
+
+// filterLabel returns the rule label changes are filtered by ("" for all).
+func (m ReviewTransformationsModel) filterLabel() string {
+	if m.filter == 0 || m.filter > len(m.labels) {
+		return ""
+	}
+	return m.labels[m.filter-1]
+}
+
+// current returns the index of the change under the cursor, or -1 when none is visible.
+func (m ReviewTransformationsModel) current() int {
+	if m.cursor < 0 || m.cursor >= len(m.visible) {
+		return -1
+	}
+	return m.visible[m.cursor]
+}
+
+func (m ReviewTransformationsModel) updateEditor(msg tea.KeyPressMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		m.editing = false
+		m.editor.Blur()
+		return m, nil
+	case "ctrl+s":
+		m.editing = false
+		m.editor.Blur()
+		if i := m.current(); i >= 0 {
+			m.Decisions[i] = sanitize.Decision{Accept: true, Text: strings.TrimSpace(m.editor.Value())}
+			m.status = fmt.Sprintf("cue #%d edited", m.changes[i].CueIndex)
+		}
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.editor, cmd = m.editor.Update(msg)
+	return m, cmd
+}
+
+// allowPattern rejects the current change and remembers its pattern; every other change
+// whose original contains the same pattern is rejected too (matched on the SRT text, like
+// allow.cuesContaining).
+func (m *ReviewTransformationsModel) allowPattern() {
+	i := m.current()
+	if i < 0 {
+		return
+	}
+	pattern := transform.RejectedPattern(m.changes[i])
+	if pattern == "" {
+		m.status = "no pattern to keep for this change"
+		return
+	}
+	if !slices.Contains(m.CuesContaining, pattern) {
+		m.CuesContaining = append(m.CuesContaining, pattern)
+	}
+	rejected := 0
+	for i, ch := range m.changes {
+		if strings.Contains(transform.SRTText(ch.Original, m.format), pattern) && m.Decisions[i].Accept {
+			m.Decisions[i].Accept = false
+			rejected++
+		}
+	}
+	m.status = fmt.Sprintf("%q will be saved to allow.cuesContaining (%d changes rejected)", pattern, rejected)
+}
+
+// setAll accepts or rejects every visible change (all of them when no filter is set).
+func (m *ReviewTransformationsModel) setAll(accept bool) {
+	for _, i := range m.visible {
+		m.Decisions[i].Accept = accept
+	}
+}
+
+func (m *ReviewTransformationsModel) move(delta int) {
+	if len(m.visible) == 0 {
+		return
+	}
+	m.cursor = min(max(m.cursor+delta, 0), len(m.visible)-1)
+	m.clampOffset()
+}
+
+// clampOffset scrolls the list so the cursor row is visible.
+func (m *ReviewTransformationsModel) clampOffset() {
+	h := m.listHeight()
+	if m.cursor < m.offset {
+		m.offset = m.cursor
+	}
+	if m.cursor >= m.offset+h {
+		m.offset = m.cursor - h + 1
+	}
+	m.offset = max(0, min(m.offset, len(m.visible)-h))
+}
+
+func (m ReviewTransformationsModel) listHeight() int {
+	used := 3 + 3 + 2*reviewDetailLines + 2 // header, details frame and labels, help
+	if m.showRules {
+		used += lipgloss.Height(m.rules) + 2
+	}
+	if m.editing {
+		used += m.editor.Height() + 1
+	}
+	return max(3, m.height-used)
+}
+
+func (m ReviewTransformationsModel) accepted() int {
+	n := 0
+	for _, d := range m.Decisions {
+		if d.Accept {
+			n++
+		}
+	}
+	return n
+}
+
+// suppressed counts the changes where every rule was suppressed (the cue is unchanged).
+func (m ReviewTransformationsModel) suppressed() int {
+	n := 0
+	for _, ch := range m.changes {
+		if !ch.Applied() {
+			n++
+		}
+	}
+	return n
+}
+
+func (m ReviewTransformationsModel) View() tea.View {
+	var b strings.Builder
+	b.WriteString(m.header())
+
+	if m.showRules {
+		b.WriteString(reviewPanelStyle.Render(m.rules) + "\n")
+	}
+
+	if m.preview {
+		b.WriteString(m.previewBody())
+	} else {
+		if len(m.visible) == 0 {
+			b.WriteString("Nothing to remove...\n")
+		}
+		h := m.listHeight()
+		for pos := m.offset; pos < len(m.visible) && pos < m.offset+h; pos++ {
+			b.WriteString(m.row(pos) + "\n")
+		}
+		for pos := len(m.visible) - m.offset; pos < h; pos++ {
+			b.WriteString("\n")
+		}
+		if len(m.visible) > 0 {
+			b.WriteString(m.details())
+		}
+	}
+	if m.editing {
+		b.WriteString("\n" + m.editor.View() + "\n")
+	}
+	if m.status != "" {
+		b.WriteString(reviewStatusStyle.Render(m.status))
+	}
+	b.WriteString(helpView("\n  " + m.help() + "\n"))
+
+	v := tea.NewView(b.String())
+	v.AltScreen = true
+	v.MouseMode = tea.MouseModeCellMotion
+	return v
+}
+
+// header renders the title and the accepted/filter status lines.
+func (m ReviewTransformationsModel) header() string {
+	status := fmt.Sprintf("%d/%d changes accepted", m.accepted(), len(m.changes))
+	if n := m.suppressed(); n > 0 {
+		status += fmt.Sprintf(" • %d kept by allowlist", n)
+	}
+	if label := m.filterLabel(); label != "" {
+		status += fmt.Sprintf(" • filter: %s (%d)", label, len(m.visible))
+	}
+	return reviewTitleStyle.Render("Subtitle Sanitizer • "+m.title) + "\n" + status + "\n\n"
+}
+
+func (m ReviewTransformationsModel) help() string {
+	switch {
+	case m.editing:
+		return "ctrl+s: Save edit • esc: Cancel"
+	case m.preview:
+		return "n/p: Next/previous change • space: Toggle • e: Edit • i: Always keep pattern • f/F: Filter by rule/clear\n" +
+			"  A/R: Accept/Reject all • r: Rules • v/esc: Back to list • q: Quit"
+	}
+	return "↑/↓: Navigate • space: Toggle • e: Edit • i: Always keep pattern • A/R: Accept/Reject all • r: Rules\n" +
+		"  f/F: Filter by rule/clear • v: Side by side • q/x: Quit • esc/n: Skip • enter/a: Apply • o/w: Overwrite • s: srt"
+}
+
+// row renders one visible change on a single line: marker, cue index, original → result, rules.
+func (m ReviewTransformationsModel) row(pos int) string {
+	i := m.visible[pos]
+	ch, d := m.changes[i], m.Decisions[i]
+	mark := "[x]"
+	if !d.Accept {
+		mark = "[ ]"
+	}
+	edited := " "
+	if d.Accept && d.Text != ch.Transformed {
+		edited = "✎"
+	}
+	result := d.Text
+	if !d.Accept {
+		result = ch.Original
+	}
+	line := fmt.Sprintf(" %s%s #%-4d %s → %s  %s",
+		mark, edited, ch.CueIndex, oneLine(ch.Original), orRemoved(oneLine(result)),
+		reviewRulesStyle.Render(ch.RulesLabel()))
+	line = ansi.Truncate(line, max(10, m.width-1), "…")
+	switch {
+	case pos == m.cursor:
+		return reviewCursorStyle.Render(ansi.Strip(line))
+	case !d.Accept:
+		return reviewRejectedStyle.Render(ansi.Strip(line))
+	default:
+		return line
+	}
+}
+
+func (m ReviewTransformationsModel) details() string {
+	i := m.current()
+	ch, d := m.changes[i], m.Decisions[i]
+	result := d.Text
+	if !d.Accept {
+		result = ch.Original + "  (rejected)"
+	}
+	width := max(20, m.width-4)
+	var b strings.Builder
+	original := ch.Original
+	if d.Accept && d.Text == ch.Transformed && len(ch.Edits) > 0 {
+		base := transform.SRTText(ch.Original, m.format)
+		original = highlightSpans(base, transform.BaseSpans(base, ch.Edits))
+	}
+	b.WriteString(reviewLabelStyle.Render("Original") + "\n")
+	b.WriteString(clipLines(original, reviewDetailLines, width) + "\n")
+	b.WriteString(reviewLabelStyle.Render("Result") + "  " + reviewRulesStyle.Render(ch.RulesLabel()) + "\n")
+	b.WriteString(clipLines(orRemoved(result), reviewDetailLines, width))
+	return reviewPanelStyle.Width(m.width-2).Render(b.String()) + "\n"
+}
+
+// clipLines keeps at most n lines of s (padding with empty lines) truncated to width.
+func clipLines(s string, n, width int) string {
+	lines := strings.Split(s, "\n")
+	out := make([]string, n)
+	for i := range n {
+		if i < len(lines) {
+			out[i] = ansi.Truncate(lines[i], width, "…")
+		}
+	}
+	if len(lines) > n {
+		out[n-1] = ansi.Truncate(out[n-1]+" …", width, "…")
+	}
+	return strings.Join(out, "\n")
+}
+
+func oneLine(s string) string {
+	return strings.ReplaceAll(s, "\n", " ⏎ ")
+}
+
+func orRemoved(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "(removed)"
+	}
+	return s
+}
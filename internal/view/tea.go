@@ -11,11 +11,9 @@ import (
 
 	"charm.land/bubbles/v2/progress"
 	"charm.land/bubbles/v2/spinner"
-	"charm.land/bubbles/v2/viewport"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
-	"github.com/charmbracelet/glamour"
-	"github.com/luismascotto/subtitle-sanitizer/internal/mkv"
+	"github.com/luismascotto/subtitle-sanitizer/internal/container"
 )
 
 // ---------------- Bubble Tea "shopping style center"----------------
@@ -54,102 +52,6 @@ var (
 	errorMark = lipgloss.NewStyle().Foreground(lipgloss.Color("160")).SetString("✗")
 )
 
-// ---------------- Review Transformations Model ----------------
-type ReviewTransformationsModel struct {
-	viewport  viewport.Model
-	Quit      bool
-	Apply     bool
-	Skip      bool
-	Overwrite bool
-}
-
-func NewViewPortModel(content string) (*ReviewTransformationsModel, error) {
-
-	const width = 100
-
-	vp := viewport.New(viewport.WithWidth(width), viewport.WithHeight(32))
-	vp.Style = lipgloss.NewStyle().
-		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("62")).
-		PaddingRight(2)
-
-	// We need to adjust the width of the glamour render from our main width
-	// to account for a few things:
-	//
-	//  * The viewport border width
-	//  * The viewport padding
-	//  * The viewport margins
-	//  * The gutter glamour applies to the left side of the content
-	//
-	const glamourGutter = 2
-	glamourRenderWidth := width - vp.Style.GetHorizontalFrameSize() - glamourGutter
-
-	renderer, err := glamour.NewTermRenderer(
-		glamour.WithAutoStyle(),
-		glamour.WithWordWrap(glamourRenderWidth),
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	str, err := renderer.Render(content)
-	if err != nil {
-		return nil, err
-	}
-
-	vp.SetContent(str)
-
-	return &ReviewTransformationsModel{
-		viewport: vp,
-	}, nil
-}
-
-func (m ReviewTransformationsModel) Init() tea.Cmd {
-	return nil
-}
-
-func (m ReviewTransformationsModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	var cmd tea.Cmd
-	switch msg := msg.(type) {
-	case tea.MouseWheelMsg:
-		m.viewport, cmd = m.viewport.Update(msg)
-		return m, cmd
-	case tea.KeyPressMsg:
-		switch msg.String() {
-
-		case "q", "x", "ctrl+c":
-			m.Quit = true
-			return m, tea.Quit
-		case "n", "esc":
-			m.Skip = true
-			return m, tea.Quit
-		case "a", "enter":
-			m.Apply = true
-			return m, tea.Quit
-		case "o", "w":
-			m.Apply = true
-			m.Overwrite = true
-			return m, tea.Quit
-		case "s":
-			m.Overwrite = true
-			return m, tea.Quit
-		default:
-			m.viewport, cmd = m.viewport.Update(msg)
-			return m, cmd
-		}
-	}
-	return m, nil
-}
-
-func (m ReviewTransformationsModel) View() tea.View {
-	content := m.viewport.View() + helpView("\n  ↑/↓: Navigate • q/x: Quit • esc/n: Skip • enter/a: Apply • o/w: Overwrite • s: srt\n")
-	v := tea.NewView(content)
-	v.MouseMode = tea.MouseModeAllMotion
-	return v
-}
-
-// ------------------------------------------------------------
-
 // ---------------- Loader Model ----------------
 
 type LoaderModel struct {
@@ -214,6 +116,7 @@ func NewLoaderModel() LoaderModel {
 // ---------------- Batch Model ----------------
 type BatchModel struct {
 	files    []string
+	opts     container.ExtractOptions
 	count    int
 	index    int
 	width    int
@@ -223,7 +126,7 @@ type BatchModel struct {
 	done     bool
 }
 
-func NewBatchModel(files []string) BatchModel {
+func NewBatchModel(files []string, opts container.ExtractOptions) BatchModel {
 	p := progress.New(
 		progress.WithDefaultBlend(),
 		progress.WithWidth(40),
@@ -234,14 +137,15 @@ func NewBatchModel(files []string) BatchModel {
 	s.Spinner = spinners[rand.Intn(len(spinners))] //nolint:gosec
 	return BatchModel{
 		files:    files,
+		opts:     opts,
 		spinner:  s,
 		progress: p,
 	}
 }
 
 func (m BatchModel) Init() tea.Cmd {
-	//return tea.Batch(extractSubtitles(m.files[m.index]), m.spinner.Tick)
-	return tea.Sequence(m.spinner.Tick, extractSubtitles(m.files[m.index]))
+	//return tea.Batch(extractSubtitles(m.files[m.index], m.opts), m.spinner.Tick)
+	return tea.Sequence(m.spinner.Tick, extractSubtitles(m.files[m.index], m.opts))
 }
 
 func (m BatchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -289,8 +193,8 @@ func (m BatchModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		return m, tea.Batch(
 			progressCmd,
-			tea.Printf("%s %s", finalMark, result), // print success message above our program
-			extractSubtitles(m.files[m.index]),     // download the next package
+			tea.Printf("%s %s", finalMark, result),     // print success message above our program
+			extractSubtitles(m.files[m.index], m.opts), // download the next package
 		)
 	case ErrorExtractingSubtitlesMsg:
 		return m, tea.Quit
@@ -360,10 +264,10 @@ func (m ErrorExtractingSubtitlesMsg) String() string {
 	return string(m)
 }
 
-func extractSubtitles(file string) tea.Cmd {
+func extractSubtitles(file string, opts container.ExtractOptions) tea.Cmd {
 	d := time.Millisecond * time.Duration(200) //nolint:gosec
 	return tea.Tick(d, func(t time.Time) tea.Msg {
-		count, _ := mkv.BatchExtractSubtitles(file)
+		count, _ := container.BatchExtractSubtitles(file, opts)
 		// if err != nil {
 		// 	time.Sleep(5 * time.Second)
 		// 	//fmt.Println("Error extracting subtitles from MKV file", err)
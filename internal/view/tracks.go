@@ -0,0 +1,117 @@
+package view
+
+import (
+	"fmt"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/container"
+)
+
+// ---------------- Track Picker Model ----------------
+
+// TrackPickerModel lists every subtitle stream of a container so the user can choose
+// the one to sanitize. Selected holds the choice when Chosen is true.
+type TrackPickerModel struct {
+	title  string
+	tracks []container.Track
+	cursor int
+	width  int
+
+	Selected container.Track
+	Chosen   bool
+	Quit     bool
+}
+
+// NewTrackPickerModel starts with the cursor on the track with stream index preferred.
+func NewTrackPickerModel(title string, tracks []container.Track, preferred int) TrackPickerModel {
+	m := TrackPickerModel{title: title, tracks: tracks, width: reviewDefaultWidth}
+	for i, t := range tracks {
+		if t.Index == preferred {
+			m.cursor = i
+		}
+	}
+	return m
+}
+
+func (m TrackPickerModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m TrackPickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+	case tea.KeyPressMsg:
+		switch msg.String() {
+		case "q", "esc", "ctrl+c":
+			m.Quit = true
+			return m, tea.Quit
+		case "enter", "space":
+			if len(m.tracks) > 0 {
+				m.Selected = m.tracks[m.cursor]
+				m.Chosen = true
+			}
+			return m, tea.Quit
+		case "up", "k":
+			m.cursor = max(0, m.cursor-1)
+		case "down", "j":
+			m.cursor = min(len(m.tracks)-1, m.cursor+1)
+		case "home", "g":
+			m.cursor = 0
+		case "end", "G":
+			m.cursor = len(m.tracks) - 1
+		}
+	}
+	return m, nil
+}
+
+func (m TrackPickerModel) View() tea.View {
+	var b strings.Builder
+	b.WriteString(reviewTitleStyle.Render("Subtitle Sanitizer • "+m.title) + "\n")
+	fmt.Fprintf(&b, "%d subtitle tracks\n\n", len(m.tracks))
+	b.WriteString(reviewLabelStyle.Render(fmt.Sprintf("  %-4s %-20s %-5s %-6s %-26s %s", "#", "codec", "lang", "cues", "flags", "title")) + "\n")
+	for i, t := range m.tracks {
+		line := trackRow(t)
+		line = ansi.Truncate(line, max(10, m.width-1), "…")
+		switch {
+		case i == m.cursor:
+			line = reviewCursorStyle.Render(line)
+		case !t.IsText():
+			line = reviewRulesStyle.Render(line)
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString(helpView("\n  ↑/↓: Navigate • enter: Sanitize track • q/esc: Quit\n"))
+	return tea.NewView(b.String())
+}
+
+// trackRow renders one track: stream index, codec, language, cue count, flags and title.
+func trackRow(t container.Track) string {
+	var flags []string
+	if t.Default {
+		flags = append(flags, "default")
+	}
+	if t.IsForced() {
+		flags = append(flags, "forced")
+	}
+	if t.SDH() {
+		flags = append(flags, "sdh")
+	}
+	if !t.IsText() {
+		flags = append(flags, "image")
+	}
+	if t.HI != nil {
+		flags = append(flags, fmt.Sprintf("hi %.0f%%", t.HI.Value*100))
+	}
+	lang, cues := t.Language, "?"
+	if lang == "" {
+		lang = "und"
+	}
+	if t.Cues >= 0 {
+		cues = fmt.Sprint(t.Cues)
+	}
+	return fmt.Sprintf("  %-4d %-20s %-5s %-6s %-26s %s", t.Index, t.Codec, lang, cues, strings.Join(flags, ","), t.Title)
+}
@@ -0,0 +1,166 @@
+package wasmbridge
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/lint"
+	"github.com/luismascotto/subtitle-sanitizer/internal/model"
+	"github.com/luismascotto/subtitle-sanitizer/internal/sanitize"
+	"github.com/luismascotto/subtitle-sanitizer/internal/subtitle"
+	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
+)
+
+// DefaultCacheSize is how many compiled configs a Processor keeps by default.
+const DefaultCacheSize = 32
+
+var defaultProcessor = NewProcessor(DefaultCacheSize)
+
+// Processor runs requests like [Process], [Lint] and [Convert], keeping the compiled
+// rules of the last configs it saw keyed by the SHA-256 of the config JSON, so callers
+// sending the same config skip validation and regex compilation. Safe for concurrent use.
+type Processor struct {
+	mu     sync.Mutex
+	size   int
+	rules  map[[sha256.Size]byte]transform.Rules
+	order  [][sha256.Size]byte // least recently used first
+	hits   int
+	misses int
+}
+
+// CacheStats are the counters of a Processor's compiled-rules cache.
+type CacheStats struct {
+	Entries int `json:"entries"`
+	Size    int `json:"size"`
+	Hits    int `json:"hits"`
+	Misses  int `json:"misses"`
+}
+
+// NewProcessor returns a Processor caching up to size compiled configs (size <= 0:
+// DefaultCacheSize).
+func NewProcessor(size int) *Processor {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &Processor{size: size, rules: map[[sha256.Size]byte]transform.Rules{}}
+}
+
+// Stats returns the cache counters.
+func (p *Processor) Stats() CacheStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return CacheStats{Entries: len(p.rules), Size: p.size, Hits: p.hits, Misses: p.misses}
+}
+
+// compiled returns the rules of a request config, compiling and caching them on a miss.
+// Invalid configs are not cached.
+func (p *Processor) compiled(raw json.RawMessage) (transform.Rules, error) {
+	key := sha256.Sum256([]byte(strings.TrimSpace(string(raw))))
+	p.mu.Lock()
+	if r, ok := p.rules[key]; ok {
+		p.hits++
+		i := slices.Index(p.order, key)
+		p.order = append(slices.Delete(p.order, i, i+1), key)
+		p.mu.Unlock()
+		return r, nil
+	}
+	p.misses++
+	p.mu.Unlock()
+
+	conf, err := configFromJSON(raw)
+	if err != nil {
+		return transform.Rules{}, err
+	}
+	r := transform.NewRules(conf)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.rules[key]; !ok {
+		if len(p.order) >= p.size {
+			delete(p.rules, p.order[0])
+			p.order = p.order[1:]
+		}
+		p.order = append(p.order, key)
+	}
+	p.rules[key] = r
+	return r, nil
+}
+
+// Process is [Process] with this Processor's cache.
+func (p *Processor) Process(body []byte) []byte {
+	req, raw, format, err := decode(body)
+	if err != nil {
+		return mustJSONErr(err)
+	}
+	r, err := p.compiled(req.Config)
+	if err != nil {
+		return configError(err)
+	}
+	doc, err := subtitle.Parse(raw, format)
+	if err != nil {
+		return mustJSONErr(err)
+	}
+	res := sanitize.ApplyRules(*doc, r.ForFile(req.Name))
+	score := hi.Analyze(*doc)
+
+	out := Response{
+		OK:      true,
+		SRT:     string(res.SRT),
+		Changes: newChanges(res.Changes, doc.Format),
+		HI:      &score,
+	}
+	return mustJSON(out)
+}
+
+// Lint is [Lint] with this Processor's cache.
+func (p *Processor) Lint(body []byte) []byte {
+	req, raw, format, err := decode(body)
+	if err != nil {
+		return mustJSONErr(err)
+	}
+	r, err := p.compiled(req.Config)
+	if err != nil {
+		return configError(err)
+	}
+	th, err := lint.ThresholdsFromConfig(r.Config().Lint, req.Preset)
+	if err != nil {
+		return mustJSONErr(err)
+	}
+
+	report := lint.FileReport{Path: req.Name, Issues: []lint.Issue{}}
+	var doc *model.Document
+	if format == model.SubtitleFormatSRT {
+		var diags []subtitle.Diagnostic
+		doc, diags, err = subtitle.ParseSRTDiagnostics(raw, true)
+		report.Issues = append(report.Issues, lint.ParseIssues(diags)...)
+	} else {
+		doc, err = subtitle.Parse(raw, format)
+	}
+	if err != nil {
+		return mustJSONErr(err)
+	}
+	report.Cues = len(doc.Cues)
+	report.Issues = append(report.Issues, lint.Document(*doc, th)...)
+	return mustJSON(Response{OK: true, Lint: &report})
+}
+
+// Convert is [Convert]; it needs no rules.
+func (p *Processor) Convert(body []byte) []byte {
+	_, raw, format, err := decode(body)
+	if err != nil {
+		return mustJSONErr(err)
+	}
+	doc, err := subtitle.Parse(raw, format)
+	if err != nil {
+		return mustJSONErr(err)
+	}
+	for _, c := range doc.Cues {
+		c.Lines = transform.SRTText(c.Lines, doc.Format)
+	}
+	doc.Format = model.SubtitleFormatSRT
+	return mustJSON(Response{OK: true, SRT: string(subtitle.FormatSRT(*doc))})
+}
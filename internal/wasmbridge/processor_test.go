@@ -0,0 +1,95 @@
+package wasmbridge
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const assSample = "[Script Info]\nScriptType: v4.00+\n\n[Events]\n" +
+	"Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n" +
+	"Dialogue: 0,0:00:01.00,0:00:02.00,Default,,0,0,0,,{\\i1}(door) Hi{\\i0}\n"
+
+func decodeResponse(t *testing.T, out []byte) Response {
+	t.Helper()
+	var resp Response
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("%v: %s", err, out)
+	}
+	return resp
+}
+
+func TestProcessor_cache(t *testing.T) {
+	t.Parallel()
+
+	p := NewProcessor(2)
+	request := func(config string) Response {
+		body, _ := json.Marshal(map[string]any{
+			"subtitle": "1\n00:00:01,000 --> 00:00:02,000\nKAREN: [door] Hi\n",
+			"config":   json.RawMessage(config),
+		})
+		return decodeResponse(t, p.Process(body))
+	}
+	light := `{"extends": "light"}`
+	for _, config := range []string{light, light, "{}", `{"extends": "aggressive"}`, light} {
+		if resp := request(config); !resp.OK {
+			t.Fatalf("%s: %s", config, resp.Error)
+		}
+	}
+	// light is evicted by aggressive (size 2), so the last request misses again.
+	if got, want := p.Stats(), (CacheStats{Entries: 2, Size: 2, Hits: 1, Misses: 4}); got != want {
+		t.Fatalf("Stats = %+v, want %+v", got, want)
+	}
+	if resp := request(light); resp.SRT != "1\n00:00:01,000 --> 00:00:02,000\nKAREN: Hi\n" {
+		t.Fatalf("cached light rules: %q", resp.SRT)
+	}
+
+	if resp := request(`{"removeBetweenDelimiters": 1}`); resp.OK || len(resp.ConfigErrors) == 0 {
+		t.Fatalf("invalid config: %+v", resp)
+	}
+	if got := p.Stats(); got.Entries != 2 || got.Misses != 5 {
+		t.Fatalf("invalid config cached: %+v", got)
+	}
+}
+
+func TestLint(t *testing.T) {
+	t.Parallel()
+
+	body, _ := json.Marshal(map[string]any{
+		"subtitle": "1\n00:00:01,000 --> 00:00:01,100\nThis line is far too long to be read in a tenth of a second\n\n" +
+			"2\n00:00:03,000 --> 00:00:02,000\nBackwards\n",
+		"name":   "a.srt",
+		"preset": "netflix",
+	})
+	resp := decodeResponse(t, Lint(body))
+	if !resp.OK || resp.Lint == nil {
+		t.Fatalf("lint: %+v", resp)
+	}
+	if resp.Lint.Path != "a.srt" || resp.Lint.Cues != 2 {
+		t.Fatalf("report: %+v", resp.Lint)
+	}
+	if errs, _ := resp.Lint.Counts(); errs == 0 {
+		t.Fatalf("expected errors: %+v", resp.Lint.Issues)
+	}
+
+	body, _ = json.Marshal(map[string]any{"subtitle": "1\n00:00:01,000 --> 00:00:02,000\nHi\n", "preset": "nope"})
+	if resp := decodeResponse(t, Lint(body)); resp.OK {
+		t.Fatal("unknown preset should fail")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	t.Parallel()
+
+	body, _ := json.Marshal(map[string]any{"subtitle": assSample})
+	resp := decodeResponse(t, Convert(body))
+	if !resp.OK || resp.SRT != "1\n00:00:01,000 --> 00:00:02,000\n<i>(door) Hi</i>\n" {
+		t.Fatalf("convert: %+v", resp)
+	}
+	if len(resp.Changes) != 0 || resp.HI != nil {
+		t.Fatalf("convert sanitized: %+v", resp)
+	}
+	if resp := decodeResponse(t, Convert([]byte(`{}`))); resp.OK || !strings.Contains(resp.Error, "required") {
+		t.Fatalf("empty request: %+v", resp)
+	}
+}
@@ -3,15 +3,19 @@
 package wasmbridge
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
 
+	"github.com/luismascotto/subtitle-sanitizer/internal/hi"
+	"github.com/luismascotto/subtitle-sanitizer/internal/lint"
+	"github.com/luismascotto/subtitle-sanitizer/internal/matroska"
 	"github.com/luismascotto/subtitle-sanitizer/internal/model"
 	"github.com/luismascotto/subtitle-sanitizer/internal/rules"
-	"github.com/luismascotto/subtitle-sanitizer/internal/sanitize"
 	"github.com/luismascotto/subtitle-sanitizer/internal/transform"
 )
 
@@ -23,52 +27,96 @@ type Request struct {
 	SubtitleB64 string `json:"subtitleB64"`
 	//Format      string          `json:"format"`
 	Config json.RawMessage `json:"config"`
+	// Track is the stream index of the subtitle to sanitize when subtitleB64 is an MKV
+	// (default: English, not SDH, not forced, text first).
+	Track *int `json:"track,omitempty"`
+	// Name is the subtitle file name, matched against the config's allow.cues.
+	Name string `json:"name,omitempty"`
+	// Preset is the lint threshold preset of [Lint] (default: config lint.preset or netflix).
+	Preset string `json:"preset,omitempty"`
 }
 
 // Response is the JSON returned by [Process].
 type Response struct {
-	OK      bool                  `json:"ok"`
-	Error   string                `json:"error,omitempty"`
-	SRT     string                `json:"srt,omitempty"`
-	Changes []transform.CueChange `json:"changes,omitempty"`
+	OK      bool     `json:"ok"`
+	Error   string   `json:"error,omitempty"`
+	SRT     string   `json:"srt,omitempty"`
+	Changes []Change `json:"changes,omitempty"`
+	// HI scores the input for hearing-impaired markers ("clean": nothing to sanitize).
+	HI *hi.Score `json:"hi,omitempty"`
+	// ConfigErrors lists the problems of an invalid request config (lines and columns
+	// are relative to the "config" value), as `config validate` reports them.
+	ConfigErrors rules.ValidationErrors `json:"configErrors,omitempty"`
+	// Lint is the report of [Lint].
+	Lint *lint.FileReport `json:"lint,omitempty"`
+}
+
+// Change is a CueChange with the ranges of its base text that its edits removed or
+// replaced, for highlighting.
+type Change struct {
+	transform.CueChange
+	// Base is the text edits and spans apply to, when it is not Original (ASS input:
+	// override tags converted to SRT tags).
+	Base  string           `json:"base,omitempty"`
+	Spans []transform.Span `json:"spans,omitempty"`
+}
+
+// newChanges adds base texts and spans to changes of a document in format.
+func newChanges(changes []transform.CueChange, format model.SubtitleFormat) []Change {
+	out := make([]Change, len(changes))
+	for i, ch := range changes {
+		base := transform.SRTText(ch.Original, format)
+		out[i] = Change{CueChange: ch, Spans: transform.BaseSpans(base, ch.Edits)}
+		if base != ch.Original {
+			out[i].Base = base
+		}
+	}
+	return out
 }
 
 // Process runs parse + sanitize from JSON bytes and returns JSON (always valid on best effort).
+// Compiled rules are cached by config (see [Processor]).
 func Process(body []byte) []byte {
-	var req Request
-	if err := json.Unmarshal(body, &req); err != nil {
-		return mustJSONErrStr(fmt.Sprintf("json: %v", err))
-	}
-	var (
-		err    error
-		raw    []byte
-		format model.SubtitleFormat
-		conf   rules.Config
-		res    sanitize.Result
-	)
+	return defaultProcessor.Process(body)
+}
 
-	if raw, err = subtitleBytes(&req); err != nil {
-		return mustJSONErr(err)
-	}
+// Lint parses the subtitle of a request and runs the lint checks with the thresholds
+// of its config's "lint" section (and preset); the result is in Response.Lint.
+func Lint(body []byte) []byte {
+	return defaultProcessor.Lint(body)
+}
 
-	if format, err = parseFormat(req.Subtitle[:min(1024, len(raw))]); err != nil {
-		return mustJSONErr(err)
-	}
+// Convert returns the subtitle of a request as SRT without sanitizing it (ASS override
+// tags become SRT tags).
+func Convert(body []byte) []byte {
+	return defaultProcessor.Convert(body)
+}
 
-	if conf, err = configFromJSON(req.Config); err != nil {
-		return mustJSONErr(err)
+// decode reads the subtitle of a JSON request: its bytes (demuxed from MKV) and format.
+func decode(body []byte) (Request, []byte, model.SubtitleFormat, error) {
+	var req Request
+	if err := json.Unmarshal(body, &req); err != nil {
+		return req, nil, model.SubtitleFormatUnknown, fmt.Errorf("json: %v", err)
 	}
-
-	if res, err = sanitize.ParseAndApply(raw, format, conf); err != nil {
-		return mustJSONErr(err)
+	raw, err := subtitleBytes(&req)
+	if err != nil {
+		return req, nil, model.SubtitleFormatUnknown, err
+	}
+	if matroska.IsMatroska(raw) {
+		raw, format, err := subtitleFromMatroska(raw, req.Track)
+		return req, raw, format, err
 	}
+	format, err := parseFormat(string(raw[:min(1024, len(raw))]))
+	return req, raw, format, err
+}
 
-	out := Response{
-		OK:      true,
-		SRT:     string(res.SRT),
-		Changes: res.Changes,
+// configError is the response to a request whose config cannot be used.
+func configError(err error) []byte {
+	var verrs rules.ValidationErrors
+	if errors.As(err, &verrs) {
+		return mustJSON(Response{OK: false, Error: "invalid config: " + err.Error(), ConfigErrors: verrs})
 	}
-	return mustJSON(out)
+	return mustJSONErr(err)
 }
 
 func subtitleBytes(req *Request) ([]byte, error) {
@@ -81,6 +129,58 @@ func subtitleBytes(req *Request) ([]byte, error) {
 	return nil, fmt.Errorf("subtitle or subtitleB64 is required")
 }
 
+// subtitleFromMatroska demuxes the SRT or ASS track with stream index track (or the
+// preferred one) from an MKV held in memory.
+func subtitleFromMatroska(raw []byte, track *int) ([]byte, model.SubtitleFormat, error) {
+	f, err := matroska.Open(bytes.NewReader(raw), int64(len(raw)))
+	if err != nil {
+		return nil, model.SubtitleFormatUnknown, err
+	}
+	var picked *matroska.Track
+	best := -1
+	for _, t := range f.SubtitleTracks() {
+		ext := t.Extension()
+		if ext != ".srt" && ext != ".ass" {
+			continue
+		}
+		if track != nil {
+			if t.Index == *track {
+				picked = &t
+				break
+			}
+			continue
+		}
+		// English, then not SDH, then not forced; the first track wins ties.
+		score := 0
+		if strings.EqualFold(t.Language, "eng") {
+			score += 4
+		}
+		if !t.HearingImpaired && !strings.Contains(strings.ToLower(t.Name), "sdh") {
+			score += 2
+		}
+		if !t.Forced && !strings.Contains(strings.ToLower(t.Name), "forced") {
+			score++
+		}
+		if score > best {
+			picked, best = &t, score
+		}
+	}
+	if picked == nil {
+		if track != nil {
+			return nil, model.SubtitleFormatUnknown, fmt.Errorf("mkv: no SRT or ASS subtitle track with index %d", *track)
+		}
+		return nil, model.SubtitleFormatUnknown, fmt.Errorf("mkv: no SRT or ASS subtitle track")
+	}
+	data, err := f.ExtractSubtitle(*picked)
+	if err != nil {
+		return nil, model.SubtitleFormatUnknown, err
+	}
+	if picked.Extension() == ".ass" {
+		return data, model.SubtitleFormatASS, nil
+	}
+	return data, model.SubtitleFormatSRT, nil
+}
+
 func parseFormat(peekSubtitle string) (model.SubtitleFormat, error) {
 	//Happy path first no allocation (almost)
 	//check on body for ASS
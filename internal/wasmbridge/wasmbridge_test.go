@@ -1,6 +1,7 @@
 package wasmbridge
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"os"
 	"path/filepath"
@@ -26,6 +27,12 @@ func TestProcess_minimalSRT(t *testing.T) {
 	if len(resp.Changes) < 1 {
 		t.Fatalf("expected changes, got %+v", resp.Changes)
 	}
+	if spans := resp.Changes[0].Spans; len(spans) != 2 || spans[0].Start != 6 || spans[0].End != 9 || spans[1].End != 10 {
+		t.Fatalf("unexpected spans: %+v", spans)
+	}
+	if resp.HI == nil || resp.HI.Cues != 1 || resp.HI.Bracketed != 1 || resp.HI.Level != "sdh" {
+		t.Fatalf("unexpected hi: %+v", resp.HI)
+	}
 }
 
 func TestProcess_invalidJSON(t *testing.T) {
@@ -39,6 +46,30 @@ func TestProcess_invalidJSON(t *testing.T) {
 	}
 }
 
+func TestProcess_invalidConfig(t *testing.T) {
+	req := `{
+		"subtitle": "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n",
+		"config": {
+  "removeBetweenDelimeters": [],
+  "removeTextBeforeColon": 1}
+	}`
+	out := Process([]byte(req))
+	var resp Response
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.OK || len(resp.ConfigErrors) != 2 {
+		t.Fatalf("expected 2 config errors: %s", out)
+	}
+	first := resp.ConfigErrors[0]
+	if first.Path != "removeBetweenDelimeters" || first.Line != 2 || first.Col != 3 || !strings.Contains(first.Msg, "removeBetweenDelimiters") {
+		t.Fatalf("unexpected first error: %+v", first)
+	}
+	if !strings.Contains(resp.Error, "line 3, col 28: removeTextBeforeColon: expected a boolean") {
+		t.Fatalf("unexpected error: %q", resp.Error)
+	}
+}
+
 // TestProcess_fullAssGolden matches sub-example.ass + config.test.json → *-his-expected.srt (WASM JSON contract).
 func TestProcess_fullAssGolden(t *testing.T) {
 	dir := filepath.Join("testdata", "full_ass")
@@ -93,3 +124,36 @@ func trunc(s string, n int) string {
 	}
 	return s[:n] + "..."
 }
+
+// sample.mkv: spa SDH SRT track (index 1) and an eng forced "Signs" ASS track (index 2).
+func TestProcess_mkv(t *testing.T) {
+	mkv, err := os.ReadFile(filepath.Join("testdata", "sample.mkv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		track string
+		want  string
+	}{
+		{track: "", want: "First\nline"},
+		{track: `, "track": 1`, want: "Hola\n"},
+	} {
+		req := `{"subtitleB64": "` + base64.StdEncoding.EncodeToString(mkv) + `"` + tc.track + `}`
+		var resp Response
+		if err := json.Unmarshal(Process([]byte(req)), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if !resp.OK {
+			t.Fatalf("track %q: ok=false: %s", tc.track, resp.Error)
+		}
+		if !strings.Contains(resp.SRT, tc.want) || strings.Contains(resp.SRT, "[música]") {
+			t.Fatalf("track %q: srt = %q", tc.track, resp.SRT)
+		}
+	}
+
+	var resp Response
+	json.Unmarshal(Process([]byte(`{"subtitleB64": "`+base64.StdEncoding.EncodeToString(mkv)+`", "track": 0}`)), &resp)
+	if resp.OK {
+		t.Fatal("track 0 (video) should fail")
+	}
+}
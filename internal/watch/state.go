@@ -0,0 +1,104 @@
+package watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileState is what the watch daemon did with one file.
+type FileState struct {
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"modTime"`
+	Processed time.Time `json:"processed"`
+	Source    string    `json:"source,omitempty"`  // outputs: the input they were made from
+	Outputs   []string  `json:"outputs,omitempty"` // inputs: the files written (or moved) for them
+	Error     string    `json:"error,omitempty"`
+}
+
+// State remembers the files a watch daemon handled, by path, size and modification
+// time, so that restarts, rescans and its own outputs do not get processed again. It is
+// a JSON file rewritten after every change. Safe for concurrent use.
+type State struct {
+	path  string
+	mu    sync.Mutex
+	files map[string]FileState
+}
+
+// OpenState reads the state file at path (missing: empty). Files that no longer exist
+// are forgotten.
+func OpenState(path string) (*State, error) {
+	s := &State{path: path, files: map[string]FileState{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("watch state: %w", err)
+	}
+	var doc struct {
+		Files map[string]FileState `json:"files"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("watch state %s: %w", path, err)
+	}
+	for file, fs := range doc.Files {
+		if _, err := os.Stat(file); err == nil {
+			s.files[file] = fs
+		}
+	}
+	return s, nil
+}
+
+// Done reports whether path was handled as it is now (same size and modification time).
+func (s *State) Done(path string) bool {
+	st, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fs, ok := s.files[path]
+	return ok && fs.Size == st.Size() && fs.ModTime.Equal(st.ModTime())
+}
+
+// Record stores fs for path, with the size and modification time path has now, and
+// saves the state file. Paths that do not exist (eg: moved away) are not recorded.
+func (s *State) Record(path string, fs FileState) error {
+	st, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	fs.Size, fs.ModTime = st.Size(), st.ModTime()
+	if fs.Processed.IsZero() {
+		fs.Processed = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.files[path] = fs
+	return s.save()
+}
+
+func (s *State) save() error {
+	data, err := json.MarshalIndent(struct {
+		Files map[string]FileState `json:"files"`
+	}{s.files}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("watch state: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("watch state: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("watch state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package watch
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// templateFields are the placeholders an output Template may use.
+var templateFields = map[string]func(f templateInput) string{
+	"dir":  func(f templateInput) string { return filepath.Dir(f.path) },
+	"root": func(f templateInput) string { return f.root },
+	"rel": func(f templateInput) string {
+		rel, err := filepath.Rel(f.root, filepath.Dir(f.path))
+		if err != nil || rel == "." {
+			return ""
+		}
+		return rel
+	},
+	"base": func(f templateInput) string {
+		name := filepath.Base(f.path)
+		return strings.TrimSuffix(name, filepath.Ext(name))
+	},
+	"name": func(f templateInput) string { return filepath.Base(f.path) },
+	"date": func(f templateInput) string { return f.now.Format(time.DateOnly) },
+}
+
+type templateInput struct {
+	root, path string
+	now        time.Time
+}
+
+// Template places the output of a watched file, eg: "/media/library/{rel}/{base}.srt" or
+// "{dir}/clean/{base}.srt". Fields: {dir} (the file's directory), {root} (the watched
+// directory), {rel} (the file's directory relative to it), {base} (the file name without
+// extension), {name} (the file name) and {date} (YYYY-MM-DD). A relative result is
+// relative to the file's directory.
+type Template struct {
+	tmpl string
+}
+
+// ParseTemplate validates tmpl.
+func ParseTemplate(tmpl string) (Template, error) {
+	if strings.TrimSpace(tmpl) == "" {
+		return Template{}, fmt.Errorf("output template is empty")
+	}
+	rest := tmpl
+	for {
+		open := strings.IndexByte(rest, '{')
+		literal := rest
+		if open >= 0 {
+			literal = rest[:open]
+		}
+		if strings.ContainsRune(literal, '}') {
+			return Template{}, fmt.Errorf("output template %q: unmatched }", tmpl)
+		}
+		if open < 0 {
+			break
+		}
+		end := strings.IndexByte(rest[open:], '}')
+		if end < 0 {
+			return Template{}, fmt.Errorf("output template %q: unclosed {", tmpl)
+		}
+		if _, ok := templateFields[rest[open+1:open+end]]; !ok {
+			return Template{}, fmt.Errorf("output template %q: unknown field {%s}", tmpl, rest[open+1:open+end])
+		}
+		rest = rest[open+end+1:]
+	}
+	return Template{tmpl: tmpl}, nil
+}
+
+func (t Template) String() string { return t.tmpl }
+
+// Path renders the template for path, found in the watched directory root, at now.
+func (t Template) Path(root, path string, now time.Time) string {
+	in := templateInput{root: root, path: path, now: now}
+	var sb strings.Builder
+	rest := t.tmpl
+	for {
+		open := strings.IndexByte(rest, '{')
+		if open < 0 {
+			sb.WriteString(rest)
+			break
+		}
+		end := open + strings.IndexByte(rest[open:], '}')
+		sb.WriteString(rest[:open])
+		sb.WriteString(templateFields[rest[open+1:end]](in))
+		rest = rest[end+1:]
+	}
+	out := filepath.FromSlash(sb.String())
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(filepath.Dir(path), out)
+	}
+	return filepath.Clean(out)
+}
@@ -0,0 +1,258 @@
+// Package watch reports files dropped into directories once they stopped growing, for
+// the watch-folder daemon: file system events when the platform has them, polling
+// otherwise (or on request, eg: for network shares). It also keeps the state file of
+// processed inputs and renders output path templates.
+package watch
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/batch"
+)
+
+// Options configures Run.
+type Options struct {
+	Dirs     []string
+	Filter   batch.Options // files reported (Recursive also watches subdirectories)
+	Interval time.Duration // polling period and how often pending files are checked (default 2s)
+	Settle   time.Duration // how long size and modification time must hold still (default 5s)
+	Poll     bool          // poll even when file system events are available
+	Once     bool          // report the files found at start once stable, then return
+	Logger   *slog.Logger  // default slog.Default()
+}
+
+// stamp is what must stay the same for a file to be stable.
+type stamp struct {
+	size int64
+	mod  int64 // unix nanoseconds
+}
+
+type pending struct {
+	stamp
+	root  string
+	since time.Time // when stamp was first seen
+}
+
+type watcher struct {
+	opts     Options
+	log      *slog.Logger
+	fw       *fsnotify.Watcher // nil when polling
+	pending  map[string]pending
+	reported map[string]stamp
+}
+
+// Run calls handle with each file of opts.Dirs the filter accepts, once its size and
+// modification time held still for opts.Settle: at start for the files already there,
+// then whenever one is added or changes. Empty files are waited on (with Once, skipped).
+// handle runs on the calling goroutine, so files are handled one at a time; Run returns
+// when ctx is done (after the current handle returns) or, with Once, when no file is
+// left pending.
+func Run(ctx context.Context, opts Options, handle func(root, path string)) error {
+	if opts.Interval <= 0 {
+		opts.Interval = 2 * time.Second
+	}
+	if opts.Settle <= 0 {
+		opts.Settle = 5 * time.Second
+	}
+	w := &watcher{opts: opts, log: opts.Logger, pending: map[string]pending{}, reported: map[string]stamp{}}
+	if w.log == nil {
+		w.log = slog.Default()
+	}
+	for _, dir := range opts.Dirs {
+		if st, err := os.Stat(dir); err != nil {
+			return err
+		} else if !st.IsDir() {
+			return &fs.PathError{Op: "watch", Path: dir, Err: errors.New("not a directory")}
+		}
+	}
+
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	mode := "poll"
+	if !opts.Poll && !opts.Once {
+		if err := w.notify(); err != nil {
+			w.log.Warn("file system events unavailable, polling", "err", err)
+		} else {
+			defer w.fw.Close()
+			events, errs, mode = w.fw.Events, w.fw.Errors, "events"
+		}
+	}
+	w.log.Info("watching", "dirs", opts.Dirs, "mode", mode, "interval", opts.Interval.String(), "settle", opts.Settle.String())
+	w.scan()
+
+	tick := time.NewTicker(opts.Interval)
+	defer tick.Stop()
+	for {
+		for _, p := range w.ready(time.Now()) {
+			if ctx.Err() != nil {
+				return nil
+			}
+			handle(p.root, p.path)
+		}
+		if opts.Once && len(w.pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-events:
+			w.event(ev)
+		case err := <-errs:
+			w.log.Warn("file system events", "err", err)
+		case <-tick.C:
+			if events == nil && !opts.Once {
+				w.scan()
+			}
+		}
+	}
+}
+
+// notify watches every directory (and subdirectory, when recursive) for events.
+func (w *watcher) notify() error {
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	w.fw = fw
+	for _, dir := range w.opts.Dirs {
+		if err := w.add(dir); err != nil {
+			fw.Close()
+			w.fw = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *watcher) add(dir string) error {
+	if !w.opts.Filter.Recursive {
+		return w.fw.Add(dir)
+	}
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return err
+		}
+		return w.fw.Add(path)
+	})
+}
+
+func (w *watcher) event(ev fsnotify.Event) {
+	root := w.root(ev.Name)
+	if root == "" {
+		return
+	}
+	st, err := os.Stat(ev.Name)
+	if err != nil {
+		delete(w.pending, ev.Name)
+		delete(w.reported, ev.Name)
+		return
+	}
+	if st.IsDir() {
+		// A new subdirectory (eg: a season folder moved in): watch it and list its files.
+		if ev.Has(fsnotify.Create) && w.opts.Filter.Recursive {
+			if err := w.add(ev.Name); err != nil {
+				w.log.Warn("watch directory", "dir", ev.Name, "err", err)
+			}
+			w.scanDir(root, ev.Name)
+		}
+		return
+	}
+	if w.opts.Filter.Accepts(root, ev.Name) {
+		w.touch(root, ev.Name, st)
+	}
+}
+
+// root is the watched directory path is under ("" for none).
+func (w *watcher) root(path string) string {
+	for _, dir := range w.opts.Dirs {
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// scan lists every watched directory. Errors (eg: a directory removed) are logged.
+func (w *watcher) scan() {
+	for _, dir := range w.opts.Dirs {
+		w.scanDir(dir, dir)
+	}
+}
+
+func (w *watcher) scanDir(root, dir string) {
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && !w.opts.Filter.Recursive {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if !w.opts.Filter.Accepts(root, path) {
+			return nil
+		}
+		if st, err := d.Info(); err == nil {
+			w.touch(root, path, st)
+		}
+		return nil
+	})
+	if err != nil {
+		w.log.Warn("scan", "dir", dir, "err", err)
+	}
+}
+
+// touch starts (or restarts) the settle time of path unless it is unchanged since it
+// was reported or last seen.
+func (w *watcher) touch(root, path string, st fs.FileInfo) {
+	s := stamp{size: st.Size(), mod: st.ModTime().UnixNano()}
+	if r, ok := w.reported[path]; ok && r == s {
+		return
+	}
+	if p, ok := w.pending[path]; ok && p.stamp == s {
+		return
+	}
+	w.pending[path] = pending{stamp: s, root: root, since: time.Now()}
+}
+
+type readyFile struct{ root, path string }
+
+// ready returns the pending files that held still for the settle time, in path order,
+// and marks them reported.
+func (w *watcher) ready(now time.Time) []readyFile {
+	var out []readyFile
+	for path, p := range w.pending {
+		st, err := os.Stat(path)
+		if err != nil {
+			delete(w.pending, path)
+			continue
+		}
+		s := stamp{size: st.Size(), mod: st.ModTime().UnixNano()}
+		switch {
+		case s != p.stamp:
+			w.pending[path] = pending{stamp: s, root: p.root, since: now}
+		case now.Sub(p.since) < w.opts.Settle:
+		case s.size > 0:
+			delete(w.pending, path)
+			w.reported[path] = s
+			out = append(out, readyFile{root: p.root, path: path})
+		case w.opts.Once:
+			delete(w.pending, path)
+			w.log.Warn("skipping empty file", "file", path)
+		}
+	}
+	slices.SortFunc(out, func(a, b readyFile) int { return strings.Compare(a.path, b.path) })
+	return out
+}
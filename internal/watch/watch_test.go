@@ -0,0 +1,173 @@
+package watch
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/luismascotto/subtitle-sanitizer/internal/batch"
+)
+
+func quietLogger() *slog.Logger { return slog.New(slog.NewTextHandler(io.Discard, nil)) }
+
+func TestRun_once(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	for _, rel := range []string{"a.srt", "b-his.srt", "notes.txt", "S01/e1.ass", "empty.srt"} {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		data := "1\n"
+		if rel == "empty.srt" {
+			data = ""
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	err := Run(context.Background(), Options{
+		Dirs:     []string{root},
+		Filter:   batch.Options{Recursive: true, Extensions: []string{".srt", ".ass"}, Exclude: []string{"*-his.srt"}},
+		Interval: 5 * time.Millisecond,
+		Settle:   20 * time.Millisecond,
+		Once:     true,
+		Logger:   quietLogger(),
+	}, func(r, path string) {
+		if r != root {
+			t.Errorf("root = %s", r)
+		}
+		rel, _ := filepath.Rel(root, path)
+		got = append(got, filepath.ToSlash(rel))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"S01/e1.ass", "a.srt"}; !slices.Equal(got, want) {
+		t.Fatalf("handled %v, want %v", got, want)
+	}
+}
+
+func TestRun_events(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	handled := make(chan string, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- Run(ctx, Options{
+			Dirs:     []string{root},
+			Filter:   batch.Options{Extensions: []string{".srt"}},
+			Interval: 10 * time.Millisecond,
+			Settle:   50 * time.Millisecond,
+			Logger:   quietLogger(),
+		}, func(_, path string) { handled <- filepath.Base(path) })
+	}()
+
+	// A file written in two steps is reported once, after it stopped growing.
+	path := filepath.Join(root, "new.srt")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("1\n")
+	time.Sleep(20 * time.Millisecond)
+	f.WriteString("00:00:01,000 --> 00:00:02,000\nHi\n")
+	f.Close()
+	os.WriteFile(filepath.Join(root, "skip.txt"), []byte("x"), 0644)
+
+	select {
+	case name := <-handled:
+		if name != "new.srt" {
+			t.Fatalf("handled %s", name)
+		}
+	case <-ctx.Done():
+		t.Fatal("new.srt was not reported")
+	}
+	select {
+	case name := <-handled:
+		t.Fatalf("reported again: %s", name)
+	case <-time.After(200 * time.Millisecond):
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestState(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "a.srt"), filepath.Join(dir, "gone.srt")
+	os.WriteFile(in, []byte("1\n"), 0644)
+	os.WriteFile(out, []byte("2\n"), 0644)
+	path := filepath.Join(dir, "state", "watch-state.json")
+
+	s, err := OpenState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Done(in) {
+		t.Fatal("Done before Record")
+	}
+	if err := s.Record(in, FileState{Outputs: []string{out}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Record(out, FileState{Source: in}); err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(out)
+
+	s, err = OpenState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.Done(in) || s.Done(out) || len(s.files) != 1 {
+		t.Fatalf("reopened state: %+v", s.files)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(in, later, later)
+	if s.Done(in) {
+		t.Fatal("Done after the file changed")
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.FromSlash("/incoming")
+	path := filepath.FromSlash("/incoming/Show/S01/Show.S01E02.en.mkv")
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	tests := []struct{ tmpl, want string }{
+		{"/library/{rel}/{base}.srt", "/library/Show/S01/Show.S01E02.en.srt"},
+		{"clean/{base}.srt", "/incoming/Show/S01/clean/Show.S01E02.en.srt"},
+		{"{root}/../done/{date}/{name}.srt", "/done/2026-10-18/Show.S01E02.en.mkv.srt"},
+		{"{dir}/{base}.srt", "/incoming/Show/S01/Show.S01E02.en.srt"},
+	}
+	for _, tt := range tests {
+		tmpl, err := ParseTemplate(tt.tmpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := tmpl.Path(root, path, now); got != filepath.FromSlash(tt.want) {
+			t.Errorf("%s: got %s, want %s", tt.tmpl, got, tt.want)
+		}
+	}
+	if got := (Template{tmpl: "/library/{rel}/{base}.srt"}).Path(root, filepath.FromSlash("/incoming/a.srt"), now); got != filepath.FromSlash("/library/a.srt") {
+		t.Errorf("top-level rel: got %s", got)
+	}
+	for _, bad := range []string{"", "{base", "base}.srt", "{lang}.srt"} {
+		if _, err := ParseTemplate(bad); err == nil {
+			t.Errorf("ParseTemplate(%q) should fail", bad)
+		}
+	}
+}